@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const apiCallUsage = "usage: tgbot api call <method> [--param key=value ...] [--json @body.json] [--file field=@path ...] [flags]"
+
+func runAPI(args []string) {
+	if len(args) == 0 || args[0] != "call" {
+		fatal(apiCallUsage)
+	}
+	runAPICall(args[1:])
+}
+
+func runAPICall(args []string) {
+	method := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		method, args = args[0], args[1:]
+	}
+
+	fs := baseFlagSet("api call")
+	var params, files stringSliceFlag
+	fs.Var(&params, "param", "method parameter as key=value (repeatable; JSON objects/arrays are sent as-is)")
+	fs.Var(&files, "file", "file upload as field=@path (repeatable; switches to multipart)")
+	jsonBody := fs.String("json", "", "JSON object with method parameters, inline or @path (@- for stdin)")
+	archivePath := fs.String("archive", "", "store the result, e.g. a sent message, in this SQLite database (see tgbot archive)")
+	tokenOpt := registerTokenFlags(fs)
+	_ = fs.Parse(args)
+	if method == "" && fs.NArg() > 0 {
+		method = fs.Arg(0)
+	}
+	if method == "" {
+		fatal(apiCallUsage)
+	}
+
+	body, err := buildAPIParams(*jsonBody, params)
+	if err != nil {
+		fatalf("api call: %v", err)
+	}
+	uploads, err := parseUploadFlags(files)
+	if err != nil {
+		fatalf("api call: %v", err)
+	}
+
+	client := mustClient(tokenOpt)
+	defer archiveClient(*archivePath, client)()
+	ctx := context.Background()
+	var res json.RawMessage
+	if len(uploads) > 0 {
+		res, err = client.CallWithFiles(ctx, method, body, uploads)
+	} else {
+		res, err = client.Call(ctx, method, body)
+	}
+	if err != nil {
+		fatalf("api call failed: %v", err)
+	}
+	printJSON(res)
+}
+
+// buildAPIParams merges the --json object with --param pairs; --param wins on
+// conflicting keys.
+func buildAPIParams(jsonBody string, params []string) (map[string]any, error) {
+	out := map[string]any{}
+	if jsonBody != "" {
+		data, err := readInlineOrFile(jsonBody)
+		if err != nil {
+			return nil, fmt.Errorf("read --json: %w", err)
+		}
+		if err := json.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("--json must be a JSON object: %w", err)
+		}
+		// null decodes into a nil map without error.
+		if out == nil {
+			return nil, errors.New("--json must be a JSON object, got null")
+		}
+	}
+	for _, p := range params {
+		key, value, ok := strings.Cut(p, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --param %q, expected key=value", p)
+		}
+		out[key] = paramValue(value)
+	}
+	return out, nil
+}
+
+// paramValue keeps JSON objects and arrays structured so fields such as
+// reply_markup reach the API as objects rather than quoted strings.
+func paramValue(value string) any {
+	trimmed := strings.TrimSpace(value)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	return value
+}
+
+func parseUploadFlags(files []string) ([]telegram.UploadFile, error) {
+	uploads := make([]telegram.UploadFile, 0, len(files))
+	for _, f := range files {
+		field, path, ok := strings.Cut(f, "=@")
+		if !ok || field == "" || path == "" {
+			return nil, fmt.Errorf("invalid --file %q, expected field=@path", f)
+		}
+		uploads = append(uploads, telegram.UploadFile{Field: field, Path: path})
+	}
+	return uploads, nil
+}
+
+// readInlineOrFile returns value itself, or the contents of the file it
+// names when prefixed with @ ("@-" reads stdin).
+func readInlineOrFile(value string) ([]byte, error) {
+	if !strings.HasPrefix(value, "@") {
+		return []byte(value), nil
+	}
+	name := strings.TrimPrefix(value, "@")
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// stringSliceFlag collects every occurrence of a repeatable flag.
+type stringSliceFlag []string
+
+func (s *stringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildAPIParams(t *testing.T) {
+	body := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(body, []byte(`{"chat_id":1,"text":"from file"}`), 0o600); err != nil {
+		t.Fatalf("write body: %v", err)
+	}
+
+	params, err := buildAPIParams("@"+body, []string{"text=override", `reply_markup={"inline_keyboard":[]}`})
+	if err != nil {
+		t.Fatalf("buildAPIParams returned error: %v", err)
+	}
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		t.Fatalf("marshal params: %v", err)
+	}
+	want := `{"chat_id":1,"reply_markup":{"inline_keyboard":[]},"text":"override"}`
+	if string(encoded) != want {
+		t.Fatalf("unexpected params: %s", encoded)
+	}
+
+	if _, err := buildAPIParams("", []string{"novalue"}); err == nil {
+		t.Fatalf("expected error for param without '='")
+	}
+}
+
+func TestParseUploadFlags(t *testing.T) {
+	uploads, err := parseUploadFlags([]string{"photo=@cat.jpg"})
+	if err != nil {
+		t.Fatalf("parseUploadFlags returned error: %v", err)
+	}
+	if len(uploads) != 1 || uploads[0].Field != "photo" || uploads[0].Path != "cat.jpg" {
+		t.Fatalf("unexpected uploads: %+v", uploads)
+	}
+	if _, err := parseUploadFlags([]string{"photo=cat.jpg"}); err == nil {
+		t.Fatalf("expected error without @")
+	}
+}
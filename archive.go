@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/archive"
+	"github.com/example/tgbot-cli/internal/config"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const (
+	archiveUsage      = "usage: tgbot archive <query|stats|export> [--db bot.db] [flags]"
+	archiveQueryUsage = "usage: tgbot archive query [--db bot.db] [--format table|jsonl] <sql>"
+)
+
+func mustOpenArchive(path string) *archive.Archive {
+	a, err := archive.Open(path)
+	if err != nil {
+		fatalf("open archive: %v", err)
+	}
+	return a
+}
+
+// attachArchive archives every successful API result of client, such as
+// the messages it sends, and returns the recorder for its updates. Failures
+// to archive a result are logged; the API call itself already succeeded.
+func attachArchive(a *archive.Archive, client *telegram.Client) *archive.BotArchive {
+	bot := a.Bot(client.BotID())
+	client.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		return telegram.NewResultTransport(next, func(method string, result json.RawMessage) {
+			if err := bot.RecordResult(method, result); err != nil {
+				fmt.Fprintf(os.Stderr, "[warn] archive: %v\n", err)
+			}
+		})
+	})
+	return bot
+}
+
+// archiveClient attaches the --archive database at path, if set, to client
+// and returns the func that closes it.
+func archiveClient(path string, client *telegram.Client) func() {
+	if path == "" {
+		return func() {}
+	}
+	a := mustOpenArchive(path)
+	attachArchive(a, client)
+	return func() { a.Close() }
+}
+
+func runArchive(args []string) {
+	if len(args) == 0 {
+		fatal(archiveUsage)
+	}
+	switch args[0] {
+	case "query":
+		runArchiveQuery(args[1:])
+	case "stats":
+		runArchiveStats(args[1:])
+	case "export":
+		runArchiveExport(args[1:])
+	default:
+		fatal(archiveUsage)
+	}
+}
+
+func runArchiveQuery(args []string) {
+	query := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		query, args = args[0], args[1:]
+	}
+	fs := baseFlagSet("archive query")
+	dbPath := fs.String("db", "bot.db", "archive database written by --archive")
+	outputFormat := fs.String("format", "table", "output format: table|jsonl")
+	_ = fs.Parse(args)
+	if query == "" && fs.NArg() > 0 {
+		query = strings.Join(fs.Args(), " ")
+	}
+	if query == "" {
+		fatal(archiveQueryUsage)
+	}
+
+	a, err := archive.OpenReadOnly(*dbPath)
+	if err != nil {
+		fatalf("archive query: %v", err)
+	}
+	defer a.Close()
+	res, err := a.Query(context.Background(), query)
+	if err != nil {
+		fatalf("archive query failed: %v", err)
+	}
+	switch *outputFormat {
+	case "jsonl":
+		for _, row := range res.Rows {
+			os.Stdout.Write(append(rowJSON(res.Columns, row), '\n'))
+		}
+	case "table":
+		writeTable(os.Stdout, res)
+	default:
+		fatalf("archive query: unknown --format %q (want table or jsonl)", *outputFormat)
+	}
+}
+
+// rowJSON encodes a row as an object keeping the column order.
+func rowJSON(cols []string, row []any) []byte {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range cols {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(mustMarshal(col))
+		buf.WriteByte(':')
+		buf.Write(mustMarshal(row[i]))
+	}
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
+
+func writeTable(out io.Writer, res archive.Result) {
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(res.Columns, "\t"))
+	for _, row := range res.Rows {
+		cells := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				cells[i] = "NULL"
+				continue
+			}
+			// Keep one row per line however long or multi-line a value is.
+			cells[i] = strings.ReplaceAll(fmt.Sprint(v), "\n", `\n`)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	_ = tw.Flush()
+}
+
+func runArchiveStats(args []string) {
+	fs := baseFlagSet("archive stats")
+	dbPath := fs.String("db", "bot.db", "archive database written by --archive")
+	_ = fs.Parse(args)
+
+	a, err := archive.OpenReadOnly(*dbPath)
+	if err != nil {
+		fatalf("archive stats: %v", err)
+	}
+	defer a.Close()
+	st, err := a.Stats(context.Background())
+	if err != nil {
+		fatalf("archive stats failed: %v", err)
+	}
+	printJSON(mustMarshal(st))
+}
+
+func runArchiveExport(args []string) {
+	fs := baseFlagSet("archive export")
+	dbPath := fs.String("db", "bot.db", "archive database written by --archive")
+	chatID := fs.Int64("chat-id", 0, "only export this chat (required for transcripts)")
+	since := fs.String("since", "", "only export updates received (messages sent, for transcripts) since an RFC 3339 time or a duration ago (e.g. 24h)")
+	threadID := fs.Int64("thread-id", 0, "only export this forum topic (message_thread_id)")
+	botID := fs.Int64("bot-id", 0, "only export what this bot archived; required when several bots archived the chat")
+	profile := fs.String("profile", "", "only export what this profile's bot archived, instead of --bot-id")
+	configPath := fs.String("config", "", "config path for --profile (default ~/.tgbot-cli/config.json)")
+	out := fs.String("out", "-", "output file, - for stdout")
+	outputFormat := fs.String("format", "session", "session (jsonl for updates replay), or a transcript: txt|markdown|html")
+	_ = fs.Parse(args)
+	transcript := *outputFormat != "session"
+	switch *outputFormat {
+	case "session", archive.FormatText, archive.FormatMarkdown, archive.FormatHTML:
+	default:
+		fatalf("archive export: unknown --format %q (want session, txt, markdown or html)", *outputFormat)
+	}
+	if transcript && *chatID == 0 {
+		fatal("archive export: --chat-id is required for transcripts")
+	}
+
+	filter := archive.ExportFilter{ChatID: *chatID, ThreadID: *threadID, BotID: *botID}
+	if *profile != "" {
+		if *botID != 0 {
+			fatal("archive export: --profile cannot be combined with --bot-id")
+		}
+		filter.BotID = mustProfileBotID(*configPath, *profile)
+	}
+	if *since != "" {
+		t, err := parseSince(*since, time.Now())
+		if err != nil {
+			fatalf("archive export: %v", err)
+		}
+		filter.Since = t
+	}
+
+	a, err := archive.OpenReadOnly(*dbPath)
+	if err != nil {
+		fatalf("archive export: %v", err)
+	}
+	defer a.Close()
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fatalf("archive export: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	ctx := context.Background()
+	if transcript {
+		t, err := a.Transcript(ctx, filter)
+		if err != nil {
+			fatalf("archive export failed: %v%s", err, severalBotsHint(err))
+		}
+		if err := archive.WriteTranscript(w, t, *outputFormat); err != nil {
+			fatalf("archive export failed: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "[info] exported %d messages\n", len(t.Messages))
+		return
+	}
+	n, err := a.ExportSession(ctx, w, filter)
+	if err != nil {
+		fatalf("archive export failed: %v%s", err, severalBotsHint(err))
+	}
+	fmt.Fprintf(os.Stderr, "[info] exported %d updates\n", n)
+}
+
+// mustProfileBotID returns the id of the bot whose token profile holds,
+// without contacting Telegram.
+func mustProfileBotID(configPath, profile string) int64 {
+	token, err := config.ResolveToken(config.TokenOptions{ConfigPath: configPath, Profile: profile})
+	if err != nil {
+		fatalf("resolve token: %v", err)
+	}
+	redactor.Add(token)
+	id := telegram.BotIDFromToken(token)
+	if id == 0 {
+		fatalf("profile %q has no valid bot token", profile)
+	}
+	return id
+}
+
+// severalBotsHint tells how to pick a bot when an export matched several.
+func severalBotsHint(err error) string {
+	if errors.Is(err, archive.ErrSeveralBots) {
+		return "; pick one with --bot-id or --profile"
+	}
+	return ""
+}
+
+// parseSince accepts an RFC 3339 time or a duration before now.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q (want e.g. 24h or 2024-05-01T00:00:00Z)", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCommandArchive(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.EnqueueText("hello")
+	db := filepath.Join(t.TempDir(), "bot.db")
+
+	runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0", "--format", "jsonl", "--archive", db}, flags...)...)
+	runOK(t, append([]string{"message", "send", "--chat-id", "42", "--text", "hi alice", "--archive", db}, flags...)...)
+	runOK(t, append([]string{"api", "call", "sendMessage", "--param", "chat_id=42", "--param", "text=again", "--archive", db}, flags...)...)
+
+	res := runOK(t, "archive", "stats", "--db", db)
+	var stats struct {
+		Updates     int64 `json:"updates"`
+		MessagesIn  int64 `json:"messages_in"`
+		MessagesOut int64 `json:"messages_out"`
+		Users       int64 `json:"users"`
+	}
+	if err := json.Unmarshal([]byte(res.stdout), &stats); err != nil {
+		t.Fatalf("decode stats %q: %v", res.stdout, err)
+	}
+	if stats.Updates != 1 || stats.MessagesIn != 1 || stats.MessagesOut != 2 || stats.Users == 0 {
+		t.Fatalf("unexpected stats: %s", res.stdout)
+	}
+
+	res = runOK(t, "archive", "query", "SELECT direction, text FROM messages ORDER BY date, message_id", "--db", db, "--format", "jsonl")
+	want := `{"direction":"in","text":"hello"}` + "\n" + `{"direction":"out","text":"hi alice"}` + "\n" + `{"direction":"out","text":"again"}` + "\n"
+	if res.stdout != want {
+		t.Fatalf("unexpected query output:\n%s", res.stdout)
+	}
+
+	res = runOK(t, "archive", "query", "--db", db, "SELECT count(*) AS n FROM chats")
+	if !strings.Contains(res.stdout, "n\n1\n") {
+		t.Fatalf("unexpected table output:\n%s", res.stdout)
+	}
+
+	res = runOK(t, "archive", "export", "--db", db, "--chat-id", "42", "--since", "1h")
+	if !strings.Contains(res.stdout, `"text":"hello"`) || !strings.Contains(res.stderr, "exported 1 updates") {
+		t.Fatalf("unexpected export: %s / %s", res.stdout, res.stderr)
+	}
+	res = runOK(t, "archive", "export", "--db", db, "--chat-id", "7")
+	if res.stdout != "" {
+		t.Fatalf("expected empty export for another chat, got %s", res.stdout)
+	}
+
+	res = runOK(t, "archive", "export", "--db", db, "--chat-id", "42", "--format", "txt")
+	if !strings.Contains(res.stdout, "@alice (#1)\nhello\n") || !strings.Contains(res.stdout, "@test_bot (#") || !strings.Contains(res.stderr, "exported 3 messages") {
+		t.Fatalf("unexpected transcript: %s / %s", res.stdout, res.stderr)
+	}
+	res = runOK(t, "archive", "export", "--db", db, "--chat-id", "42", "--format", "html")
+	if !strings.HasPrefix(res.stdout, "<!DOCTYPE html>") {
+		t.Fatalf("unexpected html transcript: %s", res.stdout)
+	}
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(cfg, []byte(`{"profiles":{"main":{"token":"123456:TEST-token"},"other":{"token":"999:other-token"}}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	res = runOK(t, "archive", "export", "--db", db, "--chat-id", "42", "--config", cfg, "--profile", "main")
+	if !strings.Contains(res.stdout, `"text":"hello"`) {
+		t.Fatalf("expected the profile's updates, got %s / %s", res.stdout, res.stderr)
+	}
+	res = runOK(t, "archive", "export", "--db", db, "--chat-id", "42", "--config", cfg, "--profile", "other")
+	if res.stdout != "" {
+		t.Fatalf("expected nothing archived by the other bot, got %s", res.stdout)
+	}
+
+	bad := runCLI(t, nil, "archive", "export", "--db", db, "--format", "markdown")
+	if bad.code == 0 || !strings.Contains(bad.stderr, "--chat-id is required") {
+		t.Fatalf("expected usage error, got %d: %s", bad.code, bad.stderr)
+	}
+
+	bad = runCLI(t, nil, "archive", "stats", "--db", filepath.Join(t.TempDir(), "missing.db"))
+	if bad.code == 0 {
+		t.Fatalf("expected missing archive to fail, got: %s", bad.stdout)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 2, 12, 0, 0, 0, time.UTC)
+	got, err := parseSince("24h", now)
+	if err != nil || !got.Equal(now.Add(-24*time.Hour)) {
+		t.Fatalf("parseSince(24h) = %v, %v", got, err)
+	}
+	got, err = parseSince("2024-05-01T00:00:00Z", now)
+	if err != nil || !got.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("parseSince(rfc3339) = %v, %v", got, err)
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Fatal("expected error for invalid --since")
+	}
+}
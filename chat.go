@@ -0,0 +1,432 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/chats"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const chatUsage = "usage: tgbot chat <info|members|admins|member|ban|unban|restrict|promote|set-admin-title|invite|join-requests|set-title|set-description|set-photo|delete-photo|set-permissions|pin|unpin|unpin-all|leave> <chat> [flags]"
+
+func runChat(args []string) {
+	if len(args) == 0 {
+		fatal(chatUsage)
+	}
+	switch args[0] {
+	case "info":
+		runChatInfo(args[1:])
+	case "members":
+		if len(args) < 2 || args[1] != "count" {
+			fatal("usage: tgbot chat members count <chat> [flags]")
+		}
+		runChatMembersCount(args[2:])
+	case "admins":
+		runChatAdmins(args[1:])
+	case "member":
+		runChatMember(args[1:])
+	case "ban":
+		runChatBan(args[1:])
+	case "unban":
+		runChatUnban(args[1:])
+	case "restrict":
+		runChatRestrict(args[1:])
+	case "promote":
+		runChatPromote(args[1:])
+	case "set-title":
+		runChatSetTitle(args[1:])
+	case "set-admin-title":
+		runChatSetAdminTitle(args[1:])
+	case "set-description":
+		runChatSetDescription(args[1:])
+	case "set-photo":
+		runChatSetPhoto(args[1:])
+	case "delete-photo":
+		runChatDeletePhoto(args[1:])
+	case "set-permissions":
+		runChatSetPermissions(args[1:])
+	case "pin":
+		runChatPin(args[1:])
+	case "unpin":
+		runChatUnpin(args[1:])
+	case "unpin-all":
+		runChatUnpinAll(args[1:])
+	case "leave":
+		runChatLeave(args[1:])
+	case "invite":
+		runChatInvite(args[1:])
+	case "join-requests":
+		if len(args) < 2 || args[1] != "watch" {
+			fatal("usage: tgbot chat join-requests watch --rules rules.json [flags]")
+		}
+		runChatJoinRequestsWatch(args[2:])
+	default:
+		fatal(chatUsage)
+	}
+}
+
+// chatCommand holds what every chat subcommand parses: the chat and any
+// further positional arguments, the output format and the client.
+type chatCommand struct {
+	tokenOpt tokenFlagOptions
+	client   *telegram.Client
+	chatID   string
+	args     []string
+	format   string
+}
+
+// parseChatCommand parses `<chat> [args...]` plus --format and the flags
+// registered by extra, requiring exactly nargs positional arguments after
+// the chat, or any number when nargs is negative.
+func parseChatCommand(name, usage string, args []string, nargs int, extra func(fs *flag.FlagSet)) chatCommand {
+	return parseChat(name, usage, args, nargs, chatWithFormat, extra)
+}
+
+// parseChatAction is parseChatCommand for commands that only report
+// success, and so take no --format.
+func parseChatAction(name, usage string, args []string, nargs int, extra func(fs *flag.FlagSet)) chatCommand {
+	return parseChat(name, usage, args, nargs, 0, extra)
+}
+
+// parseModerationCommand is parseChatCommand for commands that are hard to
+// undo: the chat must be given by id, alias or exact username.
+func parseModerationCommand(name, usage string, args []string, nargs int, extra func(fs *flag.FlagSet)) chatCommand {
+	return parseChat(name, usage, args, nargs, chatWithFormat|chatStrict, extra)
+}
+
+// parseDestructiveAction is parseChatAction with the chat resolved like
+// parseModerationCommand's.
+func parseDestructiveAction(name, usage string, args []string, nargs int, extra func(fs *flag.FlagSet)) chatCommand {
+	return parseChat(name, usage, args, nargs, chatStrict, extra)
+}
+
+// chatParseMode selects what parseChat accepts.
+type chatParseMode int
+
+const (
+	chatWithFormat chatParseMode = 1 << iota
+	chatStrict
+)
+
+func parseChat(name, usage string, args []string, nargs int, mode chatParseMode, extra func(fs *flag.FlagSet)) chatCommand {
+	withFormat := mode&chatWithFormat != 0
+	fs := baseFlagSet(name)
+	outputFormat := new(string)
+	if withFormat {
+		outputFormat = fs.String("format", "table", "output format: table|json")
+	}
+	if extra != nil {
+		extra(fs)
+	}
+	tokenOpt := registerTokenFlags(fs)
+	positional := parsePositional(fs, args)
+	if len(positional) == 0 || (nargs >= 0 && len(positional) != 1+nargs) {
+		fatal(usage)
+	}
+	if withFormat && *outputFormat != "table" && *outputFormat != "json" {
+		fatalf("unknown --format %q (want table or json)", *outputFormat)
+	}
+	resolve := mustResolveChat
+	if mode&chatStrict != 0 {
+		resolve = mustResolveChatStrict
+	}
+	client := mustClient(tokenOpt)
+	return chatCommand{
+		tokenOpt: tokenOpt,
+		client:   client,
+		chatID:   resolve(tokenOpt, client, positional[0]),
+		args:     positional[1:],
+		format:   *outputFormat,
+	}
+}
+
+func runChatInfo(args []string) {
+	cmd := parseChatCommand("chat info", "usage: tgbot chat info <chat> [--format table|json] [flags]", args, 0, nil)
+	ctx := context.Background()
+	raw, err := cmd.client.GetChat(ctx, cmd.chatID)
+	if err != nil {
+		fatalf("chat info failed: %v", err)
+	}
+	if cmd.format == "json" {
+		printJSON(raw)
+		return
+	}
+	var info telegram.ChatFullInfo
+	if err := json.Unmarshal(raw, &info); err != nil {
+		fatalf("decode chat info: %v", err)
+	}
+
+	rows := [][2]string{
+		{"id", strconv.FormatInt(info.ID, 10)},
+		{"type", info.Type},
+		{"name", chatName(info.Chat)},
+	}
+	optional := [][2]string{
+		{"username", atUsername(info.Username)},
+		{"description", info.Description},
+		{"bio", info.Bio},
+		{"invite link", info.InviteLink},
+	}
+	if info.LinkedChatID != 0 {
+		optional = append(optional, [2]string{"linked chat", strconv.FormatInt(info.LinkedChatID, 10)})
+	}
+	if info.IsForum {
+		optional = append(optional, [2]string{"forum", "yes"})
+	}
+	if info.JoinByRequest {
+		optional = append(optional, [2]string{"join by request", "yes"})
+	}
+	if info.SlowModeDelay > 0 {
+		optional = append(optional, [2]string{"slow mode", (time.Duration(info.SlowModeDelay) * time.Second).String()})
+	}
+	if info.HasProtectedContent {
+		optional = append(optional, [2]string{"protected content", "yes"})
+	}
+	for _, row := range optional {
+		if row[1] != "" {
+			rows = append(rows, row)
+		}
+	}
+	if m := info.PinnedMessage; m != nil {
+		rows = append(rows, [2]string{"pinned message", strings.TrimSpace(fmt.Sprintf("#%d %s", m.MessageID, firstNonEmpty(m.Text, m.Caption)))})
+	}
+	if perms := chatPermissionsField(raw); perms != nil {
+		rows = append(rows, [2]string{"member permissions", joinOrNone(enabledRights(perms))})
+	}
+	if info.Type != "private" {
+		if n, err := cmd.client.GetChatMemberCount(ctx, cmd.chatID); err == nil {
+			rows = append(rows, [2]string{"members", strconv.Itoa(n)})
+		}
+		// The bot's own status answers the usual "why can't it post/pin/ban".
+		if member, err := cmd.client.GetChatMember(ctx, cmd.chatID, cmd.client.BotID()); err == nil {
+			var m telegram.ChatMember
+			if json.Unmarshal(member, &m) == nil {
+				status := m.Status
+				if status != "administrator" && status != "creator" {
+					status += " (not an administrator)"
+				}
+				rows = append(rows, [2]string{"bot status", status})
+				if m.Status == "administrator" {
+					rows = append(rows, [2]string{"bot rights", joinOrNone(enabledRights(member))})
+				}
+			}
+		} else {
+			rows = append(rows, [2]string{"bot status", "unknown: " + err.Error()})
+		}
+	}
+	writeKeyValues(rows)
+}
+
+func runChatMembersCount(args []string) {
+	cmd := parseChatCommand("chat members count", "usage: tgbot chat members count <chat> [--format table|json] [flags]", args, 0, nil)
+	n, err := cmd.client.GetChatMemberCount(context.Background(), cmd.chatID)
+	if err != nil {
+		fatalf("chat members count failed: %v", err)
+	}
+	if cmd.format == "json" {
+		printJSON(mustMarshal(map[string]any{"chat_id": cmd.chatID, "count": n}))
+		return
+	}
+	fmt.Println(n)
+}
+
+func runChatAdmins(args []string) {
+	cmd := parseChatCommand("chat admins", "usage: tgbot chat admins <chat> [--format table|json] [flags]", args, 0, nil)
+	raw, err := cmd.client.GetChatAdministrators(context.Background(), cmd.chatID)
+	if err != nil {
+		fatalf("chat admins failed: %v", err)
+	}
+	if cmd.format == "json" {
+		printJSON(raw)
+		return
+	}
+	var admins []json.RawMessage
+	if err := json.Unmarshal(raw, &admins); err != nil {
+		fatalf("decode chat admins: %v", err)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "USER ID\tUSER\tSTATUS\tTITLE\tRIGHTS")
+	for _, a := range admins {
+		var m telegram.ChatMember
+		if err := json.Unmarshal(a, &m); err != nil {
+			fatalf("decode chat admins: %v", err)
+		}
+		rights := "all"
+		if m.Status != "creator" {
+			rights = joinOrNone(enabledRights(a))
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", m.User.ID, userName(m.User), m.Status, m.CustomTitle, rights)
+	}
+	_ = tw.Flush()
+}
+
+func runChatMember(args []string) {
+	cmd := parseChatCommand("chat member", "usage: tgbot chat member <chat> <user> [--format table|json] [flags]", args, 1, nil)
+	userID := mustResolveUser(cmd.tokenOpt, cmd.client, cmd.args[0])
+	raw, err := cmd.client.GetChatMember(context.Background(), cmd.chatID, userID)
+	if err != nil {
+		fatalf("chat member failed: %v", err)
+	}
+	if cmd.format == "json" {
+		printJSON(raw)
+		return
+	}
+	var m telegram.ChatMember
+	if err := json.Unmarshal(raw, &m); err != nil {
+		fatalf("decode chat member: %v", err)
+	}
+	rows := [][2]string{
+		{"user", userName(m.User)},
+		{"user id", strconv.FormatInt(m.User.ID, 10)},
+		{"status", m.Status},
+	}
+	if m.CustomTitle != "" {
+		rows = append(rows, [2]string{"title", m.CustomTitle})
+	}
+	if m.IsAnonymous {
+		rows = append(rows, [2]string{"anonymous", "yes"})
+	}
+	switch m.Status {
+	case "administrator":
+		rows = append(rows, [2]string{"rights", joinOrNone(enabledRights(raw))})
+	case "restricted":
+		rows = append(rows,
+			[2]string{"is member", strconv.FormatBool(m.IsMember)},
+			[2]string{"allowed", joinOrNone(enabledRights(raw))},
+			[2]string{"until", untilDate(m.UntilDate)})
+	case "kicked":
+		rows = append(rows, [2]string{"until", untilDate(m.UntilDate)})
+	}
+	writeKeyValues(rows)
+}
+
+// mustResolveUser accepts a numeric user id or a reference to a known
+// private chat, whose id is the user's.
+func mustResolveUser(opts tokenFlagOptions, client *telegram.Client, ref string) int64 {
+	r := userResolver{opts: opts, client: client}
+	id, warning, err := r.resolve(ref)
+	if err != nil {
+		fatal(err.Error())
+	}
+	if warning != "" {
+		fmt.Fprintf(os.Stderr, "[warn] %s\n", warning)
+	}
+	return id
+}
+
+// userResolver resolves user references, opening the chat registry only
+// for references that are not numeric. A strict resolver accepts only ids,
+// aliases and exact usernames, as moderation commands need.
+type userResolver struct {
+	opts   tokenFlagOptions
+	client *telegram.Client
+	reg    *chats.Registry
+	strict bool
+}
+
+func (r *userResolver) resolve(ref string) (int64, string, error) {
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		return id, "", nil
+	}
+	if r.reg == nil {
+		r.reg = mustRegistry(r.opts, r.client)
+	}
+	resolve, want := r.reg.Resolve, "the name of a known private chat"
+	if r.strict {
+		resolve, want = r.reg.ResolveStrict, "the alias or exact username of a known private chat"
+	}
+	res, err := resolve(ref)
+	if errors.Is(err, chats.ErrAmbiguous) {
+		return 0, "", err
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("unknown user %q: use a numeric user id or %s", ref, want)
+	}
+	id, err := strconv.ParseInt(res.ChatID, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, "", fmt.Errorf("unknown user %q: use a numeric user id or %s", ref, want)
+	}
+	return id, res.Warning, nil
+}
+
+// enabledRights lists the can_* fields set to true in a JSON object, such
+// as a ChatMember or ChatPermissions.
+func enabledRights(raw json.RawMessage) []string {
+	var fields map[string]any
+	if json.Unmarshal(raw, &fields) != nil {
+		return nil
+	}
+	var out []string
+	for k, v := range fields {
+		if on, ok := v.(bool); ok && on && strings.HasPrefix(k, "can_") {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func chatPermissionsField(raw json.RawMessage) json.RawMessage {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return nil
+	}
+	return fields["permissions"]
+}
+
+func joinOrNone(values []string) string {
+	if len(values) == 0 {
+		return "none"
+	}
+	return strings.Join(values, ", ")
+}
+
+func writeKeyValues(rows [][2]string) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(tw, "%s\t%s\n", row[0], row[1])
+	}
+	_ = tw.Flush()
+}
+
+func chatName(c telegram.Chat) string {
+	if c.Title != "" {
+		return c.Title
+	}
+	return strings.TrimSpace(c.FirstName + " " + c.LastName)
+}
+
+func userName(u telegram.User) string {
+	name := strings.TrimSpace(u.FirstName + " " + u.LastName)
+	if u.Username != "" {
+		name = strings.TrimSpace("@" + u.Username + " " + name)
+	}
+	if u.IsBot {
+		name += " (bot)"
+	}
+	return name
+}
+
+func atUsername(username string) string {
+	if username == "" {
+		return ""
+	}
+	return "@" + username
+}
+
+func untilDate(unix int64) string {
+	if unix == 0 {
+		return "forever"
+	}
+	return time.Unix(unix, 0).Local().Format("2006-01-02 15:04")
+}
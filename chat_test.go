@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func TestCommandChatInspection(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultUser, Status: "creator", Fields: map[string]any{"custom_title": "boss"}})
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "member"})
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.User{ID: 7, FirstName: "Mallory"}, Status: "kicked"})
+
+	res := runOK(t, append([]string{"chat", "info", "-1001"}, flags...)...)
+	for _, want := range []string{"Ops", "supergroup", "members", "member (not an administrator)"} {
+		if !strings.Contains(res.stdout, want) {
+			t.Fatalf("chat info missing %q:\n%s", want, res.stdout)
+		}
+	}
+
+	res = runOK(t, append([]string{"chat", "members", "count", "-1001"}, flags...)...)
+	if strings.TrimSpace(res.stdout) != "2" {
+		t.Fatalf("unexpected member count %q", res.stdout)
+	}
+
+	res = runOK(t, append([]string{"chat", "admins", "-1001"}, flags...)...)
+	if !strings.Contains(res.stdout, "@alice") || !strings.Contains(res.stdout, "boss") || strings.Contains(res.stdout, "test_bot") {
+		t.Fatalf("unexpected admins:\n%s", res.stdout)
+	}
+
+	res = runOK(t, append([]string{"chat", "member", "-1001", "7", "--format", "json"}, flags...)...)
+	var member struct {
+		Status string `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(res.stdout), &member); err != nil || member.Status != "kicked" {
+		t.Fatalf("unexpected chat member %q: %v", res.stdout, err)
+	}
+	res = runOK(t, append([]string{"chat", "member", "-1001", "7"}, flags...)...)
+	if !strings.Contains(res.stdout, "until") || !strings.Contains(res.stdout, "forever") {
+		t.Fatalf("unexpected chat member table:\n%s", res.stdout)
+	}
+
+	bad := runCLI(t, nil, append([]string{"chat", "info", "-999"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "chat not found") {
+		t.Fatalf("expected chat not found, got %d: %s", bad.code, bad.stderr)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/chats"
+	"github.com/example/tgbot-cli/internal/config"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const chatsUsage = "usage: tgbot chats <list|show|alias> [flags]"
+
+// mustRegistry opens the chat registry of the bot behind client.
+func mustRegistry(opts tokenFlagOptions, client *telegram.Client) *chats.Registry {
+	reg, err := openRegistry(opts, client)
+	if err != nil {
+		fatalf("chat registry: %v", err)
+	}
+	return reg
+}
+
+func openRegistry(opts tokenFlagOptions, client *telegram.Client) (*chats.Registry, error) {
+	path, err := config.ChatsPath(*opts.configPath)
+	if err != nil {
+		return nil, err
+	}
+	return chats.Open(path, client.BotID())
+}
+
+// chatLearner feeds received updates into the registry. A registry that
+// cannot be read or written is reported but never stops the caller.
+type chatLearner struct {
+	reg *chats.Registry
+}
+
+// newChatLearner opens the registry for learning, warning and learning
+// nothing when it cannot be read, e.g. because chats.json is corrupt.
+func newChatLearner(opts tokenFlagOptions, client *telegram.Client) chatLearner {
+	reg, err := openRegistry(opts, client)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] chat registry: %v; not learning chats\n", err)
+	}
+	return chatLearner{reg: reg}
+}
+
+func (l chatLearner) Record(update telegram.Update, receivedAt time.Time) error {
+	if l.reg == nil {
+		return nil
+	}
+	if err := l.reg.Record(update, receivedAt); err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] learn chat: %v\n", err)
+	}
+	return nil
+}
+
+// chatFlags are the two ways a command takes its target chat.
+type chatFlags struct {
+	id   *string
+	name *string
+}
+
+func registerChatFlags(fs *flag.FlagSet) chatFlags {
+	return chatFlags{
+		id:   fs.String("chat-id", "", "target chat: id, @username or @alias"),
+		name: fs.String("chat", "", "target chat by alias, username or title (see tgbot chats list)"),
+	}
+}
+
+func (f chatFlags) ref() string {
+	return firstNonEmpty(*f.id, *f.name)
+}
+
+// mustResolveChatFlags resolves --chat-id or --chat, exactly one of which
+// must be set.
+func mustResolveChatFlags(opts tokenFlagOptions, client *telegram.Client, f chatFlags) string {
+	if *f.id != "" && *f.name != "" {
+		fatal("use either --chat-id or --chat, not both")
+	}
+	return mustResolveChat(opts, client, f.ref())
+}
+
+var numericChatID = regexp.MustCompile(`^-?[0-9]+$`)
+
+// mustResolveChat turns an id, alias, username or title into the chat_id to
+// send, warning on stderr when a name was ambiguous.
+func mustResolveChat(opts tokenFlagOptions, client *telegram.Client, ref string) string {
+	if numericChatID.MatchString(ref) {
+		return ref
+	}
+	res, err := mustRegistry(opts, client).Resolve(ref)
+	if err != nil {
+		fatal(err.Error())
+	}
+	if res.Warning != "" {
+		fmt.Fprintf(os.Stderr, "[warn] %s\n", res.Warning)
+	}
+	return res.ChatID
+}
+
+// mustResolveChatStrict is mustResolveChat for destructive commands: only
+// an id, alias or exact username is accepted, and ambiguity is fatal.
+func mustResolveChatStrict(opts tokenFlagOptions, client *telegram.Client, ref string) string {
+	if numericChatID.MatchString(ref) {
+		return ref
+	}
+	res, err := mustRegistry(opts, client).ResolveStrict(ref)
+	if err != nil {
+		fatal(err.Error())
+	}
+	return res.ChatID
+}
+
+// parsePositional parses fs from args with positional arguments allowed
+// before, between and after flags, as in `chats alias dev -1001234
+// --profile prod`, and returns the positional ones. Negative numbers are
+// chat ids, not flags.
+func parsePositional(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for len(args) > 0 {
+		if arg := args[0]; !strings.HasPrefix(arg, "-") || arg == "-" || numericChatID.MatchString(arg) {
+			positional = append(positional, arg)
+			args = args[1:]
+			continue
+		}
+		_ = fs.Parse(args)
+		args = fs.Args()
+	}
+	return positional
+}
+
+func runChats(args []string) {
+	if len(args) == 0 {
+		fatal(chatsUsage)
+	}
+	switch args[0] {
+	case "list":
+		runChatsList(args[1:])
+	case "show":
+		runChatsShow(args[1:])
+	case "alias":
+		runChatsAlias(args[1:])
+	default:
+		fatal(chatsUsage)
+	}
+}
+
+func runChatsList(args []string) {
+	fs := baseFlagSet("chats list")
+	outputFormat := fs.String("format", "table", "output format: table|json")
+	tokenOpt := registerTokenFlags(fs)
+	_ = fs.Parse(args)
+
+	reg := mustRegistry(tokenOpt, mustClient(tokenOpt))
+	list := reg.Chats()
+	if *outputFormat == "json" {
+		out := make([]chatView, len(list))
+		for i, c := range list {
+			out[i] = newChatView(reg, c)
+		}
+		printJSON(mustMarshal(out))
+		return
+	}
+	if len(list) == 0 {
+		fmt.Fprintln(os.Stderr, "[info] no chats known yet; they are learned by updates listen and updates list")
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTYPE\tNAME\tUSERNAME\tALIASES\tLAST SEEN")
+	for _, c := range list {
+		username := ""
+		if c.Username != "" {
+			username = "@" + c.Username
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\n", c.ID, c.Type, c.Name(), username,
+			strings.Join(reg.Aliases(c.ID), ","), c.LastSeen.Local().Format("2006-01-02 15:04"))
+	}
+	_ = tw.Flush()
+}
+
+// chatView is a registry entry with its aliases, as printed by chats show
+// and chats list --format json.
+type chatView struct {
+	chats.Chat
+	Aliases []string `json:"aliases"`
+}
+
+func newChatView(reg *chats.Registry, c chats.Chat) chatView {
+	aliases := reg.Aliases(c.ID)
+	if aliases == nil {
+		aliases = []string{}
+	}
+	return chatView{Chat: c, Aliases: aliases}
+}
+
+func runChatsShow(args []string) {
+	fs := baseFlagSet("chats show")
+	tokenOpt := registerTokenFlags(fs)
+	positional := parsePositional(fs, args)
+	if len(positional) != 1 {
+		fatal("usage: tgbot chats show <chat> [flags]")
+	}
+
+	reg := mustRegistry(tokenOpt, mustClient(tokenOpt))
+	res, err := reg.Resolve(positional[0])
+	if err != nil {
+		fatal(err.Error())
+	}
+	if res.Warning != "" {
+		fmt.Fprintf(os.Stderr, "[warn] %s\n", res.Warning)
+	}
+	if res.Chat == nil {
+		fatalf("chat %s has not been seen yet", res.ChatID)
+	}
+	printJSON(mustMarshal(newChatView(reg, *res.Chat)))
+}
+
+func runChatsAlias(args []string) {
+	fs := baseFlagSet("chats alias")
+	remove := fs.Bool("remove", false, "delete the alias instead of setting it")
+	tokenOpt := registerTokenFlags(fs)
+	positional := parsePositional(fs, args)
+
+	if *remove {
+		if len(positional) != 1 {
+			fatal("usage: tgbot chats alias --remove <name> [flags]")
+		}
+		reg := mustRegistry(tokenOpt, mustClient(tokenOpt))
+		if err := reg.RemoveAlias(positional[0]); err != nil {
+			fatal(err.Error())
+		}
+		printJSON(mustMarshal(map[string]any{"ok": true, "removed": strings.TrimPrefix(positional[0], "@")}))
+		return
+	}
+	if len(positional) != 2 {
+		fatal("usage: tgbot chats alias <name> <chat> [flags]")
+	}
+	reg := mustRegistry(tokenOpt, mustClient(tokenOpt))
+	res, err := reg.Resolve(positional[1])
+	if err != nil {
+		fatal(err.Error())
+	}
+	if res.Warning != "" {
+		fmt.Fprintf(os.Stderr, "[warn] %s\n", res.Warning)
+	}
+	id, err := strconv.ParseInt(res.ChatID, 10, 64)
+	if err != nil {
+		fatalf("chat %s has not been seen yet; alias it by numeric id", positional[1])
+	}
+	if err := reg.SetAlias(positional[0], id); err != nil {
+		fatal(err.Error())
+	}
+	printJSON(mustMarshal(map[string]any{"ok": true, "alias": strings.TrimPrefix(positional[0], "@"), "chat_id": id}))
+}
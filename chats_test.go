@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func TestCommandChatsRegistry(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	// Each run gets its own HOME, so share the registry through --config.
+	flags = append(flags, "--config", filepath.Join(t.TempDir(), "config.json"))
+	srv.EnqueueMessage(telegramtest.IncomingMessage{Chat: telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Dev Group"}, Text: "a"})
+	srv.EnqueueMessage(telegramtest.IncomingMessage{Chat: telegramtest.Chat{ID: -1002, Type: "supergroup", Title: "Dev Group Archive"}, Text: "b"})
+	runOK(t, append([]string{"updates", "list", "--format", "jsonl"}, flags...)...)
+
+	res := runOK(t, append([]string{"chats", "list"}, flags...)...)
+	if !strings.Contains(res.stdout, "-1001") || !strings.Contains(res.stdout, "Dev Group Archive") {
+		t.Fatalf("unexpected chats list:\n%s", res.stdout)
+	}
+
+	runOK(t, append([]string{"chats", "alias", "ops", "-1002"}, flags...)...)
+	res = runOK(t, append([]string{"chats", "show", "@ops"}, flags...)...)
+	var shown struct {
+		ID      int64    `json:"id"`
+		Title   string   `json:"title"`
+		Aliases []string `json:"aliases"`
+	}
+	if err := json.Unmarshal([]byte(res.stdout), &shown); err != nil {
+		t.Fatalf("decode chats show %q: %v", res.stdout, err)
+	}
+	if shown.ID != -1002 || shown.Title != "Dev Group Archive" || len(shown.Aliases) != 1 || shown.Aliases[0] != "ops" {
+		t.Fatalf("unexpected chats show: %s", res.stdout)
+	}
+
+	runOK(t, append([]string{"message", "send", "--chat-id", "@ops", "--text", "via alias"}, flags...)...)
+	runOK(t, append([]string{"message", "send", "--chat", "dev-group", "--text", "via title"}, flags...)...)
+	res = runOK(t, append([]string{"message", "send", "--chat", "dev", "--text", "ambiguous"}, flags...)...)
+	if !strings.Contains(res.stderr, "[warn] chat \"dev\" is ambiguous") {
+		t.Fatalf("expected ambiguity warning, got: %s", res.stderr)
+	}
+	sent := srv.SentMessages()
+	if len(sent) != 3 || sent[0].ChatID != "-1002" || sent[1].ChatID != "-1001" {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+
+	bad := runCLI(t, nil, append([]string{"message", "send", "--chat", "nowhere", "--text", "x"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, `unknown chat "nowhere"`) {
+		t.Fatalf("expected unknown chat error, got %d: %s", bad.code, bad.stderr)
+	}
+	bad = runCLI(t, nil, append([]string{"message", "send", "--chat", "ops", "--chat-id", "1", "--text", "x"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "either --chat-id or --chat") {
+		t.Fatalf("expected usage error, got %d: %s", bad.code, bad.stderr)
+	}
+
+	runOK(t, append([]string{"chats", "alias", "--remove", "ops"}, flags...)...)
+	bad = runCLI(t, nil, append([]string{"chats", "show", "ops"}, flags...)...)
+	if bad.code == 0 {
+		t.Fatalf("expected removed alias to be unknown, got: %s", bad.stdout)
+	}
+}
+
+func TestCommandUpdatesWithCorruptRegistry(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	dir := t.TempDir()
+	flags = append(flags, "--config", filepath.Join(dir, "config.json"))
+	if err := os.WriteFile(filepath.Join(dir, "chats.json"), []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv.EnqueueText("still listed")
+
+	res := runOK(t, append([]string{"updates", "list", "--format", "jsonl"}, flags...)...)
+	if !strings.Contains(res.stdout, "still listed") || !strings.Contains(res.stderr, "not learning chats") {
+		t.Fatalf("expected updates with a registry warning, got %s / %s", res.stdout, res.stderr)
+	}
+	bad := runCLI(t, nil, append([]string{"chats", "list"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "parse chat registry") {
+		t.Fatalf("expected chats list to report the corrupt file, got %d: %s", bad.code, bad.stderr)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+// newFakeAPI starts a fake Bot API and returns it with the flags pointing a
+// command at it.
+func newFakeAPI(t *testing.T) (*telegramtest.Server, []string) {
+	t.Helper()
+	srv := telegramtest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv, []string{"--token", telegramtest.DefaultToken, "--api-base", srv.URL}
+}
+
+func runOK(t *testing.T, args ...string) cliResult {
+	t.Helper()
+	res := runCLI(t, nil, args...)
+	if res.code != 0 {
+		t.Fatalf("%v exited %d: %s", args, res.code, res.stderr)
+	}
+	return res
+}
+
+func TestCommandBotMe(t *testing.T) {
+	_, flags := newFakeAPI(t)
+	res := runOK(t, append([]string{"bot", "me"}, flags...)...)
+	if !strings.Contains(res.stdout, `"username": "test_bot"`) {
+		t.Fatalf("unexpected output: %s", res.stdout)
+	}
+}
+
+func TestCommandBotLogoutAndClose(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	res := runCLI(t, nil, append([]string{"bot", "logout"}, flags...)...)
+	if res.code == 0 || !strings.Contains(res.stderr, "--yes") {
+		t.Fatalf("expected confirmation error, got code %d: %s", res.code, res.stderr)
+	}
+	if srv.CallCount("logOut") != 0 {
+		t.Fatalf("logOut must not be called without --yes")
+	}
+
+	runOK(t, append([]string{"bot", "logout", "--yes"}, flags...)...)
+	res = runOK(t, append([]string{"bot", "close", "--yes"}, flags...)...)
+	if srv.CallCount("logOut") != 1 || srv.CallCount("close") != 1 {
+		t.Fatalf("expected one logOut and one close call, got %v", srv.Calls())
+	}
+	if !strings.Contains(res.stdout, `"method": "close"`) {
+		t.Fatalf("unexpected output: %s", res.stdout)
+	}
+}
+
+func TestCommandMessageSend(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	res := runOK(t, append([]string{"message", "send", "--chat-id", "42", "--text", "hello there"}, flags...)...)
+	sent := srv.SentMessages()
+	if len(sent) != 1 || sent[0].ChatID != "42" || sent[0].Text != "hello there" {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+	if !strings.Contains(res.stdout, `"text": "hello there"`) {
+		t.Fatalf("unexpected output: %s", res.stdout)
+	}
+
+	res = runCLI(t, nil, append([]string{"message", "send", "--chat-id", "42"}, flags...)...)
+	if res.code == 0 || !strings.Contains(res.stderr, "--chat-id and --text are required") {
+		t.Fatalf("expected validation error, got %d: %s", res.code, res.stderr)
+	}
+}
+
+func TestCommandUpdatesList(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	for _, text := range []string{"a", "b", "c"} {
+		srv.EnqueueText(text)
+	}
+	res := runOK(t, append([]string{"updates", "list", "--limit", "2", "--format", "jsonl"}, flags...)...)
+	lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], `"text":"b"`) || !strings.Contains(lines[1], `"text":"c"`) {
+		t.Fatalf("expected the last two updates, got %q", res.stdout)
+	}
+	if srv.CallCount("deleteWebhook") != 1 {
+		t.Fatalf("expected deleteWebhook before listing")
+	}
+}
+
+func TestCommandUpdatesListen(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.SetWebhook(telegramtest.WebhookState{URL: "https://example.com/hook"})
+	srv.EnqueueText("ping")
+
+	res := runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0"}, flags...)...)
+	if !strings.Contains(res.stdout, `"text": "ping"`) {
+		t.Fatalf("expected pretty update output, got %q", res.stdout)
+	}
+	if srv.Webhook().URL != "" {
+		t.Fatalf("listen should delete the webhook first")
+	}
+}
+
+func TestCommandUpdatesTableFormats(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.EnqueueText("hello, world")
+
+	res := runOK(t, append([]string{"updates", "list", "--format", "csv"}, flags...)...)
+	lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+	if len(lines) != 2 || lines[0] != "update_id,type,date,chat_id,chat_title,from_id,username,text" ||
+		!strings.HasPrefix(lines[1], "1,message,") || !strings.HasSuffix(lines[1], `,42,alice,"hello, world"`) {
+		t.Fatalf("unexpected csv output: %q", res.stdout)
+	}
+
+	srv.EnqueueText("again")
+	res = runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0", "--format", "tsv", "--columns", "update_id,message.from.first_name,message.nope"}, flags...)...)
+	if res.stdout != "update_id\tmessage.from.first_name\tmessage.nope\n2\tAlice\t\n" {
+		t.Fatalf("unexpected tsv output: %q", res.stdout)
+	}
+
+	bad := runCLI(t, nil, append([]string{"updates", "list", "--columns", "text"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "--columns requires --format csv or tsv") {
+		t.Fatalf("expected usage error, got %d: %s", bad.code, bad.stderr)
+	}
+}
+
+func TestCommandAPICall(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	body := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(body, []byte(`{"chat_id":7,"text":"from json"}`), 0o600); err != nil {
+		t.Fatalf("write body: %v", err)
+	}
+	res := runOK(t, append([]string{"api", "call", "sendMessage", "--json", "@" + body, "--param", `reply_markup={"inline_keyboard":[]}`}, flags...)...)
+	sent := srv.SentMessages()
+	if len(sent) != 1 || sent[0].ChatID != "7" || sent[0].Params["reply_markup"] != `{"inline_keyboard":[]}` {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+	if !strings.Contains(res.stdout, `"text": "from json"`) {
+		t.Fatalf("unexpected output: %s", res.stdout)
+	}
+
+	doc := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(doc, []byte("file body"), 0o600); err != nil {
+		t.Fatalf("write doc: %v", err)
+	}
+	runOK(t, append([]string{"api", "call", "sendDocument", "--param", "chat_id=7", "--file", "document=@" + doc}, flags...)...)
+	sent = srv.SentMessages()
+	if len(sent) != 2 || sent[1].Method != "sendDocument" || sent[1].Params["document"] == "" {
+		t.Fatalf("expected multipart sendDocument, got %+v", sent)
+	}
+
+	for _, body := range []string{"null", "[1]", `"text"`} {
+		res = runCLI(t, nil, append([]string{"api", "call", "getMe", "--json", body, "--param", "a=b"}, flags...)...)
+		if res.code != 1 || !strings.Contains(res.stderr, "--json must be a JSON object") {
+			t.Fatalf("expected object error for --json %s, got %d: %s", body, res.code, res.stderr)
+		}
+	}
+
+	res = runCLI(t, nil, append([]string{"api", "call", "noSuchMethod"}, flags...)...)
+	if res.code == 0 || !strings.Contains(res.stderr, "noSuchMethod: Not Found") {
+		t.Fatalf("expected not found error, got %d: %s", res.code, res.stderr)
+	}
+}
+
+func TestCommandFileDownload(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.AddFile("abc", "documents/report.txt", []byte("report body"))
+
+	dir := t.TempDir()
+	res := runOK(t, append([]string{"file", "download", "abc", "--out", dir}, flags...)...)
+	data, err := os.ReadFile(filepath.Join(dir, "report.txt"))
+	if err != nil || string(data) != "report body" {
+		t.Fatalf("unexpected downloaded file: %q, %v", data, err)
+	}
+	var summary map[string]any
+	if err := json.Unmarshal([]byte(res.stdout), &summary); err != nil || summary["size"] != float64(11) {
+		t.Fatalf("unexpected summary %s: %v", res.stdout, err)
+	}
+
+	res = runOK(t, append([]string{"file", "download", "abc", "--out", "-"}, flags...)...)
+	if res.stdout != "report body" {
+		t.Fatalf("expected file on stdout, got %q", res.stdout)
+	}
+}
+
+func TestCommandTestEnv(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	runOK(t, append([]string{"bot", "me", "--test-env"}, flags...)...)
+	calls := srv.Calls()
+	if len(calls) != 1 || calls[0].Method != "getMe" || !strings.Contains(calls[0].Path, "/test/") {
+		t.Fatalf("expected getMe through the test environment path, got %+v", calls)
+	}
+}
+
+func TestCommandHelpAndUnknown(t *testing.T) {
+	res := runOK(t, "help")
+	if !strings.Contains(res.stdout, "tgbot - Telegram Bot CLI") {
+		t.Fatalf("unexpected help output: %s", res.stdout)
+	}
+	res = runCLI(t, nil, "nope")
+	if res.code == 0 || !strings.Contains(res.stderr, "unknown command: nope") {
+		t.Fatalf("expected unknown command error, got %d: %s", res.code, res.stderr)
+	}
+}
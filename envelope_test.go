@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/polling"
+)
+
+func decodeEnvelopes(t *testing.T, out string) []polling.Envelope {
+	t.Helper()
+	var envs []polling.Envelope
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var env polling.Envelope
+		if err := json.Unmarshal([]byte(line), &env); err != nil {
+			t.Fatalf("decode envelope %q: %v", line, err)
+		}
+		envs = append(envs, env)
+	}
+	return envs
+}
+
+func TestCommandEnvelopeOutput(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.EnqueueText("hello")
+
+	sessionPath := filepath.Join(t.TempDir(), "session.jsonl")
+	res := runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0", "--format", "jsonl", "--envelope", "--record", sessionPath}, flags...)...)
+	envs := decodeEnvelopes(t, res.stdout)
+	if len(envs) != 1 || envs[0].Source != "polling" || envs[0].Bot != "@test_bot" || envs[0].ReceivedAt == nil || !strings.Contains(string(envs[0].Update), `"text":"hello"`) {
+		t.Fatalf("unexpected listen envelope: %s", res.stdout)
+	}
+
+	res = runOK(t, append([]string{"updates", "list", "--format", "jsonl", "--envelope"}, flags...)...)
+	envs = decodeEnvelopes(t, res.stdout)
+	if len(envs) != 1 || envs[0].Source != "polling" || envs[0].Bot != "@test_bot" {
+		t.Fatalf("unexpected list envelope: %s", res.stdout)
+	}
+
+	res = runOK(t, "updates", "replay", sessionPath, "--stdout", "--format", "jsonl", "--speed", "max", "--envelope")
+	replayed := decodeEnvelopes(t, res.stdout)
+	if len(replayed) != 1 || replayed[0].Source != "replay" || replayed[0].ReceivedAt == nil {
+		t.Fatalf("unexpected replay envelope: %s", res.stdout)
+	}
+
+	bad := runCLI(t, nil, "updates", "replay", sessionPath, "--to", "http://127.0.0.1:1/hook", "--envelope")
+	if bad.code == 0 || !strings.Contains(bad.stderr, "--envelope requires --stdout") {
+		t.Fatalf("expected usage error, got %d: %s", bad.code, bad.stderr)
+	}
+}
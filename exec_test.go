@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandUpdatesListenExec(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.EnqueueText("ping")
+
+	res := runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0", "--format", "jsonl",
+		"--exec", `grep -q '"text":"ping"' && echo "pong: $TG_TEXT ($TG_UPDATE_TYPE)"`, "--exec-reply"}, flags...)...)
+	sent := srv.SentMessages()
+	if len(sent) != 1 || sent[0].Text != "pong: ping (message)" {
+		t.Fatalf("expected stdout sent back to the chat, got %+v (stderr %s)", sent, res.stderr)
+	}
+
+	srv.EnqueueText("again")
+	res = runCLI(t, nil, append([]string{"updates", "listen", "--once", "--timeout", "0", "--exec", "echo boom >&2; exit 1", "--max-attempts", "1"}, flags...)...)
+	if res.code == 0 || !strings.Contains(res.stderr, "boom") || !strings.Contains(res.stderr, "stopping without confirming it: command failed: exit status 1") {
+		t.Fatalf("expected command failure to stop listen, got %d: %s", res.code, res.stderr)
+	}
+
+	deadPath := filepath.Join(t.TempDir(), "dead.jsonl")
+	res = runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0", "--format", "jsonl", "--exec", "exit 3",
+		"--dead-letter", deadPath}, flags...)...)
+	if !strings.Contains(res.stderr, "[dead-letter] update 1 failed after 1 attempt(s)") {
+		t.Fatalf("expected a dead-letter log, got %s", res.stderr)
+	}
+	dead, err := os.ReadFile(deadPath)
+	if err != nil || !strings.Contains(string(dead), `"attempts":1`) || !strings.Contains(string(dead), `"error":"command failed: exit status 3"`) {
+		t.Fatalf("unexpected dead-letter file: %s (%v)", dead, err)
+	}
+
+	// A single cycle confirms nothing, so retrying in it is refused.
+	bad := runCLI(t, nil, append([]string{"updates", "listen", "--once", "--timeout", "0", "--exec", "exit 3", "--max-attempts", "2"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "cannot retry failed updates") {
+		t.Fatalf("expected a validation error, got %d: %s", bad.code, bad.stderr)
+	}
+
+	bad = runCLI(t, nil, append([]string{"updates", "listen", "--exec-reply"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "require --exec") {
+		t.Fatalf("expected usage error, got %d: %s", bad.code, bad.stderr)
+	}
+}
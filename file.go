@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func runFile(args []string) {
+	if len(args) == 0 || args[0] != "download" {
+		fatal("usage: tgbot file download <file_id> [--out path] [flags]")
+	}
+	runFileDownload(args[1:])
+}
+
+func runFileDownload(args []string) {
+	fileID := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		fileID, args = args[0], args[1:]
+	}
+	fs := baseFlagSet("file download")
+	out := fs.String("out", "", "destination path, a directory, or - for stdout (default: file name in current directory)")
+	timeout := fs.Duration("timeout", telegram.DefaultDownloadTimeout, "deadline for the download itself, separate from --request-timeout")
+	tokenOpt := registerTokenFlags(fs)
+	_ = fs.Parse(args)
+	if fileID == "" && fs.NArg() > 0 {
+		fileID = fs.Arg(0)
+	}
+	if fileID == "" {
+		fatal("usage: tgbot file download <file_id> [--out path] [flags]")
+	}
+	if *timeout <= 0 {
+		fatal("--timeout must be positive")
+	}
+
+	ctx := context.Background()
+	client := mustClient(tokenOpt)
+	file, err := client.GetFile(ctx, fileID)
+	if err != nil {
+		fatalf("file download failed: %v", err)
+	}
+	if file.FilePath == "" {
+		fatal("file download failed: getFile returned no file_path")
+	}
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	if *out == "-" {
+		if _, err := client.DownloadFile(ctx, file.FilePath, os.Stdout); err != nil {
+			fatalf("file download failed: %v", err)
+		}
+		return
+	}
+
+	dest := downloadDestination(*out, file.FilePath)
+	f, err := os.Create(dest)
+	if err != nil {
+		fatalf("create %s: %v", dest, err)
+	}
+	n, err := client.DownloadFile(ctx, file.FilePath, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(dest)
+		fatalf("file download failed: %v", err)
+	}
+	printJSON(mustMarshal(map[string]any{
+		"file_id":   file.FileID,
+		"file_path": file.FilePath,
+		"saved_to":  dest,
+		"size":      n,
+	}))
+}
+
+// downloadDestination resolves --out against the remote file name: empty
+// means the current directory and an existing directory keeps the name.
+func downloadDestination(out, filePath string) string {
+	name := filepath.Base(filepath.FromSlash(filePath))
+	if out == "" {
+		return name
+	}
+	if info, err := os.Stat(out); err == nil && info.IsDir() {
+		return filepath.Join(out, name)
+	}
+	return out
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/synthetic"
+)
+
+// webhookSecretHeader is the header Telegram signs webhook requests with.
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+func runUpdatesInject(args []string) {
+	fs := baseFlagSet("updates inject")
+	to := fs.String("to", "", "webhook url to post the update to")
+	updateType := fs.String("type", "message", "update type: "+strings.Join(synthetic.Types, "|"))
+	text := fs.String("text", "", "message text (join request bio for chat_join_request)")
+	data := fs.String("data", "", "callback_query data")
+	query := fs.String("query", "", "inline_query text")
+	fromID := fs.Int64("from-id", 0, "sender user id (default 42)")
+	fromUsername := fs.String("from-username", "", "sender username")
+	fromName := fs.String("from-name", "", "sender first name")
+	chatID := fs.Int64("chat-id", 0, "chat id (default the sender's private chat)")
+	chatType := fs.String("chat-type", "", "chat type: private|group|supergroup|channel")
+	chatTitle := fs.String("chat-title", "", "chat title for group chats")
+	messageID := fs.Int64("message-id", 0, "message id")
+	updateID := fs.Int64("update-id", 0, "update id")
+	status := fs.String("status", "member", "new member status for chat_member/my_chat_member")
+	secret := fs.String("secret", "", "secret token sent as "+webhookSecretHeader)
+	timeout := fs.Duration("timeout", 10*time.Second, "webhook request timeout")
+	dryRun := fs.Bool("dry-run", false, "print the update instead of posting it")
+	_ = fs.Parse(args)
+
+	update, err := synthetic.Build(synthetic.Options{
+		Type:         *updateType,
+		UpdateID:     *updateID,
+		Text:         *text,
+		Data:         *data,
+		Query:        *query,
+		FromID:       *fromID,
+		FromUsername: *fromUsername,
+		FromName:     *fromName,
+		ChatID:       *chatID,
+		ChatType:     *chatType,
+		ChatTitle:    *chatTitle,
+		MessageID:    *messageID,
+		Status:       *status,
+	})
+	if err != nil {
+		fatalf("updates inject: %v", err)
+	}
+	payload := mustMarshal(update)
+	if *dryRun {
+		printJSON(payload)
+		return
+	}
+	if *to == "" {
+		fatal("--to is required (or use --dry-run)")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	respStatus, body, err := postWebhook(ctx, *to, *secret, payload)
+	if err != nil {
+		fatalf("updates inject failed: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "[info] POST %s update_id=%d -> %s\n", *to, update.UpdateID, respStatus)
+	if method := webhookReplyMethod(body); method != "" {
+		fmt.Fprintf(os.Stderr, "[info] webhook replied inline with method %s\n", method)
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		printJSON(body)
+	}
+	if !strings.HasPrefix(respStatus, "2") {
+		os.Exit(1)
+	}
+}
+
+// postWebhook delivers an update the way Telegram does and returns the
+// response status and body.
+func postWebhook(ctx context.Context, url, secret string, payload []byte) (string, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return "", nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if secret != "" {
+		req.Header.Set(webhookSecretHeader, secret)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, err
+	}
+	return resp.Status, body, nil
+}
+
+// webhookReplyMethod returns the Bot API method a backend answered a webhook
+// request with, if its response is such an inline reply.
+func webhookReplyMethod(body []byte) string {
+	var reply struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal(body, &reply); err != nil {
+		return ""
+	}
+	return reply.Method
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCommandUpdatesInject(t *testing.T) {
+	var gotSecret string
+	var got map[string]json.RawMessage
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotSecret = r.Header.Get(webhookSecretHeader)
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &got)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"method":"sendMessage","chat_id":42,"text":"welcome"}`))
+	}))
+	defer backend.Close()
+
+	res := runOK(t, "updates", "inject", "--to", backend.URL, "--text", "/start ref123", "--from-id", "42", "--chat-type", "private", "--secret", "s3cret")
+	if gotSecret != "s3cret" {
+		t.Fatalf("expected secret header, got %q", gotSecret)
+	}
+	if !strings.Contains(string(got["message"]), `"text":"/start ref123"`) {
+		t.Fatalf("unexpected update posted: %s", got["message"])
+	}
+	if !strings.Contains(res.stderr, "replied inline with method sendMessage") {
+		t.Fatalf("expected reply method on stderr, got %s", res.stderr)
+	}
+	if !strings.Contains(res.stdout, `"text": "welcome"`) {
+		t.Fatalf("expected backend response on stdout, got %s", res.stdout)
+	}
+}
+
+func TestCommandUpdatesInjectDryRunAndFailure(t *testing.T) {
+	res := runOK(t, "updates", "inject", "--dry-run", "--type", "callback_query", "--data", "vote:1")
+	if !strings.Contains(res.stdout, `"data": "vote:1"`) {
+		t.Fatalf("expected callback query in dry run, got %s", res.stdout)
+	}
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer backend.Close()
+	failed := runCLI(t, nil, "updates", "inject", "--to", backend.URL, "--text", "hi")
+	if failed.code == 0 || !strings.Contains(failed.stderr, "403 Forbidden") {
+		t.Fatalf("expected failure on 403, got %d: %s", failed.code, failed.stderr)
+	}
+}
@@ -0,0 +1,324 @@
+// Package archive stores updates and the bot's own API results in a local
+// SQLite database with normalized chats, users and messages tables, and
+// reads them back offline.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS chats (
+	id         INTEGER PRIMARY KEY,
+	type       TEXT NOT NULL,
+	title      TEXT,
+	username   TEXT,
+	first_name TEXT,
+	last_name  TEXT,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS users (
+	id         INTEGER PRIMARY KEY,
+	is_bot     INTEGER NOT NULL,
+	first_name TEXT,
+	last_name  TEXT,
+	username   TEXT,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS updates (
+	bot_id      INTEGER NOT NULL,
+	update_id   INTEGER NOT NULL,
+	type        TEXT NOT NULL,
+	received_at TEXT NOT NULL,
+	chat_id     INTEGER,
+	user_id     INTEGER,
+	raw         TEXT NOT NULL,
+	PRIMARY KEY (bot_id, update_id)
+);
+CREATE TABLE IF NOT EXISTS messages (
+	bot_id              INTEGER NOT NULL,
+	chat_id             INTEGER NOT NULL,
+	message_id          INTEGER NOT NULL,
+	direction           TEXT NOT NULL,
+	from_id             INTEGER,
+	date                INTEGER NOT NULL,
+	edit_date           INTEGER,
+	thread_id           INTEGER,
+	reply_to_message_id INTEGER,
+	text                TEXT,
+	caption             TEXT,
+	media               TEXT,
+	raw                 TEXT NOT NULL,
+	PRIMARY KEY (bot_id, chat_id, message_id)
+);
+CREATE TABLE IF NOT EXISTS api_results (
+	id        INTEGER PRIMARY KEY AUTOINCREMENT,
+	bot_id    INTEGER NOT NULL,
+	method    TEXT NOT NULL,
+	called_at TEXT NOT NULL,
+	result    TEXT
+);
+CREATE INDEX IF NOT EXISTS messages_chat_date ON messages (chat_id, date);
+CREATE INDEX IF NOT EXISTS updates_chat ON updates (chat_id);
+`
+
+// timeFormat has fixed-width fractions so stored times sort as text.
+const timeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// Directions of an archived message.
+const (
+	Incoming = "in"
+	Outgoing = "out"
+)
+
+// Archive is an open archive database.
+type Archive struct {
+	db *sql.DB
+}
+
+// Open opens or creates the archive at path.
+func Open(path string) (*Archive, error) {
+	db, err := openDB(path, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create archive schema: %w", err)
+	}
+	return &Archive{db: db}, nil
+}
+
+// OpenReadOnly opens an existing archive for the offline commands.
+func OpenReadOnly(path string) (*Archive, error) {
+	db, err := openDB(path, true)
+	if err != nil {
+		return nil, err
+	}
+	// Fail early on a missing or foreign file rather than on the first query.
+	if err := db.QueryRow(`SELECT count(*) FROM updates`).Scan(new(int64)); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("open archive %s: %w", path, err)
+	}
+	return &Archive{db: db}, nil
+}
+
+func openDB(path string, readOnly bool) (*sql.DB, error) {
+	q := url.Values{}
+	q.Add("_pragma", "busy_timeout(5000)")
+	if readOnly {
+		q.Set("mode", "ro")
+	} else {
+		q.Add("_pragma", "journal_mode(WAL)")
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+q.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("open archive %s: %w", path, err)
+	}
+	// One connection serializes writers from concurrent pollers and
+	// dispatcher workers without SQLITE_BUSY retries.
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// Bot returns the view of the archive for one bot, identified by the
+// numeric id that prefixes its token.
+func (a *Archive) Bot(botID int64) *BotArchive {
+	return &BotArchive{a: a, botID: botID}
+}
+
+// BotArchive writes one bot's updates and API results. It implements
+// polling.UpdateRecorder.
+type BotArchive struct {
+	a     *Archive
+	botID int64
+}
+
+// Record stores update and the chats, users and messages it carries.
+func (b *BotArchive) Record(update telegram.Update, receivedAt time.Time) error {
+	ctx := context.Background()
+	tx, err := b.a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("archive update %d: %w", update.UpdateID, err)
+	}
+	defer tx.Rollback()
+
+	now := receivedAt.UTC().Format(timeFormat)
+	var chatID, userID any
+	if chat := update.EffectiveChat(); chat != nil {
+		chatID = chat.ID
+		if err := upsertChat(tx, *chat, now); err != nil {
+			return err
+		}
+	}
+	if user := update.EffectiveUser(); user != nil {
+		userID = user.ID
+		if err := upsertUser(tx, *user, now); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT OR REPLACE INTO updates (bot_id, update_id, type, received_at, chat_id, user_id, raw) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		b.botID, update.UpdateID, update.Type(), now, chatID, userID, string(update.Raw)); err != nil {
+		return fmt.Errorf("archive update %d: %w", update.UpdateID, err)
+	}
+	if msg, raw := updateMessage(update); msg != nil {
+		if err := b.upsertMessage(tx, msg, raw, Incoming, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// RecordResult stores the result of a successful API call. Messages in the
+// result (send*, edit*, forward*, copy*) are added to the messages table as
+// outgoing; read-only get* calls are skipped.
+func (b *BotArchive) RecordResult(method string, result json.RawMessage) error {
+	if strings.HasPrefix(method, "get") {
+		return nil
+	}
+	ctx := context.Background()
+	tx, err := b.a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("archive %s result: %w", method, err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(timeFormat)
+	if _, err := tx.Exec(`INSERT INTO api_results (bot_id, method, called_at, result) VALUES (?, ?, ?, ?)`,
+		b.botID, method, now, string(result)); err != nil {
+		return fmt.Errorf("archive %s result: %w", method, err)
+	}
+	for _, raw := range resultMessages(result) {
+		var msg telegram.Message
+		if json.Unmarshal(raw, &msg) != nil || msg.MessageID == 0 || msg.Chat.ID == 0 {
+			continue
+		}
+		if err := upsertChat(tx, msg.Chat, now); err != nil {
+			return err
+		}
+		if err := b.upsertMessage(tx, &msg, raw, Outgoing, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func upsertChat(tx *sql.Tx, c telegram.Chat, now string) error {
+	_, err := tx.Exec(`INSERT INTO chats (id, type, title, username, first_name, last_name, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET type = excluded.type, title = excluded.title, username = excluded.username,
+			first_name = excluded.first_name, last_name = excluded.last_name, updated_at = excluded.updated_at`,
+		c.ID, c.Type, c.Title, c.Username, c.FirstName, c.LastName, now)
+	if err != nil {
+		return fmt.Errorf("archive chat %d: %w", c.ID, err)
+	}
+	return nil
+}
+
+func upsertUser(tx *sql.Tx, u telegram.User, now string) error {
+	_, err := tx.Exec(`INSERT INTO users (id, is_bot, first_name, last_name, username, updated_at) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET is_bot = excluded.is_bot, first_name = excluded.first_name, last_name = excluded.last_name,
+			username = excluded.username, updated_at = excluded.updated_at`,
+		u.ID, u.IsBot, u.FirstName, u.LastName, u.Username, now)
+	if err != nil {
+		return fmt.Errorf("archive user %d: %w", u.ID, err)
+	}
+	return nil
+}
+
+// upsertMessage stores msg; an edit replaces the stored text and sets
+// edit_date but keeps the original direction.
+func (b *BotArchive) upsertMessage(tx *sql.Tx, msg *telegram.Message, raw json.RawMessage, direction, now string) error {
+	if msg.From != nil {
+		if err := upsertUser(tx, *msg.From, now); err != nil {
+			return err
+		}
+	}
+	var fromID, editDate, threadID, replyTo any
+	if msg.From != nil {
+		fromID = msg.From.ID
+	}
+	if msg.EditDate != 0 {
+		editDate = msg.EditDate
+	}
+	if msg.MessageThreadID != 0 {
+		threadID = msg.MessageThreadID
+	}
+	// Topic messages without an explicit reply reply to the topic's
+	// creation; that is not a reply worth showing.
+	if msg.ReplyToMessage != nil && (!msg.IsTopicMessage || msg.ReplyToMessage.ForumTopicCreated == nil) {
+		replyTo = msg.ReplyToMessage.MessageID
+	}
+	_, err := tx.Exec(`INSERT INTO messages (bot_id, chat_id, message_id, direction, from_id, date, edit_date, thread_id, reply_to_message_id, text, caption, media, raw)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (bot_id, chat_id, message_id) DO UPDATE SET edit_date = excluded.edit_date, text = excluded.text,
+			caption = excluded.caption, media = excluded.media, raw = excluded.raw`,
+		b.botID, msg.Chat.ID, msg.MessageID, direction, fromID, msg.Date, editDate, threadID, replyTo,
+		msg.Text, msg.Caption, mediaKind(raw), string(raw))
+	if err != nil {
+		return fmt.Errorf("archive message %d in chat %d: %w", msg.MessageID, msg.Chat.ID, err)
+	}
+	return nil
+}
+
+// updateMessage returns the message an update carries together with its
+// raw JSON, which the typed model does not keep for nested objects.
+func updateMessage(update telegram.Update) (*telegram.Message, json.RawMessage) {
+	switch update.Type() {
+	case "message", "edited_message", "channel_post", "edited_channel_post":
+	default:
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(update.Raw, &fields) != nil {
+		return nil, nil
+	}
+	return update.EffectiveMessage(), fields[update.Type()]
+}
+
+// resultMessages returns the message objects in an API result: the result
+// itself or the elements of an array (sendMediaGroup).
+func resultMessages(result json.RawMessage) []json.RawMessage {
+	trimmed := strings.TrimSpace(string(result))
+	if strings.HasPrefix(trimmed, "[") {
+		var list []json.RawMessage
+		if json.Unmarshal(result, &list) != nil {
+			return nil
+		}
+		return list
+	}
+	if strings.HasPrefix(trimmed, "{") {
+		return []json.RawMessage{result}
+	}
+	return nil
+}
+
+// mediaKinds are the message fields rendered as placeholders, in the order
+// they are checked.
+var mediaKinds = []string{"photo", "video", "animation", "audio", "voice", "video_note", "document", "sticker", "location", "venue", "contact", "poll", "dice"}
+
+func mediaKind(raw json.RawMessage) string {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(raw, &fields) != nil {
+		return ""
+	}
+	for _, kind := range mediaKinds {
+		if _, ok := fields[kind]; ok {
+			return kind
+		}
+	}
+	return ""
+}
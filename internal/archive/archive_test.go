@@ -0,0 +1,134 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/session"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func mustParse(t *testing.T, raw string) telegram.Update {
+	t.Helper()
+	u, err := telegram.ParseUpdate([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse update: %v", err)
+	}
+	return u
+}
+
+func openTemp(t *testing.T) (*Archive, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bot.db")
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a, path
+}
+
+const (
+	helloUpdate = `{"update_id":10,"message":{"message_id":1,"date":1700000000,"chat":{"id":-100,"type":"group","title":"Support"},"from":{"id":42,"is_bot":false,"first_name":"Alice","username":"alice"},"text":"hello"}}`
+	editUpdate  = `{"update_id":11,"edited_message":{"message_id":1,"date":1700000000,"edit_date":1700000100,"chat":{"id":-100,"type":"group","title":"Support"},"from":{"id":42,"is_bot":false,"first_name":"Alice","username":"alice"},"text":"hello there"}}`
+	photoUpdate = `{"update_id":12,"message":{"message_id":3,"date":1700000200,"chat":{"id":42,"type":"private","first_name":"Alice"},"from":{"id":42,"is_bot":false,"first_name":"Alice"},"photo":[{"file_id":"p"}],"caption":"look"}}`
+	sentResult  = `{"message_id":2,"date":1700000050,"chat":{"id":-100,"type":"group","title":"Support"},"from":{"id":7,"is_bot":true,"first_name":"Bot","username":"test_bot"},"reply_to_message":{"message_id":1,"date":1700000000,"chat":{"id":-100,"type":"group"}},"text":"hi alice"}`
+)
+
+func TestRecordNormalizesUpdatesAndResults(t *testing.T) {
+	a, _ := openTemp(t)
+	bot := a.Bot(7)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, raw := range []string{helloUpdate, editUpdate, photoUpdate} {
+		if err := bot.Record(mustParse(t, raw), base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+	if err := bot.RecordResult("sendMessage", json.RawMessage(sentResult)); err != nil {
+		t.Fatalf("RecordResult returned error: %v", err)
+	}
+	if err := bot.RecordResult("getChat", json.RawMessage(`{"id":-100}`)); err != nil {
+		t.Fatalf("RecordResult returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	res, err := a.Query(ctx, `SELECT message_id, direction, text, edit_date, reply_to_message_id, media FROM messages WHERE bot_id = ? ORDER BY chat_id, message_id`, 7)
+	if err != nil {
+		t.Fatalf("Query returned error: %v", err)
+	}
+	got := make([]string, 0, len(res.Rows))
+	for _, row := range res.Rows {
+		b, _ := json.Marshal(row)
+		got = append(got, string(b))
+	}
+	want := []string{
+		`[1,"in","hello there",1700000100,null,""]`,
+		`[2,"out","hi alice",null,1,""]`,
+		`[3,"in","",null,null,"photo"]`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected messages:\n%s", strings.Join(got, "\n"))
+	}
+
+	st, err := a.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats returned error: %v", err)
+	}
+	if st.Updates != 3 || st.UpdatesByType["edited_message"] != 1 || st.MessagesIn != 2 || st.MessagesOut != 1 ||
+		st.Chats != 2 || st.Users != 2 || st.APIResults != 1 || st.FirstReceivedAt != "2024-05-01T12:00:00.000000Z" {
+		t.Fatalf("unexpected stats: %+v", st)
+	}
+}
+
+func TestExportSessionAndReadOnly(t *testing.T) {
+	a, path := openTemp(t)
+	bot := a.Bot(7)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, raw := range []string{helloUpdate, photoUpdate, editUpdate} {
+		if err := bot.Record(mustParse(t, raw), base.Add(time.Duration(i)*time.Second)); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	ro, err := OpenReadOnly(path)
+	if err != nil {
+		t.Fatalf("OpenReadOnly returned error: %v", err)
+	}
+	defer ro.Close()
+	var out strings.Builder
+	n, err := ro.ExportSession(context.Background(), &out, ExportFilter{ChatID: -100})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 exported updates, got %d, %v", n, err)
+	}
+	entries, err := session.Read(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("export is not a valid session: %v", err)
+	}
+	if !entries[1].ReceivedAt.Equal(base.Add(2*time.Second)) || !strings.Contains(string(entries[1].Update), `"edited_message"`) {
+		t.Fatalf("unexpected exported entries: %+v", entries)
+	}
+
+	// A second bot in the same chat makes the bot a required filter.
+	if err := a.Bot(8).Record(mustParse(t, helloUpdate), base); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if _, err := ro.ExportSession(context.Background(), &out, ExportFilter{ChatID: -100}); !errors.Is(err, ErrSeveralBots) || !strings.Contains(err.Error(), "bot ids 7, 8") {
+		t.Fatalf("expected ErrSeveralBots, got %v", err)
+	}
+	if n, err := ro.ExportSession(context.Background(), io.Discard, ExportFilter{ChatID: -100, BotID: 8}); err != nil || n != 1 {
+		t.Fatalf("expected the second bot's update only, got %d, %v", n, err)
+	}
+
+	if _, err := ro.Query(context.Background(), `DELETE FROM updates`); err == nil {
+		t.Fatalf("read-only archive accepted a write")
+	}
+	if _, err := OpenReadOnly(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Fatalf("expected error for a missing archive")
+	}
+}
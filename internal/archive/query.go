@@ -0,0 +1,216 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/session"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// Result is the outcome of Query: column names and rows of plain values
+// (int64, float64, string or nil).
+type Result struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// Query runs a read-only SQL statement against the archive.
+func (a *Archive) Query(ctx context.Context, query string, args ...any) (Result, error) {
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Result{}, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{Columns: cols}
+	for rows.Next() {
+		values := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return Result{}, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		res.Rows = append(res.Rows, values)
+	}
+	return res, rows.Err()
+}
+
+// Stats summarizes an archive.
+type Stats struct {
+	Updates         int64            `json:"updates"`
+	UpdatesByType   map[string]int64 `json:"updates_by_type"`
+	MessagesIn      int64            `json:"messages_in"`
+	MessagesOut     int64            `json:"messages_out"`
+	Chats           int64            `json:"chats"`
+	Users           int64            `json:"users"`
+	APIResults      int64            `json:"api_results"`
+	FirstReceivedAt string           `json:"first_received_at,omitempty"`
+	LastReceivedAt  string           `json:"last_received_at,omitempty"`
+}
+
+func (a *Archive) Stats(ctx context.Context) (Stats, error) {
+	st := Stats{UpdatesByType: map[string]int64{}}
+	counts := []struct {
+		query string
+		dst   *int64
+	}{
+		{`SELECT count(*) FROM updates`, &st.Updates},
+		{`SELECT count(*) FROM messages WHERE direction = 'in'`, &st.MessagesIn},
+		{`SELECT count(*) FROM messages WHERE direction = 'out'`, &st.MessagesOut},
+		{`SELECT count(*) FROM chats`, &st.Chats},
+		{`SELECT count(*) FROM users`, &st.Users},
+		{`SELECT count(*) FROM api_results`, &st.APIResults},
+	}
+	for _, c := range counts {
+		if err := a.db.QueryRowContext(ctx, c.query).Scan(c.dst); err != nil {
+			return Stats{}, fmt.Errorf("archive stats: %w", err)
+		}
+	}
+	if err := a.db.QueryRowContext(ctx, `SELECT coalesce(min(received_at), ''), coalesce(max(received_at), '') FROM updates`).
+		Scan(&st.FirstReceivedAt, &st.LastReceivedAt); err != nil {
+		return Stats{}, fmt.Errorf("archive stats: %w", err)
+	}
+
+	rows, err := a.db.QueryContext(ctx, `SELECT type, count(*) FROM updates GROUP BY type`)
+	if err != nil {
+		return Stats{}, fmt.Errorf("archive stats: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var typ string
+		var n int64
+		if err := rows.Scan(&typ, &n); err != nil {
+			return Stats{}, fmt.Errorf("archive stats: %w", err)
+		}
+		st.UpdatesByType[typ] = n
+	}
+	return st, rows.Err()
+}
+
+// ExportFilter selects updates to export. Zero values match everything.
+type ExportFilter struct {
+	ChatID int64
+	Since  time.Time
+	// ThreadID keeps the messages of one forum topic or reply thread.
+	ThreadID int64
+	// BotID keeps what one bot archived. It is required when several bots
+	// archived the chat, or the whole archive when ChatID is 0.
+	BotID int64
+}
+
+// ErrSeveralBots is returned by an export without a BotID when more than
+// one bot archived what it selects.
+var ErrSeveralBots = errors.New("archived by several bots")
+
+// requireOneBot returns ErrSeveralBots when f has no BotID and table holds
+// rows of more than one bot for f's chat. table is a constant table name.
+func (a *Archive) requireOneBot(ctx context.Context, table string, f ExportFilter) error {
+	if f.BotID != 0 {
+		return nil
+	}
+	query := `SELECT DISTINCT bot_id FROM ` + table
+	var args []any
+	if f.ChatID != 0 {
+		query += ` WHERE chat_id = ?`
+		args = append(args, f.ChatID)
+	}
+	rows, err := a.db.QueryContext(ctx, query+` ORDER BY bot_id`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(ids) > 1 {
+		return fmt.Errorf("%w (bot ids %s)", ErrSeveralBots, strings.Join(ids, ", "))
+	}
+	return nil
+}
+
+// ExportSession writes the archived updates matching f as a session file,
+// oldest first, so they can be fed to `updates replay`. It returns the
+// number of updates written.
+func (a *Archive) ExportSession(ctx context.Context, w io.Writer, f ExportFilter) (int, error) {
+	if err := a.requireOneBot(ctx, "updates", f); err != nil {
+		return 0, fmt.Errorf("archive export: %w", err)
+	}
+	query := `SELECT received_at, raw FROM updates WHERE 1 = 1`
+	var args []any
+	if f.BotID != 0 {
+		query += ` AND bot_id = ?`
+		args = append(args, f.BotID)
+	}
+	if f.ChatID != 0 {
+		query += ` AND chat_id = ?`
+		args = append(args, f.ChatID)
+	}
+	if !f.Since.IsZero() {
+		query += ` AND received_at >= ?`
+		args = append(args, f.Since.UTC().Format(timeFormat))
+	}
+	query += ` ORDER BY received_at, update_id`
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("archive export: %w", err)
+	}
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		var receivedAt, raw string
+		if err := rows.Scan(&receivedAt, &raw); err != nil {
+			return n, fmt.Errorf("archive export: %w", err)
+		}
+		if f.ThreadID != 0 && !inThread(raw, f.ThreadID) {
+			continue
+		}
+		at, _ := time.Parse(time.RFC3339Nano, receivedAt)
+		line, err := json.Marshal(session.Entry{ReceivedAt: at, Update: json.RawMessage(raw)})
+		if err != nil {
+			return n, fmt.Errorf("archive export: %w", err)
+		}
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// inThread reports whether the raw update carries a message of threadID.
+// The updates table has no thread column, so the update is decoded.
+func inThread(raw string, threadID int64) bool {
+	update, err := telegram.ParseUpdate(json.RawMessage(raw))
+	if err != nil {
+		return false
+	}
+	m := update.EffectiveMessage()
+	return m != nil && m.MessageThreadID == threadID
+}
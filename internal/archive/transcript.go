@@ -0,0 +1,332 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// Transcript formats accepted by WriteTranscript.
+const (
+	FormatText     = "txt"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// transcriptTime is how timestamps appear in transcripts.
+const transcriptTime = "2006-01-02 15:04:05 MST"
+
+// Transcript is one chat's archived conversation, oldest message first.
+type Transcript struct {
+	ChatID     int64
+	Title      string
+	ExportedAt time.Time
+	Messages   []TranscriptMessage
+}
+
+// TranscriptMessage is a message prepared for display.
+type TranscriptMessage struct {
+	ID        int64
+	Direction string
+	From      string
+	Date      time.Time
+	// EditDate is zero for messages that were never edited.
+	EditDate time.Time
+	ThreadID int64
+	// Topic is the name of the forum topic ThreadID, empty when the
+	// archive never saw it.
+	Topic string
+	// Service describes a service message such as a topic's creation.
+	Service string
+	// ReplyTo is the id of the replied message, 0 if none; ReplyQuote is a
+	// short excerpt of it, empty when it is not in the archive.
+	ReplyTo    int64
+	ReplyQuote string
+	Text       string
+	Caption    string
+	Media      string
+}
+
+// Transcript loads the messages of one chat, both received and sent,
+// matching f. Since applies to the message date.
+func (a *Archive) Transcript(ctx context.Context, f ExportFilter) (Transcript, error) {
+	t := Transcript{ChatID: f.ChatID, ExportedAt: time.Now().UTC()}
+
+	var title, username, first, last sql.NullString
+	err := a.db.QueryRowContext(ctx, `SELECT title, username, first_name, last_name FROM chats WHERE id = ?`, f.ChatID).
+		Scan(&title, &username, &first, &last)
+	if err != nil && err != sql.ErrNoRows {
+		return Transcript{}, fmt.Errorf("archive transcript: %w", err)
+	}
+	t.Title = firstNonEmpty(title.String, strings.TrimSpace(first.String+" "+last.String), at(username.String), "chat "+strconv.FormatInt(f.ChatID, 10))
+
+	// Every bot in the chat archived its own copy of each message, under
+	// the same message ids, so one bot's view is exported.
+	if err := a.requireOneBot(ctx, "messages", f); err != nil {
+		return Transcript{}, fmt.Errorf("archive transcript: %w", err)
+	}
+	query := `SELECT m.message_id, m.direction, m.date, m.edit_date, m.thread_id, m.reply_to_message_id,
+			m.text, m.caption, m.media, m.from_id, u.username, u.first_name, u.last_name, m.raw
+		FROM messages m LEFT JOIN users u ON u.id = m.from_id
+		WHERE m.chat_id = ?`
+	args := []any{f.ChatID}
+	if f.BotID != 0 {
+		query += ` AND m.bot_id = ?`
+		args = append(args, f.BotID)
+	}
+	if !f.Since.IsZero() {
+		query += ` AND m.date >= ?`
+		args = append(args, f.Since.Unix())
+	}
+	if f.ThreadID != 0 {
+		query += ` AND m.thread_id = ?`
+		args = append(args, f.ThreadID)
+	}
+	query += ` ORDER BY m.date, m.message_id`
+
+	rows, err := a.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return Transcript{}, fmt.Errorf("archive transcript: %w", err)
+	}
+	defer rows.Close()
+	// Topic names are learned from the messages as they go by, so each
+	// message shows the name its topic had at the time.
+	topics := map[int64]string{}
+	for rows.Next() {
+		var (
+			raw                           string
+			m                             TranscriptMessage
+			date                          int64
+			editDate, threadID, replyTo   sql.NullInt64
+			fromID                        sql.NullInt64
+			text, caption, media          sql.NullString
+			fromUser, fromFirst, fromLast sql.NullString
+		)
+		if err := rows.Scan(&m.ID, &m.Direction, &date, &editDate, &threadID, &replyTo,
+			&text, &caption, &media, &fromID, &fromUser, &fromFirst, &fromLast, &raw); err != nil {
+			return Transcript{}, fmt.Errorf("archive transcript: %w", err)
+		}
+		m.Date = time.Unix(date, 0).UTC()
+		if editDate.Valid {
+			m.EditDate = time.Unix(editDate.Int64, 0).UTC()
+		}
+		m.ThreadID, m.ReplyTo = threadID.Int64, replyTo.Int64
+		m.Text, m.Caption, m.Media = text.String, caption.String, media.String
+		if fromID.Valid {
+			m.From = firstNonEmpty(at(fromUser.String), strings.TrimSpace(fromFirst.String+" "+fromLast.String), "user "+strconv.FormatInt(fromID.Int64, 10))
+		} else {
+			// Channel posts have no sender but the channel itself.
+			m.From = t.Title
+		}
+		var msg telegram.Message
+		if json.Unmarshal([]byte(raw), &msg) == nil {
+			learnTopic(&m, &msg, topics)
+		}
+		t.Messages = append(t.Messages, m)
+	}
+	if err := rows.Err(); err != nil {
+		return Transcript{}, fmt.Errorf("archive transcript: %w", err)
+	}
+
+	byID := make(map[int64]TranscriptMessage, len(t.Messages))
+	for _, m := range t.Messages {
+		byID[m.ID] = m
+	}
+	for i, m := range t.Messages {
+		if replied, ok := byID[m.ReplyTo]; ok && m.ReplyTo != 0 {
+			t.Messages[i].ReplyQuote = replied.From + ": " + excerpt(replied.summary(), 60)
+		}
+	}
+	return t, nil
+}
+
+// learnTopic names m's topic from msg, the decoded message, and records
+// renames in topics. A topic message only implies its topic's original
+// name, so it never overrides one already known.
+func learnTopic(m *TranscriptMessage, msg *telegram.Message, topics map[int64]string) {
+	switch {
+	case msg.ForumTopicCreated != nil:
+		if m.ThreadID == 0 {
+			m.ThreadID = m.ID
+		}
+		m.Service = fmt.Sprintf("created topic %q", msg.ForumTopicCreated.Name)
+	case msg.ForumTopicEdited != nil && msg.ForumTopicEdited.Name != "":
+		m.Service = fmt.Sprintf("renamed topic to %q", msg.ForumTopicEdited.Name)
+	case msg.ForumTopicEdited != nil:
+		m.Service = "changed topic icon"
+	}
+	if m.ThreadID == 0 {
+		return
+	}
+	if name := msg.TopicName(); name != "" && (m.Service != "" || topics[m.ThreadID] == "") {
+		topics[m.ThreadID] = name
+	}
+	m.Topic = topics[m.ThreadID]
+}
+
+// summary is the message's text, or its media placeholder and caption.
+func (m TranscriptMessage) summary() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	return strings.TrimSpace(m.Placeholder() + " " + m.Caption)
+}
+
+// Placeholder stands in for the message's media, e.g. "[photo]".
+func (m TranscriptMessage) Placeholder() string {
+	if m.Media == "" && m.Service != "" {
+		return "[" + m.Service + "]"
+	}
+	if m.Media == "" {
+		return ""
+	}
+	return "[" + strings.ReplaceAll(m.Media, "_", " ") + "]"
+}
+
+// Body is the message content: text, or the media placeholder followed by
+// the caption.
+func (m TranscriptMessage) Body() string {
+	if m.Text != "" {
+		return m.Text
+	}
+	body := strings.TrimSpace(m.Placeholder() + "\n" + m.Caption)
+	if body == "" {
+		return "[unsupported message]"
+	}
+	return body
+}
+
+// Meta lists the message id and its reply, thread and edit details.
+func (m TranscriptMessage) Meta() string {
+	parts := []string{"#" + strconv.FormatInt(m.ID, 10)}
+	if m.ReplyTo != 0 {
+		parts = append(parts, "reply to #"+strconv.FormatInt(m.ReplyTo, 10))
+	}
+	switch {
+	case m.Topic != "":
+		parts = append(parts, "topic "+m.Topic+" ("+strconv.FormatInt(m.ThreadID, 10)+")")
+	case m.ThreadID != 0:
+		parts = append(parts, "topic "+strconv.FormatInt(m.ThreadID, 10))
+	}
+	if !m.EditDate.IsZero() {
+		parts = append(parts, "edited "+m.EditDate.Format(transcriptTime))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteTranscript renders t as txt, markdown or html.
+func WriteTranscript(w io.Writer, t Transcript, format string) error {
+	switch format {
+	case FormatText:
+		return writeTextTranscript(w, t)
+	case FormatMarkdown:
+		return writeMarkdownTranscript(w, t)
+	case FormatHTML:
+		return htmlTranscript.Execute(w, t)
+	default:
+		return fmt.Errorf("unknown transcript format %q (want txt, markdown or html)", format)
+	}
+}
+
+func writeTextTranscript(w io.Writer, t Transcript) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Chat: %s (%d)\n", t.Title, t.ChatID)
+	fmt.Fprintf(&b, "Exported: %s, %d message(s)\n", t.ExportedAt.Format(transcriptTime), len(t.Messages))
+	for _, m := range t.Messages {
+		fmt.Fprintf(&b, "\n[%s] %s (%s)\n", m.Date.Format(transcriptTime), m.From, m.Meta())
+		if m.ReplyQuote != "" {
+			fmt.Fprintf(&b, "> %s\n", m.ReplyQuote)
+		}
+		b.WriteString(m.Body())
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeMarkdownTranscript(w io.Writer, t Transcript) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscape(t.Title))
+	fmt.Fprintf(&b, "Chat `%d`, exported %s, %d message(s).\n", t.ChatID, t.ExportedAt.Format(transcriptTime), len(t.Messages))
+	for _, m := range t.Messages {
+		fmt.Fprintf(&b, "\n**%s** · %s · _%s_\n\n", markdownEscape(m.From), m.Date.Format(transcriptTime), markdownEscape(m.Meta()))
+		if m.ReplyQuote != "" {
+			fmt.Fprintf(&b, "> %s\n\n", markdownEscape(m.ReplyQuote))
+		}
+		for _, line := range strings.Split(m.Body(), "\n") {
+			// Two trailing spaces keep the message's own line breaks.
+			fmt.Fprintf(&b, "%s  \n", markdownEscape(line))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", `*`, `\*`, `_`, `\_`, `[`, `\[`, `]`, `\]`,
+	`<`, `\<`, `>`, `\>`, `#`, `\#`, `|`, `\|`,
+)
+
+func markdownEscape(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+var htmlTranscript = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time": func(t time.Time) string { return t.Format(transcriptTime) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48em; margin: 2em auto; color: #222; }
+.msg { margin: 1em 0; padding: .5em .8em; border-radius: 6px; background: #f1f1f1; }
+.msg.out { background: #e3f0ff; margin-left: 4em; }
+.meta { font-size: .85em; color: #666; }
+.quote { border-left: 3px solid #aaa; padding-left: .5em; color: #555; font-size: .9em; }
+.body { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Chat {{.ChatID}}, exported {{time .ExportedAt}}, {{len .Messages}} message(s).</p>
+{{range .Messages}}<div class="msg {{.Direction}}" id="m{{.ID}}">
+<div class="meta"><strong>{{.From}}</strong> · {{time .Date}} · {{.Meta}}</div>
+{{if .ReplyQuote}}<div class="quote"><a href="#m{{.ReplyTo}}">{{.ReplyQuote}}</a></div>
+{{end}}<div class="body">{{.Body}}</div>
+</div>
+{{end}}</body>
+</html>
+`))
+
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n]) + "…"
+	}
+	return s
+}
+
+func at(username string) string {
+	if username == "" {
+		return ""
+	}
+	return "@" + username
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package archive
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTranscriptFormats(t *testing.T) {
+	a, _ := openTemp(t)
+	bot := a.Bot(7)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, raw := range []string{helloUpdate, editUpdate} {
+		if err := bot.Record(mustParse(t, raw), base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+	if err := bot.RecordResult("sendMessage", json.RawMessage(sentResult)); err != nil {
+		t.Fatalf("RecordResult returned error: %v", err)
+	}
+	media := `{"update_id":13,"message":{"message_id":4,"date":1700000300,"chat":{"id":-100,"type":"group","title":"Support"},"from":{"id":43,"is_bot":false,"first_name":"Bob"},"message_thread_id":9,"video_note":{"file_id":"v"}}}`
+	if err := bot.Record(mustParse(t, media), base); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+
+	tr, err := a.Transcript(context.Background(), ExportFilter{ChatID: -100})
+	if err != nil {
+		t.Fatalf("Transcript returned error: %v", err)
+	}
+	if tr.Title != "Support" || len(tr.Messages) != 3 {
+		t.Fatalf("unexpected transcript: %+v", tr)
+	}
+
+	var txt strings.Builder
+	if err := WriteTranscript(&txt, tr, FormatText); err != nil {
+		t.Fatalf("WriteTranscript returned error: %v", err)
+	}
+	for _, want := range []string{
+		"Chat: Support (-100)",
+		"[2023-11-14 22:13:20 UTC] @alice (#1, edited 2023-11-14 22:15:00 UTC)\nhello there\n",
+		"@test_bot (#2, reply to #1)\n> @alice: hello there\nhi alice\n",
+		"Bob (#4, topic 9)\n[video note]\n",
+	} {
+		if !strings.Contains(txt.String(), want) {
+			t.Fatalf("txt transcript missing %q:\n%s", want, txt.String())
+		}
+	}
+
+	var md strings.Builder
+	if err := WriteTranscript(&md, tr, FormatMarkdown); err != nil {
+		t.Fatalf("WriteTranscript returned error: %v", err)
+	}
+	if !strings.Contains(md.String(), "# Support\n") || !strings.Contains(md.String(), "**@test\\_bot**") || !strings.Contains(md.String(), "> @alice: hello there\n") {
+		t.Fatalf("unexpected markdown transcript:\n%s", md.String())
+	}
+
+	md.Reset()
+	topical := Transcript{Title: "Support", Messages: []TranscriptMessage{{ID: 5, From: "Bob", Date: base, ThreadID: 9, Topic: "*bugs*_v2", Text: "hi"}}}
+	if err := WriteTranscript(&md, topical, FormatMarkdown); err != nil {
+		t.Fatalf("WriteTranscript returned error: %v", err)
+	}
+	if !strings.Contains(md.String(), `_\#5, topic \*bugs\*\_v2 (9)_`) {
+		t.Fatalf("expected an escaped topic name:\n%s", md.String())
+	}
+
+	var html strings.Builder
+	if err := WriteTranscript(&html, tr, FormatHTML); err != nil {
+		t.Fatalf("WriteTranscript returned error: %v", err)
+	}
+	if !strings.Contains(html.String(), `<div class="msg out" id="m2">`) || !strings.Contains(html.String(), `<a href="#m1">@alice: hello there</a>`) {
+		t.Fatalf("unexpected html transcript:\n%s", html.String())
+	}
+
+	since, err := a.Transcript(context.Background(), ExportFilter{ChatID: -100, Since: time.Unix(1700000100, 0)})
+	if err != nil || len(since.Messages) != 1 || since.Messages[0].ID != 4 {
+		t.Fatalf("unexpected filtered transcript: %+v, %v", since, err)
+	}
+
+	if err := WriteTranscript(&txt, tr, "pdf"); err == nil {
+		t.Fatal("expected error for unknown format")
+	}
+
+	// A second bot in the group archives the same messages again.
+	if err := a.Bot(8).Record(mustParse(t, helloUpdate), base); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if _, err := a.Transcript(context.Background(), ExportFilter{ChatID: -100}); !errors.Is(err, ErrSeveralBots) {
+		t.Fatalf("expected ErrSeveralBots, got %v", err)
+	}
+	one, err := a.Transcript(context.Background(), ExportFilter{ChatID: -100, BotID: 7})
+	if err != nil || len(one.Messages) != 3 {
+		t.Fatalf("expected the first bot's 3 messages, got %+v, %v", one.Messages, err)
+	}
+}
+
+func TestTranscriptEscapesHTML(t *testing.T) {
+	a, _ := openTemp(t)
+	raw := `{"update_id":1,"message":{"message_id":1,"date":1700000000,"chat":{"id":5,"type":"private","first_name":"Eve"},"from":{"id":5,"is_bot":false,"first_name":"Eve"},"text":"<script>alert(1)</script>"}}`
+	if err := a.Bot(7).Record(mustParse(t, raw), time.Now()); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	tr, err := a.Transcript(context.Background(), ExportFilter{ChatID: 5})
+	if err != nil {
+		t.Fatalf("Transcript returned error: %v", err)
+	}
+	var html strings.Builder
+	if err := WriteTranscript(&html, tr, FormatHTML); err != nil {
+		t.Fatalf("WriteTranscript returned error: %v", err)
+	}
+	if strings.Contains(html.String(), "<script>") || !strings.Contains(html.String(), "&lt;script&gt;") {
+		t.Fatalf("message text not escaped:\n%s", html.String())
+	}
+}
+
+func TestTranscriptTopicNames(t *testing.T) {
+	a, _ := openTemp(t)
+	bot := a.Bot(7)
+	chat := `"chat":{"id":-100,"type":"supergroup","title":"Support","is_forum":true}`
+	created := `{"message_id":10,"message_thread_id":10,"date":1700000000,` + chat + `,"is_topic_message":true,"forum_topic_created":{"name":"Bugs","icon_color":7322096}}`
+	for _, raw := range []string{
+		`{"update_id":1,"message":` + created + `}`,
+		`{"update_id":2,"message":{"message_id":11,"message_thread_id":10,"date":1700000060,` + chat + `,"from":{"id":42,"is_bot":false,"first_name":"A"},"is_topic_message":true,"reply_to_message":` + created + `,"text":"crash"}}`,
+		`{"update_id":3,"message":{"message_id":12,"message_thread_id":10,"date":1700000120,` + chat + `,"is_topic_message":true,"forum_topic_edited":{"name":"Bug reports"}}}`,
+		`{"update_id":4,"message":{"message_id":13,"message_thread_id":10,"date":1700000180,` + chat + `,"from":{"id":42,"is_bot":false,"first_name":"A"},"is_topic_message":true,"reply_to_message":` + created + `,"text":"still crashing"}}`,
+		`{"update_id":5,"message":{"message_id":14,"date":1700000240,` + chat + `,"from":{"id":42,"is_bot":false,"first_name":"A"},"text":"general chat"}}`,
+	} {
+		if err := bot.Record(mustParse(t, raw), time.Now()); err != nil {
+			t.Fatalf("Record returned error: %v", err)
+		}
+	}
+
+	tr, err := a.Transcript(context.Background(), ExportFilter{ChatID: -100, ThreadID: 10})
+	if err != nil || len(tr.Messages) != 4 {
+		t.Fatalf("unexpected topic transcript %+v: %v", tr, err)
+	}
+	var txt strings.Builder
+	if err := WriteTranscript(&txt, tr, FormatText); err != nil {
+		t.Fatalf("WriteTranscript returned error: %v", err)
+	}
+	for _, want := range []string{
+		"(#10, topic Bugs (10))\n[created topic \"Bugs\"]\n",
+		"A (#11, topic Bugs (10))\ncrash\n",
+		"(#12, topic Bug reports (10))\n[renamed topic to \"Bug reports\"]\n",
+		"A (#13, topic Bug reports (10))\nstill crashing\n",
+	} {
+		if !strings.Contains(txt.String(), want) {
+			t.Fatalf("topic transcript missing %q:\n%s", want, txt.String())
+		}
+	}
+}
@@ -0,0 +1,441 @@
+// Package chats keeps a local registry of the chats a bot has seen, with
+// user-defined aliases, so commands can take a chat by name instead of by
+// numeric id.
+package chats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// seenInterval limits how often a chat that did not change is rewritten
+// just to move its last-seen time.
+const seenInterval = time.Minute
+
+// Chat is a registry entry.
+type Chat struct {
+	ID        int64     `json:"id"`
+	Type      string    `json:"type"`
+	Title     string    `json:"title,omitempty"`
+	Username  string    `json:"username,omitempty"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// Name is how the chat is shown: its title, a private chat's user name, or
+// its username.
+func (c Chat) Name() string {
+	switch {
+	case c.Title != "":
+		return c.Title
+	case c.FirstName != "" || c.LastName != "":
+		return strings.TrimSpace(c.FirstName + " " + c.LastName)
+	case c.Username != "":
+		return "@" + c.Username
+	}
+	return strconv.FormatInt(c.ID, 10)
+}
+
+type fileData struct {
+	Bots map[string]*botData `json:"bots"`
+}
+
+type botData struct {
+	Chats   map[string]Chat  `json:"chats"`
+	Aliases map[string]int64 `json:"aliases"`
+}
+
+// Registry is one bot's view of the registry file. Every change re-reads
+// the file under a lock before writing it, so listeners of several bots
+// sharing the file and a separate `chats alias` call do not overwrite each
+// other.
+type Registry struct {
+	path  string
+	botID int64
+	file  *sync.Mutex
+
+	mu   sync.Mutex
+	data *botData
+}
+
+// fileLocks serializes writers of one file within the process; lockFile
+// does the same across processes where the platform supports it.
+var fileLocks sync.Map
+
+func fileLock(path string) *sync.Mutex {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	mu, _ := fileLocks.LoadOrStore(path, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// Open loads the registry of botID from path. A missing file is an empty
+// registry.
+func Open(path string, botID int64) (*Registry, error) {
+	r := &Registry{path: path, botID: botID, file: fileLock(path)}
+	f, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.data = f.bot(botID)
+	return r, nil
+}
+
+func (r *Registry) load() (*fileData, error) {
+	f := &fileData{}
+	data, err := os.ReadFile(r.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return f, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read chat registry: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse chat registry %s: %w", r.path, err)
+	}
+	return f, nil
+}
+
+func (f *fileData) bot(botID int64) *botData {
+	if f.Bots == nil {
+		f.Bots = map[string]*botData{}
+	}
+	key := strconv.FormatInt(botID, 10)
+	b := f.Bots[key]
+	if b == nil {
+		b = &botData{}
+		f.Bots[key] = b
+	}
+	if b.Chats == nil {
+		b.Chats = map[string]Chat{}
+	}
+	if b.Aliases == nil {
+		b.Aliases = map[string]int64{}
+	}
+	return b
+}
+
+// update applies change to the bot's section of a freshly read file and
+// writes it back atomically.
+func (r *Registry) update(change func(b *botData) error) error {
+	r.file.Lock()
+	defer r.file.Unlock()
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o700); err != nil {
+		return fmt.Errorf("write chat registry: %w", err)
+	}
+	unlock, err := lockFile(r.path + ".lock")
+	if err != nil {
+		return fmt.Errorf("lock chat registry: %w", err)
+	}
+	defer unlock()
+
+	f, err := r.load()
+	if err != nil {
+		return err
+	}
+	b := f.bot(r.botID)
+	if err := change(b); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode chat registry: %w", err)
+	}
+	if err := writeAtomic(r.path, append(data, '\n')); err != nil {
+		return fmt.Errorf("write chat registry: %w", err)
+	}
+	r.mu.Lock()
+	r.data = b
+	r.mu.Unlock()
+	return nil
+}
+
+// writeAtomic replaces path with data through a uniquely named temporary
+// file in the same directory.
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Learn adds or refreshes chat. The file is only written when something
+// changed, or the last-seen time is more than a minute old.
+func (r *Registry) Learn(chat telegram.Chat, seen time.Time) error {
+	if chat.ID == 0 {
+		return nil
+	}
+	entry := Chat{
+		ID:        chat.ID,
+		Type:      chat.Type,
+		Title:     chat.Title,
+		Username:  chat.Username,
+		FirstName: chat.FirstName,
+		LastName:  chat.LastName,
+		LastSeen:  seen.UTC().Truncate(time.Second),
+	}
+	key := strconv.FormatInt(chat.ID, 10)
+
+	r.mu.Lock()
+	old, ok := r.data.Chats[key]
+	r.mu.Unlock()
+	if ok && sameChat(old, entry) && entry.LastSeen.Sub(old.LastSeen) < seenInterval {
+		return nil
+	}
+	return r.update(func(b *botData) error {
+		if cur, ok := b.Chats[key]; ok && cur.LastSeen.After(entry.LastSeen) {
+			entry.LastSeen = cur.LastSeen
+		}
+		b.Chats[key] = entry
+		return nil
+	})
+}
+
+func sameChat(a, b Chat) bool {
+	a.LastSeen, b.LastSeen = time.Time{}, time.Time{}
+	return a == b
+}
+
+// Record learns the chat of update. It implements polling.UpdateRecorder.
+func (r *Registry) Record(update telegram.Update, receivedAt time.Time) error {
+	chat := update.EffectiveChat()
+	if chat == nil {
+		return nil
+	}
+	return r.Learn(*chat, receivedAt)
+}
+
+// Chats returns the known chats, most recently seen first.
+func (r *Registry) Chats() []Chat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Chat, 0, len(r.data.Chats))
+	for _, c := range r.data.Chats {
+		out = append(out, c)
+	}
+	sortBySeen(out)
+	return out
+}
+
+// Aliases returns the aliases pointing at chatID, sorted.
+func (r *Registry) Aliases(chatID int64) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var out []string
+	for name, id := range r.data.Aliases {
+		if id == chatID {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+var aliasPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// SetAlias points name at chatID, replacing any previous target.
+func (r *Registry) SetAlias(name string, chatID int64) error {
+	name = strings.TrimPrefix(name, "@")
+	if !aliasPattern.MatchString(name) {
+		return fmt.Errorf("invalid alias %q: use letters, digits, '-', '_' and '.'", name)
+	}
+	if _, err := strconv.ParseInt(name, 10, 64); err == nil {
+		return fmt.Errorf("invalid alias %q: it would read as a chat id", name)
+	}
+	return r.update(func(b *botData) error {
+		b.Aliases[name] = chatID
+		return nil
+	})
+}
+
+// RemoveAlias deletes name.
+func (r *Registry) RemoveAlias(name string) error {
+	name = strings.TrimPrefix(name, "@")
+	return r.update(func(b *botData) error {
+		if _, ok := b.Aliases[name]; !ok {
+			return fmt.Errorf("unknown alias %q", name)
+		}
+		delete(b.Aliases, name)
+		return nil
+	})
+}
+
+// Resolution is the outcome of Resolve.
+type Resolution struct {
+	// ChatID is the value to send as chat_id: a numeric id, or an
+	// @username left for Telegram to resolve.
+	ChatID string
+	// Chat is the registry entry, when the chat is known.
+	Chat *Chat
+	// Warning is set when the reference matched several chats.
+	Warning string
+}
+
+// Resolve turns a chat reference into a chat id. It accepts, in order:
+//
+//   - a numeric id, used as is;
+//   - an alias, with or without a leading @;
+//   - the username of a known chat, with or without @;
+//   - a known chat's title, matched case-insensitively and ignoring
+//     punctuation, first exactly and then as a substring.
+//
+// An unknown @username is passed through for Telegram to resolve. When a
+// name matches several chats the most recently seen one wins and Warning
+// lists the others.
+func (r *Registry) Resolve(ref string) (Resolution, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Resolution{}, errors.New("empty chat reference")
+	}
+	if id, err := strconv.ParseInt(ref, 10, 64); err == nil {
+		res := Resolution{ChatID: ref}
+		if c, ok := r.lookup(id); ok {
+			res.Chat = &c
+		}
+		return res, nil
+	}
+
+	name := strings.TrimPrefix(ref, "@")
+	r.mu.Lock()
+	id, isAlias := r.data.Aliases[name]
+	r.mu.Unlock()
+	if isAlias {
+		res := Resolution{ChatID: strconv.FormatInt(id, 10)}
+		if c, ok := r.lookup(id); ok {
+			res.Chat = &c
+		}
+		return res, nil
+	}
+
+	chats := r.Chats()
+	matchers := []func(c Chat) bool{
+		func(c Chat) bool { return c.Username != "" && strings.EqualFold(c.Username, name) },
+	}
+	if !strings.HasPrefix(ref, "@") {
+		key := normalize(ref)
+		matchers = append(matchers,
+			func(c Chat) bool { return key != "" && normalize(c.Name()) == key },
+			func(c Chat) bool { return key != "" && strings.Contains(normalize(c.Name()), key) },
+		)
+	}
+	for _, match := range matchers {
+		var found []Chat
+		for _, c := range chats {
+			if match(c) {
+				found = append(found, c)
+			}
+		}
+		if len(found) == 0 {
+			continue
+		}
+		res := Resolution{ChatID: strconv.FormatInt(found[0].ID, 10), Chat: &found[0]}
+		if len(found) > 1 {
+			names := make([]string, len(found))
+			for i, c := range found {
+				names[i] = fmt.Sprintf("%s (%d)", c.Name(), c.ID)
+			}
+			res.Warning = fmt.Sprintf("chat %q is ambiguous, matching %s; using the most recently seen %s", ref, strings.Join(names, ", "), names[0])
+		}
+		return res, nil
+	}
+
+	if strings.HasPrefix(ref, "@") {
+		return Resolution{ChatID: ref}, nil
+	}
+	return Resolution{}, fmt.Errorf("unknown chat %q: not an id, alias or known chat (see tgbot chats list)", ref)
+}
+
+// ErrAmbiguous is returned by ResolveStrict when a reference matches
+// several chats.
+var ErrAmbiguous = errors.New("ambiguous chat reference")
+
+// ResolveStrict is Resolve for commands that ban, demote or otherwise
+// cannot be undone by rerunning them on the right chat: it accepts only a
+// numeric id, an alias or the exact username of a known chat, never a
+// title, and a username matching several chats is an error rather than a
+// guess. An unknown @username is still passed through, since Telegram
+// resolves usernames exactly.
+func (r *Registry) ResolveStrict(ref string) (Resolution, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return Resolution{}, errors.New("empty chat reference")
+	}
+	name := strings.TrimPrefix(ref, "@")
+	r.mu.Lock()
+	_, isAlias := r.data.Aliases[name]
+	r.mu.Unlock()
+	if _, err := strconv.ParseInt(ref, 10, 64); err == nil || isAlias {
+		return r.Resolve(ref)
+	}
+
+	var found []Chat
+	for _, c := range r.Chats() {
+		if c.Username != "" && strings.EqualFold(c.Username, name) {
+			found = append(found, c)
+		}
+	}
+	switch {
+	case len(found) == 1:
+		return Resolution{ChatID: strconv.FormatInt(found[0].ID, 10), Chat: &found[0]}, nil
+	case len(found) > 1:
+		ids := make([]string, len(found))
+		for i, c := range found {
+			ids[i] = fmt.Sprintf("%s (%d)", c.Name(), c.ID)
+		}
+		return Resolution{}, fmt.Errorf("%w %q, matching %s; use the numeric id", ErrAmbiguous, ref, strings.Join(ids, ", "))
+	case strings.HasPrefix(ref, "@"):
+		return Resolution{ChatID: ref}, nil
+	}
+	return Resolution{}, fmt.Errorf("unknown chat %q: this command needs a numeric id, an alias or an exact @username (see tgbot chats list)", ref)
+}
+
+func (r *Registry) lookup(id int64) (Chat, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.data.Chats[strconv.FormatInt(id, 10)]
+	return c, ok
+}
+
+func sortBySeen(chats []Chat) {
+	sort.Slice(chats, func(i, j int) bool {
+		if !chats[i].LastSeen.Equal(chats[j].LastSeen) {
+			return chats[i].LastSeen.After(chats[j].LastSeen)
+		}
+		return chats[i].ID < chats[j].ID
+	})
+}
+
+// normalize lowercases s and drops everything but letters and digits, so
+// "dev-group" matches "Dev Group".
+func normalize(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,185 @@
+package chats
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func openTemp(t *testing.T) (*Registry, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chats.json")
+	r, err := Open(path, 7)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	return r, path
+}
+
+func TestLearnAndResolve(t *testing.T) {
+	r, path := openTemp(t)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	learn := []telegram.Chat{
+		{ID: -1001, Type: "supergroup", Title: "Dev Group"},
+		{ID: -1002, Type: "supergroup", Title: "Dev Group Archive"},
+		{ID: -1003, Type: "channel", Title: "News", Username: "acme_news"},
+		{ID: 42, Type: "private", FirstName: "Alice", Username: "alice"},
+	}
+	for i, c := range learn {
+		if err := r.Learn(c, base.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatalf("Learn returned error: %v", err)
+		}
+	}
+	if err := r.SetAlias("ops", -1002); err != nil {
+		t.Fatalf("SetAlias returned error: %v", err)
+	}
+
+	// A fresh Open sees everything that was written.
+	r, err := Open(path, 7)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	if got := r.Chats(); len(got) != 4 || got[0].ID != 42 {
+		t.Fatalf("unexpected chats: %+v", got)
+	}
+
+	cases := []struct {
+		ref, want string
+		warn      bool
+	}{
+		{"-1001", "-1001", false},
+		{"@ops", "-1002", false},
+		{"ops", "-1002", false},
+		{"acme_news", "-1003", false},
+		{"@ACME_NEWS", "-1003", false},
+		{"dev-group", "-1001", false},
+		{"archive", "-1002", false},
+		{"dev", "-1002", true},
+		{"alice", "42", false},
+		{"@someone_else", "@someone_else", false},
+	}
+	for _, tc := range cases {
+		res, err := r.Resolve(tc.ref)
+		if err != nil {
+			t.Fatalf("Resolve(%q) returned error: %v", tc.ref, err)
+		}
+		if res.ChatID != tc.want || (res.Warning != "") != tc.warn {
+			t.Fatalf("Resolve(%q) = %+v, want %s (warning %v)", tc.ref, res, tc.want, tc.warn)
+		}
+	}
+	if _, err := r.Resolve("nowhere"); err == nil || !strings.Contains(err.Error(), "unknown chat") {
+		t.Fatalf("expected unknown chat error, got %v", err)
+	}
+	if res, _ := r.Resolve("ops"); res.Chat == nil || res.Chat.Title != "Dev Group Archive" {
+		t.Fatalf("expected alias to carry the chat entry, got %+v", res)
+	}
+
+	for ref, want := range map[string]string{"-1001": "-1001", "ops": "-1002", "@ACME_NEWS": "-1003", "alice": "42", "@someone_else": "@someone_else"} {
+		if res, err := r.ResolveStrict(ref); err != nil || res.ChatID != want {
+			t.Fatalf("ResolveStrict(%q) = %+v, %v; want %s", ref, res, err, want)
+		}
+	}
+	for _, ref := range []string{"dev", "Dev Group", "nowhere"} {
+		if res, err := r.ResolveStrict(ref); err == nil {
+			t.Fatalf("ResolveStrict(%q) = %+v, want an error", ref, res)
+		}
+	}
+	// Usernames move between chats; a stale entry makes a username ambiguous.
+	if err := r.Learn(telegram.Chat{ID: -1004, Type: "channel", Title: "Old News", Username: "acme_news"}, base); err != nil {
+		t.Fatalf("Learn returned error: %v", err)
+	}
+	if _, err := r.ResolveStrict("acme_news"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguity error, got %v", err)
+	}
+}
+
+func TestRecordSkipsUnchangedChats(t *testing.T) {
+	r, path := openTemp(t)
+	update, err := telegram.ParseUpdate([]byte(`{"update_id":1,"message":{"message_id":1,"date":1,"chat":{"id":5,"type":"private","first_name":"Bob"},"text":"hi"}}`))
+	if err != nil {
+		t.Fatalf("parse update: %v", err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if err := r.Record(update, now); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	other, _ := Open(path, 7)
+	if err := other.SetAlias("bob", 5); err != nil {
+		t.Fatalf("SetAlias returned error: %v", err)
+	}
+	// Within a minute nothing is written; afterwards the write keeps the
+	// alias added by the other registry.
+	if err := r.Record(update, now.Add(10*time.Second)); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := r.Record(update, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	reread, _ := Open(path, 7)
+	if got := reread.Chats(); len(got) != 1 || !got[0].LastSeen.Equal(now.Add(2*time.Minute)) {
+		t.Fatalf("unexpected chats: %+v", got)
+	}
+	if got := reread.Aliases(5); len(got) != 1 || got[0] != "bob" {
+		t.Fatalf("alias lost: %v", got)
+	}
+}
+
+func TestAliasesAreValidatedAndPerBot(t *testing.T) {
+	r, path := openTemp(t)
+	for _, bad := range []string{"", "-100", "has space", "-x"} {
+		if err := r.SetAlias(bad, 1); err == nil {
+			t.Fatalf("SetAlias(%q) should fail", bad)
+		}
+	}
+	if err := r.SetAlias("@team", 1); err != nil {
+		t.Fatalf("SetAlias returned error: %v", err)
+	}
+	other, _ := Open(path, 8)
+	if _, err := other.Resolve("team"); err == nil {
+		t.Fatal("aliases must not leak between bots")
+	}
+	if err := r.RemoveAlias("team"); err != nil {
+		t.Fatalf("RemoveAlias returned error: %v", err)
+	}
+	if err := r.RemoveAlias("team"); err == nil {
+		t.Fatal("expected error removing an unknown alias")
+	}
+}
+
+func TestConcurrentWritersShareTheFile(t *testing.T) {
+	r, path := openTemp(t)
+	other, err := Open(path, 8)
+	if err != nil {
+		t.Fatalf("Open returned error: %v", err)
+	}
+	now := time.Now()
+	var wg sync.WaitGroup
+	for i := int64(1); i <= 20; i++ {
+		for _, reg := range []*Registry{r, other} {
+			wg.Add(1)
+			go func(reg *Registry, id int64) {
+				defer wg.Done()
+				if err := reg.Learn(telegram.Chat{ID: -id, Type: "group", Title: "g"}, now); err != nil {
+					t.Errorf("Learn returned error: %v", err)
+				}
+			}(reg, i)
+		}
+	}
+	wg.Wait()
+	for _, botID := range []int64{7, 8} {
+		reread, err := Open(path, botID)
+		if err != nil {
+			t.Fatalf("Open returned error: %v", err)
+		}
+		if got := len(reread.Chats()); got != 20 {
+			t.Fatalf("bot %d: expected 20 chats, got %d", botID, got)
+		}
+	}
+	if leftovers, _ := filepath.Glob(path + ".*.tmp"); len(leftovers) != 0 {
+		t.Fatalf("temporary files left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package chats
+
+// lockFile is a no-op where advisory locks are not available; writers in
+// one process are still serialized by fileLock.
+func lockFile(string) (unlock func(), err error) {
+	return func() {}, nil
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
 )
 
 type TokenOptions struct {
@@ -22,6 +24,47 @@ type fileConfig struct {
 
 type profileConfig struct {
 	Token string `json:"token"`
+	ProfileSettings
+}
+
+// ProfileSettings holds the per-profile client settings stored next to the
+// token in the JSON config.
+type ProfileSettings struct {
+	Proxy              string   `json:"proxy"`
+	CAFile             string   `json:"ca_file"`
+	ClientCert         string   `json:"client_cert"`
+	ClientKey          string   `json:"client_key"`
+	InsecureSkipVerify bool     `json:"insecure_skip_verify"`
+	DialTimeout        Duration `json:"dial_timeout"`
+	IdleConnTimeout    Duration `json:"idle_conn_timeout"`
+	RequestTimeout     Duration `json:"request_timeout"`
+	LocalAPI           bool     `json:"local_api"`
+	TestEnv            bool     `json:"test_env"`
+}
+
+// Duration is a time.Duration written as a Go duration string ("10s") in
+// the config file.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"10s\": %w", err)
+	}
+	if s == "" {
+		*d = 0
+		return nil
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
 }
 
 func ResolveToken(opts TokenOptions) (string, error) {
@@ -90,3 +133,101 @@ func resolveConfigPath(pathFlag string) (string, error) {
 	}
 	return filepath.Join(home, ".tgbot-cli", "config.json"), nil
 }
+
+// ChatsPath returns the chat registry file, chats.json next to the config
+// file.
+func ChatsPath(configPath string) (string, error) {
+	cfgPath, err := resolveConfigPath(configPath)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(cfgPath), "chats.json"), nil
+}
+
+// LoadProfileSettings returns the settings of the selected profile. It is
+// lenient where ResolveToken is strict: a missing config, a plain-text token
+// file or an unset active_profile simply yield zero settings, so the token
+// may still come from --token or TG_BOT_TOKEN.
+func LoadProfileSettings(opts TokenOptions) (ProfileSettings, error) {
+	cfgPath, err := resolveConfigPath(opts.ConfigPath)
+	if err != nil {
+		return ProfileSettings{}, err
+	}
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ProfileSettings{}, nil
+		}
+		return ProfileSettings{}, fmt.Errorf("read config: %w", err)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return ProfileSettings{}, nil
+	}
+
+	var cfg fileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return ProfileSettings{}, fmt.Errorf("parse config json: %w", err)
+	}
+	profile := opts.Profile
+	if profile == "" {
+		profile = cfg.ActiveProfile
+	}
+	p, ok := cfg.Profiles[profile]
+	if !ok {
+		if opts.Profile != "" {
+			return ProfileSettings{}, fmt.Errorf("profile %q not found", opts.Profile)
+		}
+		return ProfileSettings{}, nil
+	}
+	return p.ProfileSettings, nil
+}
+
+// ProfileNames lists the profiles of a JSON config file in sorted order.
+func ProfileNames(configPath string) ([]string, error) {
+	cfg, err := loadFileConfig(configPath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// ProfileToken returns the token stored for profile. Unlike ResolveToken it
+// ignores --token and TG_BOT_TOKEN, which cannot tell several bots apart.
+func ProfileToken(configPath, profile string) (string, error) {
+	cfg, err := loadFileConfig(configPath)
+	if err != nil {
+		return "", err
+	}
+	p, ok := cfg.Profiles[profile]
+	if !ok {
+		return "", fmt.Errorf("profile %q not found", profile)
+	}
+	if p.Token == "" {
+		return "", fmt.Errorf("profile %q has empty token", profile)
+	}
+	return p.Token, nil
+}
+
+func loadFileConfig(pathFlag string) (fileConfig, error) {
+	cfgPath, err := resolveConfigPath(pathFlag)
+	if err != nil {
+		return fileConfig{}, err
+	}
+	data, err := os.ReadFile(cfgPath)
+	if err != nil {
+		return fileConfig{}, fmt.Errorf("read config: %w", err)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		return fileConfig{}, fmt.Errorf("%s has no profiles (not a JSON config)", cfgPath)
+	}
+	var cfg fileConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return fileConfig{}, fmt.Errorf("parse config json: %w", err)
+	}
+	return cfg, nil
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestResolveTokenPriority(t *testing.T) {
@@ -63,3 +64,78 @@ func TestResolveTokenFromJSONProfile(t *testing.T) {
 		t.Fatalf("expected json-token, got %q", tok)
 	}
 }
+
+func TestLoadProfileSettings(t *testing.T) {
+	dir := t.TempDir()
+	cfg := filepath.Join(dir, "config.json")
+	payload := `{"active_profile":"dev","profiles":{
+		"dev":{"token":"t","proxy":"socks5://127.0.0.1:1080","ca_file":"/etc/ca.pem","dial_timeout":"5s"},
+		"prod":{"token":"p"}}}`
+	if err := os.WriteFile(cfg, []byte(payload), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	settings, err := LoadProfileSettings(TokenOptions{ConfigPath: cfg})
+	if err != nil {
+		t.Fatalf("LoadProfileSettings returned error: %v", err)
+	}
+	if settings.Proxy != "socks5://127.0.0.1:1080" || settings.CAFile != "/etc/ca.pem" {
+		t.Fatalf("unexpected settings: %+v", settings)
+	}
+	if time.Duration(settings.DialTimeout) != 5*time.Second {
+		t.Fatalf("expected 5s dial timeout, got %v", time.Duration(settings.DialTimeout))
+	}
+
+	if _, err := LoadProfileSettings(TokenOptions{ConfigPath: cfg, Profile: "missing"}); err == nil {
+		t.Fatalf("expected error for unknown explicit profile")
+	}
+}
+
+func TestLoadProfileSettingsLenient(t *testing.T) {
+	dir := t.TempDir()
+	raw := filepath.Join(dir, "raw.txt")
+	if err := os.WriteFile(raw, []byte("raw-token\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	for _, path := range []string{raw, filepath.Join(dir, "missing.json")} {
+		settings, err := LoadProfileSettings(TokenOptions{ConfigPath: path})
+		if err != nil {
+			t.Fatalf("LoadProfileSettings(%s) returned error: %v", path, err)
+		}
+		if settings != (ProfileSettings{}) {
+			t.Fatalf("expected zero settings for %s, got %+v", path, settings)
+		}
+	}
+}
+
+func TestProfileNamesAndToken(t *testing.T) {
+	t.Setenv("TG_BOT_TOKEN", "env-token")
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	content := `{"active_profile":"dev","profiles":{"staging":{"token":"s-token"},"dev":{"token":"d-token"},"empty":{}}}`
+	if err := os.WriteFile(cfg, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	names, err := ProfileNames(cfg)
+	if err != nil {
+		t.Fatalf("ProfileNames returned error: %v", err)
+	}
+	if len(names) != 3 || names[0] != "dev" || names[2] != "staging" {
+		t.Fatalf("unexpected profile names: %v", names)
+	}
+	tok, err := ProfileToken(cfg, "staging")
+	if err != nil || tok != "s-token" {
+		t.Fatalf("expected s-token ignoring TG_BOT_TOKEN, got %q, %v", tok, err)
+	}
+	if _, err := ProfileToken(cfg, "empty"); err == nil {
+		t.Fatalf("expected error for empty token")
+	}
+
+	raw := filepath.Join(t.TempDir(), "raw")
+	if err := os.WriteFile(raw, []byte("raw-token"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if _, err := ProfileNames(raw); err == nil {
+		t.Fatalf("expected error for a plain-text config")
+	}
+}
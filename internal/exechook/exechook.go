@@ -0,0 +1,179 @@
+// Package exechook runs an external command for every update, passing the
+// raw update on stdin and its most useful fields as TG_* environment
+// variables.
+package exechook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// ReplyFunc sends text back to the chat an update came from, into the forum
+// topic threadID when it is not 0.
+type ReplyFunc func(ctx context.Context, chatID string, threadID int64, text string) error
+
+type Options struct {
+	// Command is run through the shell (sh -c, or cmd /C on Windows).
+	Command string
+	// Timeout kills a command that runs longer. Zero means no limit.
+	Timeout time.Duration
+	// Reply, when set, sends the command's trimmed stdout to the update's
+	// chat, in the same forum topic. Empty output sends nothing.
+	Reply ReplyFunc
+	// Env adds variables to every command, e.g. TG_PROFILE.
+	Env []string
+	// Stdout receives command output when Reply is nil; Stderr receives
+	// command stderr.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// Runner executes Command once per update. It implements
+// polling.UpdateHandler and is safe for concurrent use; the poller decides
+// how many commands run at once and retries failures.
+type Runner struct {
+	opts   Options
+	stdout io.Writer
+	stderr io.Writer
+}
+
+func New(opts Options) *Runner {
+	if opts.Stdout == nil {
+		opts.Stdout = io.Discard
+	}
+	if opts.Stderr == nil {
+		opts.Stderr = io.Discard
+	}
+	return &Runner{
+		opts:   opts,
+		stdout: &lockedWriter{w: opts.Stdout},
+		stderr: &lockedWriter{w: opts.Stderr},
+	}
+}
+
+// HandleUpdate executes the command for update synchronously. A non-zero
+// exit, a timeout or a failed reply is returned as an error.
+func (r *Runner) HandleUpdate(ctx context.Context, update telegram.Update) error {
+	if r.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.opts.Timeout)
+		defer cancel()
+	}
+
+	cmd := shellCommand(ctx, r.opts.Command)
+	cmd.Stdin = bytes.NewReader(update.Raw)
+	cmd.Env = append(append(os.Environ(), r.opts.Env...), Env(update)...)
+	cmd.Stderr = r.stderr
+	cmd.WaitDelay = time.Second
+	var out bytes.Buffer
+	if r.opts.Reply != nil {
+		cmd.Stdout = &out
+	} else {
+		cmd.Stdout = r.stdout
+	}
+
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("command timed out after %s", r.opts.Timeout)
+		}
+		return fmt.Errorf("command failed: %w", err)
+	}
+	if r.opts.Reply == nil {
+		return nil
+	}
+
+	text := strings.TrimSpace(out.String())
+	if text == "" {
+		return nil
+	}
+	chat := update.EffectiveChat()
+	if chat == nil {
+		return errors.New("reply requested but the update has no chat")
+	}
+	var threadID int64
+	if m := update.EffectiveMessage(); m != nil && m.IsTopicMessage {
+		threadID = m.MessageThreadID
+	}
+	if err := r.opts.Reply(ctx, strconv.FormatInt(chat.ID, 10), threadID, text); err != nil {
+		return fmt.Errorf("send reply: %w", err)
+	}
+	return nil
+}
+
+// Env returns the TG_* variables describing update. Fields the update does
+// not have are set to empty strings, so scripts can test them uniformly.
+func Env(update telegram.Update) []string {
+	vars := map[string]string{
+		"TG_UPDATE_ID":     strconv.FormatInt(update.UpdateID, 10),
+		"TG_UPDATE_TYPE":   update.Type(),
+		"TG_CHAT_ID":       "",
+		"TG_CHAT_TYPE":     "",
+		"TG_FROM_ID":       "",
+		"TG_USERNAME":      "",
+		"TG_MESSAGE_ID":    "",
+		"TG_THREAD_ID":     "",
+		"TG_TEXT":          "",
+		"TG_CALLBACK_DATA": "",
+	}
+	if chat := update.EffectiveChat(); chat != nil {
+		vars["TG_CHAT_ID"] = strconv.FormatInt(chat.ID, 10)
+		vars["TG_CHAT_TYPE"] = chat.Type
+	}
+	if user := update.EffectiveUser(); user != nil {
+		vars["TG_FROM_ID"] = strconv.FormatInt(user.ID, 10)
+		vars["TG_USERNAME"] = user.Username
+	}
+	if m := update.EffectiveMessage(); m != nil {
+		vars["TG_MESSAGE_ID"] = strconv.FormatInt(m.MessageID, 10)
+		if m.MessageThreadID != 0 {
+			vars["TG_THREAD_ID"] = strconv.FormatInt(m.MessageThreadID, 10)
+		}
+		vars["TG_TEXT"] = m.Text
+		if m.Text == "" {
+			vars["TG_TEXT"] = m.Caption
+		}
+	}
+	switch {
+	case update.CallbackQuery != nil:
+		vars["TG_CALLBACK_DATA"] = update.CallbackQuery.Data
+	case update.InlineQuery != nil:
+		vars["TG_TEXT"] = update.InlineQuery.Query
+	}
+
+	env := make([]string, 0, len(vars))
+	for k, v := range vars {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// lockedWriter serializes writes from concurrently running commands.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
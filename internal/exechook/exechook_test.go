@@ -0,0 +1,99 @@
+package exechook
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func parse(t *testing.T, raw string) telegram.Update {
+	t.Helper()
+	u, err := telegram.ParseUpdate([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse update: %v", err)
+	}
+	return u
+}
+
+const textUpdate = `{"update_id":7,"message":{"message_id":3,"date":1,"chat":{"id":-100,"type":"group"},"from":{"id":42,"is_bot":false,"first_name":"A","username":"alice"},"text":"hello"}}`
+
+func TestEnv(t *testing.T) {
+	env := strings.Join(Env(parse(t, textUpdate)), "\n")
+	for _, want := range []string{"TG_UPDATE_ID=7", "TG_UPDATE_TYPE=message", "TG_CHAT_ID=-100", "TG_CHAT_TYPE=group", "TG_FROM_ID=42", "TG_USERNAME=alice", "TG_MESSAGE_ID=3", "TG_TEXT=hello", "TG_CALLBACK_DATA="} {
+		if !strings.Contains(env+"\n", want+"\n") {
+			t.Fatalf("missing %s in %s", want, env)
+		}
+	}
+
+	cb := `{"update_id":8,"callback_query":{"id":"1","from":{"id":42,"first_name":"A"},"chat_instance":"x","data":"vote:1"}}`
+	env = strings.Join(Env(parse(t, cb)), "\n")
+	if !strings.Contains(env, "TG_CALLBACK_DATA=vote:1") || !strings.Contains(env, "TG_CHAT_ID=\n") {
+		t.Fatalf("unexpected callback env: %s", env)
+	}
+}
+
+func TestRunPipesUpdateAndReplies(t *testing.T) {
+	var gotChat, gotText string
+	r := New(Options{
+		Command: `cat >/dev/null; echo "got $TG_TEXT from $TG_USERNAME"`,
+		Reply: func(ctx context.Context, chatID string, _ int64, text string) error {
+			gotChat, gotText = chatID, text
+			return nil
+		},
+	})
+	if err := r.HandleUpdate(context.Background(), parse(t, textUpdate)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if gotChat != "-100" || gotText != "got hello from alice" {
+		t.Fatalf("unexpected reply to %s: %q", gotChat, gotText)
+	}
+
+	var out strings.Builder
+	r = New(Options{Command: `cat`, Stdout: &out})
+	if err := r.HandleUpdate(context.Background(), parse(t, textUpdate)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if out.String() != textUpdate {
+		t.Fatalf("expected raw update on stdin, got %s", out.String())
+	}
+}
+
+func TestRunRepliesInTopic(t *testing.T) {
+	topicUpdate := `{"update_id":9,"message":{"message_id":20,"message_thread_id":12,"is_topic_message":true,"date":1,"chat":{"id":-100,"type":"supergroup","is_forum":true},"text":"hi"}}`
+	var gotThread int64
+	r := New(Options{
+		Command: `echo "thread $TG_THREAD_ID"`,
+		Reply: func(ctx context.Context, chatID string, threadID int64, text string) error {
+			if text != "thread 12" {
+				t.Errorf("unexpected reply text %q", text)
+			}
+			gotThread = threadID
+			return nil
+		},
+	})
+	if err := r.HandleUpdate(context.Background(), parse(t, topicUpdate)); err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if gotThread != 12 {
+		t.Fatalf("expected reply in topic 12, got %d", gotThread)
+	}
+}
+
+func TestRunFailureAndTimeout(t *testing.T) {
+	r := New(Options{Command: "exit 3"})
+	if err := r.HandleUpdate(context.Background(), parse(t, textUpdate)); err == nil || !strings.Contains(err.Error(), "exit status 3") {
+		t.Fatalf("expected exit status error, got %v", err)
+	}
+
+	r = New(Options{Command: "sleep 5", Timeout: 50 * time.Millisecond})
+	start := time.Now()
+	if err := r.HandleUpdate(context.Background(), parse(t, textUpdate)); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("timeout did not stop the command")
+	}
+}
@@ -0,0 +1,191 @@
+// Package joinrules decides chat join requests from a JSON rule file, for
+// `tgbot chat join-requests watch`.
+package joinrules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// Action is what happens to a join request.
+type Action string
+
+const (
+	Approve Action = "approve"
+	Decline Action = "decline"
+	// Ignore leaves the request pending for a human administrator.
+	Ignore Action = "ignore"
+)
+
+// Rule matches join requests on every condition it sets; conditions left
+// empty match anything.
+type Rule struct {
+	// Name labels the rule in decisions; rules[i] when empty.
+	Name   string `json:"name,omitempty"`
+	Action Action `json:"action"`
+	// Chats are chat ids or @usernames the rule applies to.
+	Chats   []string `json:"chats,omitempty"`
+	UserIDs []int64  `json:"user_ids,omitempty"`
+	// UsernameRegex, NameRegex and BioRegex are regular expressions
+	// matched against the username (without @), the first and last name,
+	// and the bio.
+	UsernameRegex string `json:"username_regex,omitempty"`
+	NameRegex     string `json:"name_regex,omitempty"`
+	BioRegex      string `json:"bio_regex,omitempty"`
+	HasUsername   *bool  `json:"has_username,omitempty"`
+	// Languages are IETF language codes such as "en"; a request without a
+	// language code does not match.
+	Languages []string `json:"languages,omitempty"`
+	// InviteLinkName matches the name of the invite link used to join.
+	InviteLinkName string `json:"invite_link_name,omitempty"`
+
+	username, name, bio *regexp.Regexp
+}
+
+// Rules is a parsed rule file. The first matching rule decides a request;
+// Default decides requests no rule matches.
+type Rules struct {
+	Default Action `json:"default"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Decision is the outcome of Decide.
+type Decision struct {
+	Action Action `json:"action"`
+	// Rule is the name of the matching rule, or "default".
+	Rule string `json:"rule"`
+}
+
+// Load reads and validates the rule file at path.
+func Load(path string) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read rule file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse validates a rule file and compiles its regular expressions. A
+// missing default is Ignore.
+func Parse(data []byte) (*Rules, error) {
+	var rs Rules
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rs); err != nil {
+		return nil, fmt.Errorf("parse rule file: %w", err)
+	}
+	if rs.Default == "" {
+		rs.Default = Ignore
+	}
+	if err := rs.Default.validate(); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	for i := range rs.Rules {
+		r := &rs.Rules[i]
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rules[%d]", i)
+		}
+		if err := r.Action.validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", r.Name, err)
+		}
+		for _, re := range []struct {
+			field, expr string
+			dst         **regexp.Regexp
+		}{
+			{"username_regex", r.UsernameRegex, &r.username},
+			{"name_regex", r.NameRegex, &r.name},
+			{"bio_regex", r.BioRegex, &r.bio},
+		} {
+			if re.expr == "" {
+				continue
+			}
+			compiled, err := regexp.Compile(re.expr)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", r.Name, re.field, err)
+			}
+			*re.dst = compiled
+		}
+	}
+	return &rs, nil
+}
+
+func (a Action) validate() error {
+	switch a {
+	case Approve, Decline, Ignore:
+		return nil
+	case "":
+		return errors.New("action is required (approve, decline or ignore)")
+	}
+	return fmt.Errorf("unknown action %q (want approve, decline or ignore)", a)
+}
+
+// Decide returns the action of the first rule matching req.
+func (rs *Rules) Decide(req telegram.ChatJoinRequest) Decision {
+	for _, r := range rs.Rules {
+		if r.matches(req) {
+			return Decision{Action: r.Action, Rule: r.Name}
+		}
+	}
+	return Decision{Action: rs.Default, Rule: "default"}
+}
+
+func (r Rule) matches(req telegram.ChatJoinRequest) bool {
+	user := req.From
+	switch {
+	case len(r.Chats) > 0 && !matchesChat(r.Chats, req.Chat):
+		return false
+	case len(r.UserIDs) > 0 && !containsID(r.UserIDs, user.ID):
+		return false
+	case r.HasUsername != nil && *r.HasUsername != (user.Username != ""):
+		return false
+	case r.username != nil && !r.username.MatchString(user.Username):
+		return false
+	case r.name != nil && !r.name.MatchString(strings.TrimSpace(user.FirstName+" "+user.LastName)):
+		return false
+	case r.bio != nil && !r.bio.MatchString(req.Bio):
+		return false
+	case len(r.Languages) > 0 && !matchesLanguage(r.Languages, user.LanguageCode):
+		return false
+	case r.InviteLinkName != "" && (req.InviteLink == nil || req.InviteLink.Name != r.InviteLinkName):
+		return false
+	}
+	return true
+}
+
+func matchesChat(refs []string, chat telegram.Chat) bool {
+	for _, ref := range refs {
+		if ref == strconv.FormatInt(chat.ID, 10) ||
+			(chat.Username != "" && strings.EqualFold(strings.TrimPrefix(ref, "@"), chat.Username)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesLanguage compares primary subtags, so "en" matches "en-US".
+func matchesLanguage(languages []string, code string) bool {
+	primary, _, _ := strings.Cut(strings.ToLower(code), "-")
+	for _, lang := range languages {
+		if want, _, _ := strings.Cut(strings.ToLower(lang), "-"); primary != "" && want == primary {
+			return true
+		}
+	}
+	return false
+}
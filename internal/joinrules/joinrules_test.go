@@ -0,0 +1,63 @@
+package joinrules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const ruleFile = `{
+  "default": "ignore",
+  "rules": [
+    {"name": "vip", "action": "approve", "user_ids": [7]},
+    {"name": "spam", "action": "decline", "name_regex": "(?i)crypto|airdrop", "bio_regex": "t\\.me/"},
+    {"action": "decline", "chats": ["@acme"], "has_username": false},
+    {"name": "partners", "action": "approve", "invite_link_name": "partners"},
+    {"name": "english", "action": "approve", "chats": ["-1001"], "languages": ["en"]}
+  ]
+}`
+
+func TestDecide(t *testing.T) {
+	rs, err := Parse([]byte(ruleFile))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	group := telegram.Chat{ID: -1001, Type: "supergroup", Title: "Dev"}
+	acme := telegram.Chat{ID: -1002, Type: "supergroup", Username: "Acme"}
+	cases := []struct {
+		name string
+		req  telegram.ChatJoinRequest
+		want Decision
+	}{
+		{"user id", telegram.ChatJoinRequest{Chat: acme, From: telegram.User{ID: 7}}, Decision{Approve, "vip"}},
+		{"name and bio", telegram.ChatJoinRequest{Chat: group, From: telegram.User{ID: 8, FirstName: "Free", LastName: "CRYPTO"}, Bio: "see t.me/x"}, Decision{Decline, "spam"}},
+		{"name without bio", telegram.ChatJoinRequest{Chat: group, From: telegram.User{ID: 8, FirstName: "crypto", Username: "c", LanguageCode: "de"}}, Decision{Ignore, "default"}},
+		{"unnamed rule by username", telegram.ChatJoinRequest{Chat: acme, From: telegram.User{ID: 9}}, Decision{Decline, "rules[2]"}},
+		{"invite link", telegram.ChatJoinRequest{Chat: acme, From: telegram.User{ID: 9, Username: "bob"}, InviteLink: &telegram.ChatInviteLink{Name: "partners"}}, Decision{Approve, "partners"}},
+		{"language subtag", telegram.ChatJoinRequest{Chat: group, From: telegram.User{ID: 10, Username: "eve", LanguageCode: "en-US"}}, Decision{Approve, "english"}},
+		{"language other chat", telegram.ChatJoinRequest{Chat: acme, From: telegram.User{ID: 10, Username: "eve", LanguageCode: "en"}}, Decision{Ignore, "default"}},
+	}
+	for _, c := range cases {
+		if got := rs.Decide(c.req); got != c.want {
+			t.Errorf("%s: Decide = %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, c := range []struct{ file, want string }{
+		{`{"default": "maybe"}`, `unknown action "maybe"`},
+		{`{"rules": [{"name": "x"}]}`, "x: action is required"},
+		{`{"rules": [{"action": "approve", "bio_regex": "("}]}`, "rules[0]: bio_regex"},
+		{`{"rules": [{"action": "approve", "usernames": ["a"]}]}`, `unknown field "usernames"`},
+	} {
+		if _, err := Parse([]byte(c.file)); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("Parse(%s) error = %v, want %q", c.file, err, c.want)
+		}
+	}
+	rs, err := Parse([]byte(`{}`))
+	if err != nil || rs.Default != Ignore {
+		t.Fatalf("expected empty file to ignore everything, got %+v %v", rs, err)
+	}
+}
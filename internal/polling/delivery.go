@@ -0,0 +1,139 @@
+package polling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// UpdateHandler processes one update. An update is only confirmed to
+// Telegram after HandleUpdate succeeds (or the update is dead-lettered), so
+// a crash mid-handling redelivers it on the next run.
+type UpdateHandler interface {
+	HandleUpdate(ctx context.Context, update telegram.Update) error
+}
+
+// HandlerFunc adapts a function to UpdateHandler.
+type HandlerFunc func(ctx context.Context, update telegram.Update) error
+
+func (f HandlerFunc) HandleUpdate(ctx context.Context, update telegram.Update) error {
+	return f(ctx, update)
+}
+
+// RetryPolicy controls how often a failing update is retried. Backoff
+// doubles after every failed attempt, up to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+const (
+	DefaultRetryBackoff    = time.Second
+	DefaultRetryMaxBackoff = time.Minute
+)
+
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = 1
+	}
+	if r.Backoff <= 0 {
+		r.Backoff = DefaultRetryBackoff
+	}
+	if r.MaxBackoff <= 0 {
+		r.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	return r
+}
+
+// delay is the wait after the given failed attempt (1-based).
+func (r RetryPolicy) delay(attempt int) time.Duration {
+	d := r.Backoff
+	for i := 1; i < attempt && d < r.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, r.MaxBackoff)
+}
+
+// DeadLetterSink stores updates that still fail after every attempt.
+type DeadLetterSink interface {
+	DeadLetter(update telegram.Update, attempts int, cause error) error
+}
+
+// DeadLetter is one line of a dead-letter file. Its update field makes the
+// file readable by session.Read, so it can be fed to `updates replay`.
+type DeadLetter struct {
+	FailedAt time.Time       `json:"failed_at"`
+	Attempts int             `json:"attempts"`
+	Error    string          `json:"error"`
+	Update   json.RawMessage `json:"update"`
+}
+
+// DeadLetterWriter appends DeadLetter lines. It is safe for concurrent use.
+type DeadLetterWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewDeadLetterWriter(w io.Writer) *DeadLetterWriter {
+	return &DeadLetterWriter{w: w}
+}
+
+func (d *DeadLetterWriter) DeadLetter(update telegram.Update, attempts int, cause error) error {
+	line, err := json.Marshal(DeadLetter{
+		FailedAt: time.Now().UTC(),
+		Attempts: attempts,
+		Error:    cause.Error(),
+		Update:   update.Raw,
+	})
+	if err != nil {
+		return fmt.Errorf("encode dead letter: %w", err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, err = d.w.Write(append(line, '\n'))
+	return err
+}
+
+// deliver runs the handler with retries. An update that fails every attempt
+// is dead-lettered. Without a sink, or when the sink fails, the error is
+// returned so the poller stops without confirming the update and the next
+// run receives it again.
+func (p *Poller) deliver(ctx context.Context, update telegram.Update, errWriter io.Writer) error {
+	if p.opts.Handler == nil {
+		return nil
+	}
+	policy := p.opts.Retry.withDefaults()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = p.opts.Handler.HandleUpdate(ctx, update); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt == policy.MaxAttempts {
+			break
+		}
+		delay := policy.delay(attempt)
+		fmt.Fprintf(errWriter, "[retry] update %d attempt %d/%d failed: %v; retrying in %s\n", update.UpdateID, attempt, policy.MaxAttempts, err, delay)
+		if err := p.sleep(ctx, delay, nil); err != nil {
+			return err
+		}
+	}
+
+	if p.opts.DeadLetter == nil {
+		return fmt.Errorf("update %d failed after %d attempt(s), stopping without confirming it: %w", update.UpdateID, policy.MaxAttempts, err)
+	}
+	if dlErr := p.opts.DeadLetter.DeadLetter(update, policy.MaxAttempts, err); dlErr != nil {
+		return fmt.Errorf("dead-letter update %d: %w", update.UpdateID, dlErr)
+	}
+	fmt.Fprintf(errWriter, "[dead-letter] update %d failed after %d attempt(s): %v\n", update.UpdateID, policy.MaxAttempts, err)
+	return nil
+}
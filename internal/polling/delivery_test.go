@@ -0,0 +1,116 @@
+package polling
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	r := RetryPolicy{Backoff: time.Second, MaxBackoff: 5 * time.Second}.withDefaults()
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		if got := r.delay(i + 1); got != w {
+			t.Fatalf("delay(%d) = %s, want %s", i+1, got, w)
+		}
+	}
+	if r.MaxAttempts != 1 {
+		t.Fatalf("expected a single attempt by default, got %d", r.MaxAttempts)
+	}
+}
+
+func failingHandler(failures int) (*int, UpdateHandler) {
+	calls := 0
+	return &calls, HandlerFunc(func(ctx context.Context, u telegram.Update) error {
+		calls++
+		if calls <= failures {
+			return errors.New("backend down")
+		}
+		return nil
+	})
+}
+
+func TestPollerRetriesFailedUpdates(t *testing.T) {
+	calls, h := failingHandler(2)
+	api := &fakeAPI{updates: [][]telegram.Update{{{UpdateID: 1, Raw: []byte(`{"update_id":1}`)}}}}
+	p := New(api, Options{TimeoutSecond: 1, OutputFormat: "jsonl", Handler: h, Retry: RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}})
+	var errOut strings.Builder
+	if err := p.Run(context.Background(), &strings.Builder{}, &errOut); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the fake to end the run, got %v", err)
+	}
+	if *calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", *calls)
+	}
+	if !strings.Contains(errOut.String(), "[retry] update 1 attempt 2/3 failed: backend down; retrying in 2ms") {
+		t.Fatalf("expected retry log, got %s", errOut.String())
+	}
+}
+
+func TestPollerDeadLettersPoisonUpdates(t *testing.T) {
+	calls, h := failingHandler(100)
+	api := &fakeAPI{updates: [][]telegram.Update{{
+		{UpdateID: 1, Raw: []byte(`{"update_id":1}`)},
+		{UpdateID: 2, Raw: []byte(`{"update_id":2}`)},
+	}}}
+	var dead bytes.Buffer
+	p := New(api, Options{TimeoutSecond: 1, OutputFormat: "jsonl", Handler: h, Retry: RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond}, DeadLetter: NewDeadLetterWriter(&dead)})
+	var errOut strings.Builder
+	if err := p.Run(context.Background(), &strings.Builder{}, &errOut); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the fake to end the run, got %v", err)
+	}
+	if *calls != 4 {
+		t.Fatalf("expected 2 attempts per update, got %d calls", *calls)
+	}
+	lines := strings.Split(strings.TrimSpace(dead.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 dead letters, got %q", dead.String())
+	}
+	var dl DeadLetter
+	if err := json.Unmarshal([]byte(lines[1]), &dl); err != nil {
+		t.Fatalf("decode dead letter: %v", err)
+	}
+	if dl.Attempts != 2 || dl.Error != "backend down" || string(dl.Update) != `{"update_id":2}` || dl.FailedAt.IsZero() {
+		t.Fatalf("unexpected dead letter: %+v", dl)
+	}
+	if !strings.Contains(errOut.String(), "[dead-letter] update 2 failed after 2 attempt(s): backend down") {
+		t.Fatalf("expected dead-letter log, got %s", errOut.String())
+	}
+}
+
+type brokenSink struct{}
+
+func (brokenSink) DeadLetter(telegram.Update, int, error) error {
+	return errors.New("disk full")
+}
+
+func TestPollerStopsWhenDeadLetterFails(t *testing.T) {
+	_, h := failingHandler(100)
+	api := &fakeAPI{updates: [][]telegram.Update{{{UpdateID: 1, Raw: []byte(`{"update_id":1}`)}}, {}}}
+	p := New(api, Options{TimeoutSecond: 1, OutputFormat: "jsonl", Handler: h, DeadLetter: brokenSink{}})
+	err := p.Run(context.Background(), &strings.Builder{}, &strings.Builder{})
+	if err == nil || err.Error() != "dead-letter update 1: disk full" {
+		t.Fatalf("expected dead-letter failure, got %v", err)
+	}
+	if len(api.offsets) != 1 {
+		t.Fatalf("failed update must not be confirmed, got polls %v", api.offsets)
+	}
+
+	// Without a sink an exhausted update stops the poller unconfirmed rather
+	// than being dropped.
+	_, h = failingHandler(100)
+	api = &fakeAPI{updates: [][]telegram.Update{{{UpdateID: 1, Raw: []byte(`{"update_id":1}`)}}, {}}}
+	p = New(api, Options{TimeoutSecond: 1, OutputFormat: "jsonl", Handler: h, Retry: RetryPolicy{MaxAttempts: 1}})
+	err = p.Run(context.Background(), &strings.Builder{}, &strings.Builder{})
+	if err == nil || err.Error() != "update 1 failed after 1 attempt(s), stopping without confirming it: backend down" {
+		t.Fatalf("expected delivery failure, got %v", err)
+	}
+	if len(api.offsets) != 1 {
+		t.Fatalf("failed update must not be confirmed, got polls %v", api.offsets)
+	}
+}
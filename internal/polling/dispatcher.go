@@ -0,0 +1,187 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// dispatchQueueSize bounds how many updates may be queued or in flight
+// before Dispatch blocks; it matches the getUpdates batch limit.
+//
+// The bound is shared by all chats on purpose: the confirmed offset cannot
+// pass the oldest unfinished update, and getUpdates returns at most 100
+// updates from that offset, so a chat whose handler hangs stalls every
+// chat once 100 updates have arrived after its oldest one. A per-chat bound
+// would not help, since the updates behind it could not be fetched anyway.
+// Handlers should therefore bound their own run time.
+const dispatchQueueSize = 100
+
+// Dispatcher runs an UpdateHandler on a bounded pool of workers. Updates for the
+// same chat are handled one at a time in the order they were dispatched;
+// different chats are handled in parallel, until one slow chat fills the
+// shared queue (see dispatchQueueSize).
+type Dispatcher struct {
+	ctx    context.Context
+	handle UpdateHandler
+	slots  chan struct{}
+	ready  chan string
+	wg     sync.WaitGroup
+
+	mu       sync.Mutex
+	queues   map[string][]telegram.Update
+	pending  map[int64]struct{}
+	next     int64
+	err      error
+	progress chan struct{}
+}
+
+// NewDispatcher starts workers that call handle with ctx. Once ctx is done,
+// queued updates are dropped unhandled and stay pending, so their offset is
+// never confirmed.
+func NewDispatcher(ctx context.Context, workers int, handle UpdateHandler) *Dispatcher {
+	if workers <= 0 {
+		workers = 1
+	}
+	d := &Dispatcher{
+		ctx:      ctx,
+		handle:   handle,
+		slots:    make(chan struct{}, dispatchQueueSize),
+		ready:    make(chan string, dispatchQueueSize),
+		queues:   make(map[string][]telegram.Update),
+		pending:  make(map[int64]struct{}),
+		progress: make(chan struct{}),
+	}
+	d.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go d.work()
+	}
+	return d
+}
+
+// Dispatch queues update for its chat, blocking while the queue is full.
+// It returns the first handler error seen so far, if any.
+func (d *Dispatcher) Dispatch(ctx context.Context, update telegram.Update) error {
+	if err := d.Err(); err != nil {
+		return err
+	}
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	key := chatKey(update)
+	d.mu.Lock()
+	d.pending[update.UpdateID] = struct{}{}
+	if update.UpdateID >= d.next {
+		d.next = update.UpdateID + 1
+	}
+	queue, busy := d.queues[key]
+	d.queues[key] = append(queue, update)
+	d.mu.Unlock()
+
+	if !busy {
+		d.ready <- key
+	}
+	return nil
+}
+
+// Offset returns the lowest update id not yet handled, which is the offset
+// that can safely be confirmed to Telegram. It is 0 before the first
+// Dispatch.
+func (d *Dispatcher) Offset() int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	offset := d.next
+	for id := range d.pending {
+		if id < offset {
+			offset = id
+		}
+	}
+	return offset
+}
+
+// Progress returns a channel closed the next time an update finishes.
+func (d *Dispatcher) Progress() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.progress
+}
+
+// Err returns the first handler error.
+func (d *Dispatcher) Err() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.err
+}
+
+// Wait stops accepting updates, waits for queued ones to finish and returns
+// the first handler error. Dispatch must not be called afterwards.
+func (d *Dispatcher) Wait() error {
+	close(d.ready)
+	d.wg.Wait()
+	return d.Err()
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for key := range d.ready {
+		d.drain(key)
+	}
+}
+
+// drain handles the queue for key until it is empty. The head stays in the
+// queue while it is handled, so Dispatch knows the chat is busy and does
+// not hand it to a second worker.
+func (d *Dispatcher) drain(key string) {
+	for {
+		d.mu.Lock()
+		queue := d.queues[key]
+		if len(queue) == 0 {
+			delete(d.queues, key)
+			d.mu.Unlock()
+			return
+		}
+		update := queue[0]
+		d.mu.Unlock()
+
+		var err error
+		handled := d.ctx.Err() == nil
+		if handled {
+			err = d.handle.HandleUpdate(d.ctx, update)
+			if d.ctx.Err() != nil {
+				// Interrupted: leave the update pending so it is redelivered.
+				handled, err = false, nil
+			}
+		}
+
+		d.mu.Lock()
+		d.queues[key] = d.queues[key][1:]
+		if handled {
+			delete(d.pending, update.UpdateID)
+		}
+		if err != nil && d.err == nil {
+			d.err = fmt.Errorf("handle update %d: %w", update.UpdateID, err)
+		}
+		close(d.progress)
+		d.progress = make(chan struct{})
+		d.mu.Unlock()
+		<-d.slots
+	}
+}
+
+// chatKey groups updates that must be handled in order: by chat, then by
+// user for chatless updates such as inline queries, otherwise not at all.
+func chatKey(update telegram.Update) string {
+	if chat := update.EffectiveChat(); chat != nil {
+		return "chat:" + strconv.FormatInt(chat.ID, 10)
+	}
+	if user := update.EffectiveUser(); user != nil {
+		return "user:" + strconv.FormatInt(user.ID, 10)
+	}
+	return "update:" + strconv.FormatInt(update.UpdateID, 10)
+}
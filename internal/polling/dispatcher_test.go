@@ -0,0 +1,128 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func chatUpdate(t *testing.T, id, chatID int64) telegram.Update {
+	t.Helper()
+	raw := fmt.Sprintf(`{"update_id":%d,"message":{"message_id":%d,"date":1,"chat":{"id":%d,"type":"private"},"text":"m%d"}}`, id, id, chatID, id)
+	u, err := telegram.ParseUpdate([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse update: %v", err)
+	}
+	return u
+}
+
+func TestDispatcherOrdersPerChatAndRunsChatsInParallel(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	order := map[int64][]int64{}
+	d := NewDispatcher(context.Background(), 4, HandlerFunc(func(ctx context.Context, u telegram.Update) error {
+		if u.UpdateID == 1 {
+			// Chat 10 stays blocked until chat 20 has been handled.
+			<-release
+		}
+		mu.Lock()
+		chat := u.EffectiveChat().ID
+		order[chat] = append(order[chat], u.UpdateID)
+		mu.Unlock()
+		if u.UpdateID == 4 {
+			close(release)
+		}
+		return nil
+	}))
+
+	for i, chat := range []int64{10, 10, 10, 20} {
+		if err := d.Dispatch(context.Background(), chatUpdate(t, int64(i+1), chat)); err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+	}
+	if err := d.Wait(); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if fmt.Sprint(order[10]) != "[1 2 3]" || fmt.Sprint(order[20]) != "[4]" {
+		t.Fatalf("unexpected handling order: %v", order)
+	}
+}
+
+func TestDispatcherOffsetWaitsForOldestUpdate(t *testing.T) {
+	release := make(chan struct{})
+	d := NewDispatcher(context.Background(), 2, HandlerFunc(func(ctx context.Context, u telegram.Update) error {
+		if u.UpdateID == 5 {
+			<-release
+		}
+		return nil
+	}))
+	if d.Offset() != 0 {
+		t.Fatalf("expected offset 0 before dispatch, got %d", d.Offset())
+	}
+	progress := d.Progress()
+	_ = d.Dispatch(context.Background(), chatUpdate(t, 5, 1))
+	_ = d.Dispatch(context.Background(), chatUpdate(t, 6, 2))
+	select {
+	case <-progress:
+	case <-time.After(time.Second):
+		t.Fatalf("update 6 was not handled while 5 was blocked")
+	}
+	if got := d.Offset(); got != 5 {
+		t.Fatalf("expected offset held at 5, got %d", got)
+	}
+	close(release)
+	if err := d.Wait(); err != nil {
+		t.Fatalf("wait: %v", err)
+	}
+	if got := d.Offset(); got != 7 {
+		t.Fatalf("expected offset 7 once all done, got %d", got)
+	}
+}
+
+func TestDispatcherReportsHandlerError(t *testing.T) {
+	d := NewDispatcher(context.Background(), 2, HandlerFunc(func(ctx context.Context, u telegram.Update) error {
+		return fmt.Errorf("boom")
+	}))
+	_ = d.Dispatch(context.Background(), chatUpdate(t, 1, 1))
+	if err := d.Wait(); err == nil || err.Error() != "handle update 1: boom" {
+		t.Fatalf("expected handler error, got %v", err)
+	}
+}
+
+func TestPollerRunWithWorkersSkipsInFlightUpdates(t *testing.T) {
+	release := make(chan struct{})
+	first, second := chatUpdate(t, 1, 10), chatUpdate(t, 2, 20)
+	api := &fakeAPI{updates: [][]telegram.Update{{first, second}, {first}}}
+	api.onCall = func(call int) {
+		if call == 1 {
+			close(release)
+		}
+	}
+	var handled sync.Map
+	p := New(api, Options{TimeoutSecond: 1, OutputFormat: "jsonl", Workers: 2, Handler: HandlerFunc(func(ctx context.Context, u telegram.Update) error {
+		if u.UpdateID == 1 {
+			<-release
+		}
+		if _, dup := handled.LoadOrStore(u.UpdateID, true); dup {
+			t.Errorf("update %d handled twice", u.UpdateID)
+		}
+		return nil
+	})})
+
+	var out strings.Builder
+	err := p.Run(context.Background(), &out, &strings.Builder{})
+	if err != context.Canceled {
+		t.Fatalf("expected fake api to end the run, got %v", err)
+	}
+	if len(api.offsets) < 2 || api.offsets[1] != 1 {
+		t.Fatalf("expected offset held at the in-flight update, got %v", api.offsets)
+	}
+	if strings.Count(out.String(), "\n") != 2 {
+		t.Fatalf("expected each update written once, got %q", out.String())
+	}
+}
@@ -0,0 +1,56 @@
+package polling
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// Sources of an enveloped update.
+const (
+	SourcePolling = "polling"
+	SourceWebhook = "webhook"
+	SourceReplay  = "replay"
+)
+
+// Envelope wraps an update with when, by which bot and over which transport
+// it was received, and the name of its forum topic. Unknown fields are
+// omitted.
+type Envelope struct {
+	ReceivedAt *time.Time      `json:"received_at,omitempty"`
+	Bot        string          `json:"bot,omitempty"`
+	Profile    string          `json:"profile,omitempty"`
+	Source     string          `json:"source,omitempty"`
+	Topic      string          `json:"topic,omitempty"`
+	Update     json.RawMessage `json:"update"`
+}
+
+// NewEnvelope wraps update, taking the receive time from update.ReceivedAt
+// and the topic from update.Topic.
+func NewEnvelope(update telegram.Update, source string, tag Tag) Envelope {
+	env := Envelope{Bot: tag.Bot, Profile: tag.Profile, Source: source, Topic: update.Topic, Update: update.Raw}
+	if !update.ReceivedAt.IsZero() {
+		at := update.ReceivedAt.UTC()
+		env.ReceivedAt = &at
+	}
+	return env
+}
+
+// FormatEnvelope renders env like FormatUpdate renders a bare update: one
+// line for jsonl, indented JSON otherwise.
+func FormatEnvelope(env Envelope, outputFormat string) ([]byte, error) {
+	if outputFormat == "jsonl" {
+		line, err := json.Marshal(env)
+		if err != nil {
+			return nil, fmt.Errorf("encode update envelope: %w", err)
+		}
+		return append(line, '\n'), nil
+	}
+	pretty, err := json.MarshalIndent(env, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encode update envelope: %w", err)
+	}
+	return append(pretty, []byte("\n\n")...), nil
+}
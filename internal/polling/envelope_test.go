@@ -0,0 +1,49 @@
+package polling
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func TestFormatEnvelope(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("X", 3600))
+	update := telegram.Update{UpdateID: 1, Raw: []byte(`{"update_id":1}`), ReceivedAt: at}
+	out, err := FormatEnvelope(NewEnvelope(update, SourceReplay, Tag{Profile: "dev", Bot: "@dev_bot"}), "jsonl")
+	if err != nil {
+		t.Fatalf("FormatEnvelope returned error: %v", err)
+	}
+	want := `{"received_at":"2024-05-01T11:00:00Z","bot":"@dev_bot","profile":"dev","source":"replay","update":{"update_id":1}}` + "\n"
+	if string(out) != want {
+		t.Fatalf("unexpected envelope:\n got %s\nwant %s", out, want)
+	}
+
+	out, err = FormatEnvelope(NewEnvelope(telegram.Update{Raw: []byte(`{"update_id":2}`)}, SourcePolling, Tag{}), "pretty")
+	if err != nil {
+		t.Fatalf("FormatEnvelope returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "{\n  \"source\": \"polling\",\n  \"update\": {\n    \"update_id\": 2") {
+		t.Fatalf("unexpected pretty envelope: %s", out)
+	}
+}
+
+func TestPollerRunWritesEnvelopes(t *testing.T) {
+	api := &fakeAPI{updates: [][]telegram.Update{{{UpdateID: 3, Raw: []byte(`{"update_id":3}`)}}}}
+	p := New(api, Options{Once: true, OutputFormat: "jsonl", Envelope: true, Tag: Tag{Profile: "dev", Bot: "@dev_bot"}})
+	var out strings.Builder
+	before := time.Now().UTC().Add(-time.Second)
+	if err := p.Run(context.Background(), &out, &strings.Builder{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	var env Envelope
+	if err := json.Unmarshal([]byte(out.String()), &env); err != nil {
+		t.Fatalf("decode envelope: %v (%s)", err, out.String())
+	}
+	if env.Source != SourcePolling || env.Bot != "@dev_bot" || env.ReceivedAt == nil || env.ReceivedAt.Before(before) || string(env.Update) != `{"update_id":3}` {
+		t.Fatalf("unexpected envelope: %s", out.String())
+	}
+}
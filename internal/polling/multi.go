@@ -0,0 +1,79 @@
+package polling
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// Tag identifies the bot an update came from when several bots are polled
+// into one stream.
+type Tag struct {
+	Profile string `json:"profile"`
+	Bot     string `json:"bot"`
+}
+
+// FormatTaggedUpdate is FormatUpdate for merged multi-bot streams: jsonl
+// lines become {"bot":...,"profile":...,"update":{...}} and pretty output
+// gets a "[profile @bot]" header. A zero tag formats like FormatUpdate.
+func FormatTaggedUpdate(raw []byte, outputFormat string, tag Tag) ([]byte, error) {
+	if tag == (Tag{}) {
+		return FormatUpdate(raw, outputFormat)
+	}
+	if outputFormat == "jsonl" {
+		return FormatEnvelope(Envelope{Bot: tag.Bot, Profile: tag.Profile, Update: raw}, outputFormat)
+	}
+	body, err := FormatUpdate(raw, outputFormat)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(fmt.Sprintf("[%s %s]\n", tag.Profile, tag.Bot)), body...), nil
+}
+
+// RunMany runs pollers concurrently and merges their output into
+// outWriter. The first failure stops the others and is returned. With more
+// than one poller, logs and errors are prefixed with the poller's profile.
+func RunMany(ctx context.Context, pollers []*Poller, outWriter, errWriter io.Writer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	outWriter, errWriter = &syncWriter{w: outWriter}, &syncWriter{w: errWriter}
+
+	labeled := len(pollers) > 1
+	errs := make(chan error, len(pollers))
+	for _, p := range pollers {
+		go func(p *Poller) {
+			logs := errWriter
+			if labeled {
+				logs = &prefixWriter{prefix: "[" + p.opts.Tag.Profile + "] ", w: errWriter}
+			}
+			err := p.Run(ctx, outWriter, logs)
+			if err != nil && labeled {
+				err = fmt.Errorf("%s: %w", p.opts.Tag.Profile, err)
+			}
+			errs <- err
+		}(p)
+	}
+
+	var first error
+	for range pollers {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
+
+// prefixWriter labels each write, which the poller always makes one line
+// at a time.
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, p.prefix+string(b)); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,67 @@
+package polling
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func TestFormatTaggedUpdate(t *testing.T) {
+	tag := Tag{Profile: "dev", Bot: "@dev_bot"}
+	out, err := FormatTaggedUpdate([]byte(`{"update_id":1}`), "jsonl", tag)
+	if err != nil {
+		t.Fatalf("FormatTaggedUpdate returned error: %v", err)
+	}
+	if string(out) != `{"bot":"@dev_bot","profile":"dev","update":{"update_id":1}}`+"\n" {
+		t.Fatalf("unexpected jsonl output: %s", out)
+	}
+
+	out, err = FormatTaggedUpdate([]byte(`{"update_id":1}`), "pretty", tag)
+	if err != nil {
+		t.Fatalf("FormatTaggedUpdate returned error: %v", err)
+	}
+	if !strings.HasPrefix(string(out), "[dev @dev_bot]\n{\n  \"update_id\": 1") {
+		t.Fatalf("unexpected pretty output: %s", out)
+	}
+
+	out, _ = FormatTaggedUpdate([]byte(`{"update_id":1}`), "jsonl", Tag{})
+	if string(out) != "{\"update_id\":1}\n" {
+		t.Fatalf("zero tag should not change output, got %s", out)
+	}
+}
+
+type failingAPI struct{ fakeAPI }
+
+func (f *failingAPI) GetUpdates(context.Context, int64, int) ([]telegram.Update, error) {
+	return nil, errors.New("unauthorized")
+}
+
+func TestRunManyMergesTaggedStreams(t *testing.T) {
+	dev := New(&fakeAPI{updates: [][]telegram.Update{{{UpdateID: 1, Raw: []byte(`{"update_id":1}`)}}}},
+		Options{Once: true, OutputFormat: "jsonl", Tag: Tag{Profile: "dev", Bot: "@dev_bot"}})
+	staging := New(&fakeAPI{updates: [][]telegram.Update{{{UpdateID: 7, Raw: []byte(`{"update_id":7}`)}}}},
+		Options{Once: true, OutputFormat: "jsonl", Tag: Tag{Profile: "staging", Bot: "@staging_bot"}})
+	var out strings.Builder
+	if err := RunMany(context.Background(), []*Poller{dev, staging}, &out, &strings.Builder{}); err != nil {
+		t.Fatalf("RunMany returned error: %v", err)
+	}
+	for _, want := range []string{`{"bot":"@dev_bot","profile":"dev","update":{"update_id":1}}`, `{"bot":"@staging_bot","profile":"staging","update":{"update_id":7}}`} {
+		if !strings.Contains(out.String(), want) {
+			t.Fatalf("missing %s in merged output: %s", want, out.String())
+		}
+	}
+
+	broken := New(&failingAPI{}, Options{TimeoutSecond: 1, DeleteWebhook: true, Tag: Tag{Profile: "support", Bot: "@support_bot"}})
+	var errOut strings.Builder
+	idle := New(&fakeAPI{updates: [][]telegram.Update{{}}}, Options{Once: true, Tag: Tag{Profile: "dev"}})
+	err := RunMany(context.Background(), []*Poller{broken, idle}, &strings.Builder{}, &errOut)
+	if err == nil || err.Error() != "support: unauthorized" {
+		t.Fatalf("expected profile-prefixed error, got %v", err)
+	}
+	if !strings.HasPrefix(errOut.String(), "[support] [info] deleting webhook") {
+		t.Fatalf("expected profile-prefixed log, got %q", errOut.String())
+	}
+}
@@ -3,8 +3,10 @@ package polling
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	"github.com/example/tgbot-cli/internal/telegram"
@@ -15,6 +17,37 @@ type telegramAPI interface {
 	GetUpdates(ctx context.Context, offset int64, timeoutSec int) ([]telegram.Update, error)
 }
 
+// allowedUpdatesAPI is implemented by clients that can restrict getUpdates
+// to some update types, such as *telegram.Client.
+type allowedUpdatesAPI interface {
+	GetUpdatesAllowed(ctx context.Context, offset int64, timeoutSec int, allowed []string) ([]telegram.Update, error)
+}
+
+// requestTimeouter is implemented by clients that put a deadline on each
+// request, such as *telegram.Client.
+type requestTimeouter interface {
+	RequestTimeout(method string, longPollSec int) time.Duration
+}
+
+// UpdateRecorder stores updates as they are received, e.g. a
+// *session.Writer.
+type UpdateRecorder interface {
+	Record(update telegram.Update, receivedAt time.Time) error
+}
+
+// MultiRecorder records every update with each of its recorders in turn,
+// stopping at the first error.
+type MultiRecorder []UpdateRecorder
+
+func (m MultiRecorder) Record(update telegram.Update, receivedAt time.Time) error {
+	for _, r := range m {
+		if err := r.Record(update, receivedAt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 type Options struct {
 	Interval      time.Duration
 	TimeoutSecond int
@@ -22,11 +55,47 @@ type Options struct {
 	DeleteWebhook bool
 	Once          bool
 	OutputFormat  string
+	// AllowedUpdates, when set, asks Telegram for these update types only,
+	// so other updates are not consumed. Telegram keeps the setting after
+	// Run returns.
+	AllowedUpdates []string
+	Recorder       UpdateRecorder
+	// Handler, when set, is called for every update after it is written,
+	// e.g. to run an exec hook. Failures are retried according to Retry
+	// and then passed to DeadLetter.
+	Handler    UpdateHandler
+	Retry      RetryPolicy
+	DeadLetter DeadLetterSink
+	// Columns selects the csv/tsv columns, DefaultColumns when empty. Run
+	// writes rows only; see FormatHeader.
+	Columns []string
+	// Envelope wraps every update with its receive time, bot, profile and
+	// source; see Envelope.
+	Envelope bool
+	// Tag, when set, labels every update with the bot it came from; see
+	// FormatTaggedUpdate.
+	Tag Tag
+	// Filter, when set, skips the updates it rejects: they are still
+	// recorded and confirmed, but neither written nor handled.
+	Filter func(update telegram.Update) bool
+	// Workers above 1 handle updates on a Dispatcher: chats in parallel,
+	// each chat in order, confirming an update to Telegram only once it and
+	// every update before it are done.
+	Workers int
+	// KeepOffset polls with InitialOffset throughout, so no update is
+	// confirmed to Telegram, e.g. for a dry run. Updates already handled
+	// are skipped by id; only the first 100 pending updates are ever seen.
+	KeepOffset bool
 }
 
+// pendingRecheck is how long a dispatching Run waits for a worker to finish
+// before polling again when getUpdates only returned in-flight updates.
+const pendingRecheck = time.Second
+
 type Poller struct {
-	api  telegramAPI
-	opts Options
+	api    telegramAPI
+	opts   Options
+	topics TopicNames
 }
 
 func New(api telegramAPI, opts Options) *Poller {
@@ -39,11 +108,34 @@ func New(api telegramAPI, opts Options) *Poller {
 	if opts.OutputFormat == "" {
 		opts.OutputFormat = "pretty"
 	}
-	return &Poller{api: api, opts: opts}
+	return &Poller{api: api, opts: opts, topics: TopicNames{}}
+}
+
+// Validate reports option combinations that cannot work, before any request
+// is made: a client deadline at or below the long-poll timeout cuts every
+// empty poll short, a zero timeout with a zero interval polls in a busy
+// loop, and retries are pointless with Once, which never confirms the
+// updates it handled.
+func (p *Poller) Validate() error {
+	if rt, ok := p.api.(requestTimeouter); ok {
+		longPoll := time.Duration(p.opts.TimeoutSecond) * time.Second
+		if deadline := rt.RequestTimeout("getUpdates", p.opts.TimeoutSecond); deadline > 0 && deadline <= longPoll {
+			return fmt.Errorf("client request deadline %s does not exceed long-poll timeout %s", deadline, longPoll)
+		}
+	}
+	if !p.opts.Once && p.opts.TimeoutSecond == 0 && p.opts.Interval == 0 {
+		return errors.New("a long-poll timeout of 0 needs a polling interval above 0")
+	}
+	if p.opts.Once && p.opts.Handler != nil && p.opts.Retry.MaxAttempts > 1 {
+		return errors.New("a single polling cycle confirms nothing, so it cannot retry failed updates")
+	}
+	return nil
 }
 
 func (p *Poller) Run(ctx context.Context, outWriter, errWriter io.Writer) error {
-	offset := p.opts.InitialOffset
+	if err := p.Validate(); err != nil {
+		return err
+	}
 	if p.opts.DeleteWebhook {
 		if _, err := fmt.Fprintln(errWriter, "[info] deleting webhook before polling..."); err != nil {
 			return err
@@ -53,39 +145,185 @@ func (p *Poller) Run(ctx context.Context, outWriter, errWriter io.Writer) error
 		}
 	}
 
+	outWriter, errWriter = &syncWriter{w: outWriter}, &syncWriter{w: errWriter}
+	handle := HandlerFunc(func(ctx context.Context, update telegram.Update) error {
+		if p.opts.Filter != nil && !p.opts.Filter(update) {
+			return nil
+		}
+		formatted, err := p.format(update)
+		if err != nil {
+			return err
+		}
+		if _, err := outWriter.Write(formatted); err != nil {
+			return err
+		}
+		return p.deliver(ctx, update, errWriter)
+	})
+
+	if p.opts.Workers > 1 {
+		return p.runDispatched(ctx, handle)
+	}
+
+	offset := p.opts.InitialOffset
+	seen := offset
 	for {
-		updates, err := p.api.GetUpdates(ctx, offset, p.opts.TimeoutSecond)
+		updates, err := p.getUpdates(ctx, offset)
 		if err != nil {
 			return err
 		}
+		receivedAt := time.Now()
 
 		for _, update := range updates {
-			formatted, err := FormatUpdate(update.Raw, p.opts.OutputFormat)
-			if err != nil {
+			if update.UpdateID < seen {
+				continue
+			}
+			seen = update.UpdateID + 1
+			update.ReceivedAt = receivedAt
+			p.topics.Learn(&update)
+			if err := p.record(update, receivedAt); err != nil {
 				return err
 			}
-			if _, err := outWriter.Write(formatted); err != nil {
+			if err := handle(ctx, update); err != nil {
 				return err
 			}
-			if update.UpdateID >= offset {
-				offset = update.UpdateID + 1
+			if !p.opts.KeepOffset {
+				offset = seen
 			}
 		}
 
 		if p.opts.Once {
 			return nil
 		}
+		if err := p.sleep(ctx, p.opts.Interval, nil); err != nil {
+			return err
+		}
+	}
+}
+
+// runDispatched polls with the offset held at the oldest unfinished update.
+// getUpdates therefore returns in-flight updates again; they are skipped by
+// id, and when nothing new arrived Run waits for a worker before re-polling.
+func (p *Poller) runDispatched(ctx context.Context, handle UpdateHandler) error {
+	d := NewDispatcher(ctx, p.opts.Workers, handle)
+	offset := p.opts.InitialOffset
+	seen := offset
+
+	fail := func(err error) error {
+		if werr := d.Wait(); werr != nil {
+			return werr
+		}
+		return err
+	}
+
+	for {
+		if next := d.Offset(); next > offset && !p.opts.KeepOffset {
+			offset = next
+		}
+		updates, err := p.getUpdates(ctx, offset)
+		if err != nil {
+			return fail(err)
+		}
+		receivedAt := time.Now()
 
-		if p.opts.Interval > 0 {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-time.After(p.opts.Interval):
+		fresh := 0
+		for _, update := range updates {
+			if update.UpdateID < seen {
+				continue
+			}
+			seen = update.UpdateID + 1
+			fresh++
+			update.ReceivedAt = receivedAt
+			p.topics.Learn(&update)
+			if err := p.record(update, receivedAt); err != nil {
+				return fail(err)
 			}
+			if err := d.Dispatch(ctx, update); err != nil {
+				return fail(err)
+			}
+		}
+
+		if p.opts.Once {
+			return d.Wait()
+		}
+		if err := d.Err(); err != nil {
+			return fail(err)
+		}
+
+		progress := d.Progress()
+		wait, wake := p.opts.Interval, (<-chan struct{})(nil)
+		if fresh == 0 && d.Offset() < seen {
+			wait, wake = max(wait, pendingRecheck), progress
+		}
+		if err := p.sleep(ctx, wait, wake); err != nil {
+			return fail(err)
 		}
 	}
 }
 
+func (p *Poller) getUpdates(ctx context.Context, offset int64) ([]telegram.Update, error) {
+	if len(p.opts.AllowedUpdates) == 0 {
+		return p.api.GetUpdates(ctx, offset, p.opts.TimeoutSecond)
+	}
+	api, ok := p.api.(allowedUpdatesAPI)
+	if !ok {
+		return nil, errors.New("client cannot restrict allowed updates")
+	}
+	return api.GetUpdatesAllowed(ctx, offset, p.opts.TimeoutSecond, p.opts.AllowedUpdates)
+}
+
+func (p *Poller) format(update telegram.Update) ([]byte, error) {
+	if IsTableFormat(p.opts.OutputFormat) {
+		return FormatUpdateRow(update, p.opts.OutputFormat, p.opts.Columns, p.opts.Tag)
+	}
+	if p.opts.Envelope {
+		return FormatEnvelope(NewEnvelope(update, SourcePolling, p.opts.Tag), p.opts.OutputFormat)
+	}
+	formatted, err := FormatTaggedUpdate(update.Raw, p.opts.OutputFormat, p.opts.Tag)
+	if err != nil {
+		return nil, err
+	}
+	return WithTopicHeader(formatted, update, p.opts.OutputFormat), nil
+}
+
+func (p *Poller) record(update telegram.Update, receivedAt time.Time) error {
+	if p.opts.Recorder == nil {
+		return nil
+	}
+	if err := p.opts.Recorder.Record(update, receivedAt); err != nil {
+		return fmt.Errorf("record update: %w", err)
+	}
+	return nil
+}
+
+// sleep waits for d, cut short when wake is closed. It returns ctx's error
+// if ctx is done first.
+func (p *Poller) sleep(ctx context.Context, d time.Duration, wake <-chan struct{}) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+	case <-wake:
+	}
+	return nil
+}
+
+// syncWriter serializes writes from dispatcher workers.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
+
 func FormatUpdate(raw []byte, outputFormat string) ([]byte, error) {
 	switch outputFormat {
 	case "jsonl":
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/example/tgbot-cli/internal/telegram"
 )
@@ -13,6 +14,8 @@ type fakeAPI struct {
 	deleteCalled bool
 	updates      [][]telegram.Update
 	idx          int
+	offsets      []int64
+	onCall       func(call int)
 }
 
 func (f *fakeAPI) DeleteWebhook(_ context.Context) error {
@@ -20,7 +23,11 @@ func (f *fakeAPI) DeleteWebhook(_ context.Context) error {
 	return nil
 }
 
-func (f *fakeAPI) GetUpdates(_ context.Context, _ int64, _ int) ([]telegram.Update, error) {
+func (f *fakeAPI) GetUpdates(_ context.Context, offset int64, _ int) ([]telegram.Update, error) {
+	f.offsets = append(f.offsets, offset)
+	if f.onCall != nil {
+		f.onCall(f.idx)
+	}
 	if f.idx >= len(f.updates) {
 		return nil, context.Canceled
 	}
@@ -63,6 +70,46 @@ func TestPollerRunWritesFormattedOutput(t *testing.T) {
 	}
 }
 
+func TestPollerRunFilter(t *testing.T) {
+	api := &fakeAPI{updates: [][]telegram.Update{{
+		{UpdateID: 1, Raw: []byte(`{"update_id":1,"message":{"text":"general"}}`)},
+		{UpdateID: 2, Raw: []byte(`{"update_id":2,"message":{"text":"topic"}}`), Message: &telegram.Message{MessageThreadID: 5}},
+	}}}
+	rec := &memRecorder{}
+	p := New(api, Options{
+		Once:         true,
+		OutputFormat: "jsonl",
+		Recorder:     rec,
+		Filter:       func(u telegram.Update) bool { return u.Message != nil && u.Message.MessageThreadID == 5 },
+	})
+	var out strings.Builder
+	if err := p.Run(context.Background(), &out, &strings.Builder{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if out.String() != "{\"update_id\":2,\"message\":{\"text\":\"topic\"}}\n" || len(rec.got) != 2 {
+		t.Fatalf("expected only the topic update written and both recorded, got %q (%d recorded)", out.String(), len(rec.got))
+	}
+}
+
+func TestPollerRunKeepOffset(t *testing.T) {
+	first := telegram.Update{UpdateID: 7, Raw: []byte(`{"update_id":7}`)}
+	second := telegram.Update{UpdateID: 8, Raw: []byte(`{"update_id":8}`)}
+	api := &fakeAPI{updates: [][]telegram.Update{{first}, {first, second}}}
+	p := New(api, Options{TimeoutSecond: 1, OutputFormat: "jsonl", KeepOffset: true})
+	var out strings.Builder
+	if err := p.Run(context.Background(), &out, &strings.Builder{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the fake to stop Run, got %v", err)
+	}
+	if out.String() != "{\"update_id\":7}\n{\"update_id\":8}\n" {
+		t.Fatalf("expected each update written once, got %q", out.String())
+	}
+	for _, offset := range api.offsets {
+		if offset != 0 {
+			t.Fatalf("expected no offset to be confirmed, got %v", api.offsets)
+		}
+	}
+}
+
 func TestPollerRunDeleteWebhook(t *testing.T) {
 	api := &fakeAPI{updates: [][]telegram.Update{{}}}
 	p := New(api, Options{Once: true, DeleteWebhook: true, OutputFormat: "jsonl"})
@@ -88,3 +135,88 @@ func TestPollerRunErrorOnInvalidJSON(t *testing.T) {
 		t.Fatalf("should not be context canceled")
 	}
 }
+
+type deadlineAPI struct {
+	fakeAPI
+	deadline time.Duration
+}
+
+func (d *deadlineAPI) RequestTimeout(_ string, _ int) time.Duration {
+	return d.deadline
+}
+
+func TestPollerValidate(t *testing.T) {
+	api := &deadlineAPI{fakeAPI: fakeAPI{updates: [][]telegram.Update{{}}}, deadline: 30 * time.Second}
+	p := New(api, Options{Once: true, TimeoutSecond: 60, DeleteWebhook: true})
+	err := p.Run(context.Background(), &strings.Builder{}, &strings.Builder{})
+	if err == nil || !strings.Contains(err.Error(), "does not exceed long-poll timeout") {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+	if api.deleteCalled {
+		t.Fatalf("validation should fail before any request")
+	}
+	api.deadline = 75 * time.Second
+	if err := p.Validate(); err != nil {
+		t.Fatalf("expected valid combination, got %v", err)
+	}
+
+	handler := HandlerFunc(func(context.Context, telegram.Update) error { return nil })
+	for name, tc := range map[string]struct {
+		opts Options
+		want string
+	}{
+		"busy loop":      {Options{}, "needs a polling interval"},
+		"once retries":   {Options{Once: true, Handler: handler, Retry: RetryPolicy{MaxAttempts: 3}}, "cannot retry"},
+		"interval only":  {Options{Interval: time.Second}, ""},
+		"once no retry":  {Options{Once: true, Handler: handler, Retry: RetryPolicy{MaxAttempts: 1}}, ""},
+		"long poll only": {Options{TimeoutSecond: 20}, ""},
+	} {
+		err := New(&fakeAPI{}, tc.opts).Validate()
+		if tc.want == "" && err != nil || tc.want != "" && (err == nil || !strings.Contains(err.Error(), tc.want)) {
+			t.Fatalf("%s: expected %q, got %v", name, tc.want, err)
+		}
+	}
+}
+
+type recordedUpdate struct {
+	id int64
+	at time.Time
+}
+
+type memRecorder struct {
+	got []recordedUpdate
+}
+
+func (m *memRecorder) Record(update telegram.Update, receivedAt time.Time) error {
+	m.got = append(m.got, recordedUpdate{id: update.UpdateID, at: receivedAt})
+	return nil
+}
+
+func TestPollerRunRecordsUpdates(t *testing.T) {
+	api := &fakeAPI{updates: [][]telegram.Update{{
+		{UpdateID: 1, Raw: []byte(`{"update_id":1}`)},
+		{UpdateID: 2, Raw: []byte(`{"update_id":2}`)},
+	}}}
+	rec := &memRecorder{}
+	p := New(api, Options{Once: true, OutputFormat: "jsonl", Recorder: rec})
+	if err := p.Run(context.Background(), &strings.Builder{}, &strings.Builder{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(rec.got) != 2 || rec.got[1].id != 2 || rec.got[0].at.IsZero() {
+		t.Fatalf("unexpected recorded updates: %+v", rec.got)
+	}
+}
+
+func TestPollerRunMultiRecorder(t *testing.T) {
+	api := &fakeAPI{updates: [][]telegram.Update{{
+		{UpdateID: 1, Raw: []byte(`{"update_id":1}`)},
+	}}}
+	first, second := &memRecorder{}, &memRecorder{}
+	p := New(api, Options{Once: true, OutputFormat: "jsonl", Recorder: MultiRecorder{first, second}})
+	if err := p.Run(context.Background(), &strings.Builder{}, &strings.Builder{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if len(first.got) != 1 || len(second.got) != 1 {
+		t.Fatalf("expected both recorders to get the update: %+v %+v", first.got, second.got)
+	}
+}
@@ -0,0 +1,231 @@
+package polling
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// DefaultColumns are the csv/tsv columns used when none are given.
+var DefaultColumns = []string{"update_id", "type", "date", "chat_id", "chat_title", "from_id", "username", "text"}
+
+// columnFuncs are the named columns. Any other column is a dot-separated
+// JSON path into the raw update, such as message.from.language_code or
+// message.photo.0.file_id.
+var columnFuncs = map[string]func(update telegram.Update, tag Tag) string{
+	"update_id": func(u telegram.Update, _ Tag) string { return strconv.FormatInt(u.UpdateID, 10) },
+	"type":      func(u telegram.Update, _ Tag) string { return u.Type() },
+	"date":      func(u telegram.Update, _ Tag) string { return updateDate(u) },
+	"received_at": func(u telegram.Update, _ Tag) string {
+		if u.ReceivedAt.IsZero() {
+			return ""
+		}
+		return u.ReceivedAt.UTC().Format(time.RFC3339)
+	},
+	"chat_id": func(u telegram.Update, _ Tag) string {
+		if c := u.EffectiveChat(); c != nil {
+			return strconv.FormatInt(c.ID, 10)
+		}
+		return ""
+	},
+	"chat_type": func(u telegram.Update, _ Tag) string {
+		if c := u.EffectiveChat(); c != nil {
+			return c.Type
+		}
+		return ""
+	},
+	"chat_title": func(u telegram.Update, _ Tag) string {
+		c := u.EffectiveChat()
+		switch {
+		case c == nil:
+			return ""
+		case c.Title != "":
+			return c.Title
+		case c.FirstName != "" || c.LastName != "":
+			return strings.TrimSpace(c.FirstName + " " + c.LastName)
+		}
+		return c.Username
+	},
+	"from_id": func(u telegram.Update, _ Tag) string {
+		if user := u.EffectiveUser(); user != nil {
+			return strconv.FormatInt(user.ID, 10)
+		}
+		return ""
+	},
+	"username": func(u telegram.Update, _ Tag) string {
+		if user := u.EffectiveUser(); user != nil {
+			return user.Username
+		}
+		return ""
+	},
+	"message_id": func(u telegram.Update, _ Tag) string {
+		if m := u.EffectiveMessage(); m != nil {
+			return strconv.FormatInt(m.MessageID, 10)
+		}
+		return ""
+	},
+	"thread_id": func(u telegram.Update, _ Tag) string {
+		if m := u.EffectiveMessage(); m != nil && m.MessageThreadID != 0 {
+			return strconv.FormatInt(m.MessageThreadID, 10)
+		}
+		return ""
+	},
+	"topic": func(u telegram.Update, _ Tag) string {
+		if u.Topic != "" {
+			return u.Topic
+		}
+		if m := u.EffectiveMessage(); m != nil {
+			return m.TopicName()
+		}
+		return ""
+	},
+	"text":    func(u telegram.Update, _ Tag) string { return updateText(u) },
+	"bot":     func(_ telegram.Update, tag Tag) string { return tag.Bot },
+	"profile": func(_ telegram.Update, tag Tag) string { return tag.Profile },
+}
+
+// IsTableFormat reports whether outputFormat is csv or tsv.
+func IsTableFormat(outputFormat string) bool {
+	return outputFormat == "csv" || outputFormat == "tsv"
+}
+
+// FormatHeader returns the csv/tsv header row for columns. Callers write
+// it once before the rows, since Run cannot tell whether it starts a
+// stream or is one of several pollers sharing it.
+func FormatHeader(outputFormat string, columns []string) ([]byte, error) {
+	return formatRecord(outputFormat, orDefaultColumns(columns))
+}
+
+// FormatUpdateRow renders update as one csv/tsv row. Missing fields are
+// empty; nested objects and arrays are written as compact JSON.
+func FormatUpdateRow(update telegram.Update, outputFormat string, columns []string, tag Tag) ([]byte, error) {
+	columns = orDefaultColumns(columns)
+	var fields map[string]any
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		if fn, ok := columnFuncs[col]; ok {
+			row[i] = fn(update, tag)
+			continue
+		}
+		if fields == nil {
+			decoder := json.NewDecoder(bytes.NewReader(update.Raw))
+			decoder.UseNumber()
+			if err := decoder.Decode(&fields); err != nil {
+				return nil, fmt.Errorf("decode update json: %w", err)
+			}
+		}
+		value, err := cellValue(lookupPath(fields, col))
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", col, err)
+		}
+		row[i] = value
+	}
+	return formatRecord(outputFormat, row)
+}
+
+func orDefaultColumns(columns []string) []string {
+	if len(columns) == 0 {
+		return DefaultColumns
+	}
+	return columns
+}
+
+func formatRecord(outputFormat string, record []string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	switch outputFormat {
+	case "csv":
+	case "tsv":
+		w.Comma = '\t'
+	default:
+		return nil, fmt.Errorf("unknown table format %q (want csv or tsv)", outputFormat)
+	}
+	if err := w.Write(record); err != nil {
+		return nil, err
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// lookupPath walks a dot-separated path through objects and, by index,
+// arrays. It returns nil when any step is missing.
+func lookupPath(v any, path string) any {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func cellValue(v any) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return v.String(), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
+
+// updateDate is the payload's own date in RFC 3339, falling back to the
+// receive time for payloads without one (callback and inline queries).
+func updateDate(u telegram.Update) string {
+	var unix int64
+	switch {
+	case u.CallbackQuery != nil, u.InlineQuery != nil:
+	case u.EffectiveMessage() != nil:
+		unix = u.EffectiveMessage().Date
+	case u.MyChatMember != nil:
+		unix = u.MyChatMember.Date
+	case u.ChatMember != nil:
+		unix = u.ChatMember.Date
+	case u.ChatJoinRequest != nil:
+		unix = u.ChatJoinRequest.Date
+	}
+	switch {
+	case unix != 0:
+		return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	case !u.ReceivedAt.IsZero():
+		return u.ReceivedAt.UTC().Format(time.RFC3339)
+	}
+	return ""
+}
+
+// updateText is the text a user sent: message text or caption, callback
+// data or inline query.
+func updateText(u telegram.Update) string {
+	switch {
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.Data
+	case u.InlineQuery != nil:
+		return u.InlineQuery.Query
+	}
+	if m := u.EffectiveMessage(); m != nil {
+		if m.Text != "" {
+			return m.Text
+		}
+		return m.Caption
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package polling
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func parseUpdate(t *testing.T, raw string) telegram.Update {
+	t.Helper()
+	u, err := telegram.ParseUpdate([]byte(raw))
+	if err != nil {
+		t.Fatalf("parse update: %v", err)
+	}
+	return u
+}
+
+func TestFormatUpdateRowDefaultColumns(t *testing.T) {
+	u := parseUpdate(t, `{"update_id":7,"message":{"message_id":1,"date":1700000000,"chat":{"id":-100,"type":"group","title":"Ops, \"EU\""},"from":{"id":42,"is_bot":false,"first_name":"Alice","username":"alice"},"text":"line one\nline two"}}`)
+
+	header, err := FormatHeader("csv", nil)
+	if err != nil {
+		t.Fatalf("FormatHeader returned error: %v", err)
+	}
+	if string(header) != "update_id,type,date,chat_id,chat_title,from_id,username,text\n" {
+		t.Fatalf("unexpected header: %q", header)
+	}
+	row, err := FormatUpdateRow(u, "csv", nil, Tag{})
+	if err != nil {
+		t.Fatalf("FormatUpdateRow returned error: %v", err)
+	}
+	want := "7,message,2023-11-14T22:13:20Z,-100,\"Ops, \"\"EU\"\"\",42,alice,\"line one\nline two\"\n"
+	if string(row) != want {
+		t.Fatalf("unexpected csv row:\n got %q\nwant %q", row, want)
+	}
+
+	row, err = FormatUpdateRow(u, "tsv", []string{"chat_id", "text"}, Tag{})
+	if err != nil {
+		t.Fatalf("FormatUpdateRow returned error: %v", err)
+	}
+	if string(row) != "-100\t\"line one\nline two\"\n" {
+		t.Fatalf("unexpected tsv row: %q", row)
+	}
+}
+
+func TestFormatUpdateRowTopicColumns(t *testing.T) {
+	u := parseUpdate(t, `{"update_id":9,"message":{"message_id":11,"message_thread_id":10,"is_topic_message":true,"date":1700000000,"chat":{"id":-100,"type":"supergroup","is_forum":true},"reply_to_message":{"message_id":10,"date":1699990000,"chat":{"id":-100,"type":"supergroup"},"forum_topic_created":{"name":"Bugs","icon_color":7322096}},"text":"crash"}}`)
+	row, err := FormatUpdateRow(u, "csv", []string{"thread_id", "topic", "text"}, Tag{})
+	if err != nil {
+		t.Fatalf("FormatUpdateRow returned error: %v", err)
+	}
+	if string(row) != "10,Bugs,crash\n" {
+		t.Fatalf("unexpected topic row: %q", row)
+	}
+}
+
+func TestFormatUpdateRowJSONPaths(t *testing.T) {
+	u := parseUpdate(t, `{"update_id":8,"message":{"message_id":2,"date":1700000000,"chat":{"id":1,"type":"private"},"from":{"id":1,"is_bot":false,"first_name":"A","language_code":"de"},"photo":[{"file_id":"small","width":90},{"file_id":"big","width":800}],"caption":"pic"}}`)
+	u.ReceivedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cols := []string{"message.from.language_code", "message.photo.1.file_id", "message.photo.0", "message.missing.deep", "message.photo.9", "text", "received_at", "bot", "profile"}
+	row, err := FormatUpdateRow(u, "csv", cols, Tag{Profile: "dev", Bot: "@dev_bot"})
+	if err != nil {
+		t.Fatalf("FormatUpdateRow returned error: %v", err)
+	}
+	want := `de,big,"{""file_id"":""small"",""width"":90}",,,pic,2024-05-01T12:00:00Z,@dev_bot,dev` + "\n"
+	if string(row) != want {
+		t.Fatalf("unexpected row:\n got %q\nwant %q", row, want)
+	}
+}
+
+func TestFormatUpdateRowQueries(t *testing.T) {
+	u := parseUpdate(t, `{"update_id":9,"callback_query":{"id":"c","from":{"id":5,"is_bot":false,"first_name":"B","username":"bob"},"chat_instance":"x","data":"vote:1","message":{"message_id":3,"date":1600000000,"chat":{"id":5,"type":"private","first_name":"B"},"text":"pick"}}}`)
+	u.ReceivedAt = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	row, err := FormatUpdateRow(u, "csv", nil, Tag{})
+	if err != nil {
+		t.Fatalf("FormatUpdateRow returned error: %v", err)
+	}
+	if string(row) != "9,callback_query,2024-05-01T12:00:00Z,5,B,5,bob,vote:1\n" {
+		t.Fatalf("unexpected row: %q", row)
+	}
+}
+
+func TestPollerRunWritesTableRows(t *testing.T) {
+	api := &fakeAPI{updates: [][]telegram.Update{{
+		parseUpdate(t, `{"update_id":1,"message":{"message_id":1,"date":1700000000,"chat":{"id":3,"type":"private"},"text":"hi"}}`),
+	}}}
+	var out strings.Builder
+	p := New(api, Options{Once: true, OutputFormat: "tsv", Columns: []string{"update_id", "message.text"}})
+	if err := p.Run(context.Background(), &out, &strings.Builder{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if out.String() != "1\thi\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
@@ -0,0 +1,40 @@
+package polling
+
+import (
+	"fmt"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// TopicNames remembers forum topic names as updates arrive. A message only
+// carries its topic's name when it creates or renames the topic or replies
+// to nothing else, so the names are learned from those and applied to the
+// rest of the topic's messages.
+type TopicNames map[topicKey]string
+
+type topicKey struct{ chatID, threadID int64 }
+
+// Learn records the topic name update's message reveals, if any, and sets
+// update.Topic to its topic's name when known.
+func (t TopicNames) Learn(update *telegram.Update) {
+	m := update.EffectiveMessage()
+	if m == nil || m.MessageThreadID == 0 {
+		return
+	}
+	key := topicKey{m.Chat.ID, m.MessageThreadID}
+	rename := m.ForumTopicCreated != nil || m.ForumTopicEdited != nil
+	if name := m.TopicName(); name != "" && (rename || t[key] == "") {
+		t[key] = name
+	}
+	update.Topic = t[key]
+}
+
+// WithTopicHeader prefixes pretty output of update with a "[topic Name (id)]"
+// line when its topic name is known. Other formats are returned as is.
+func WithTopicHeader(formatted []byte, update telegram.Update, outputFormat string) []byte {
+	if update.Topic == "" || outputFormat == "jsonl" || IsTableFormat(outputFormat) {
+		return formatted
+	}
+	header := fmt.Sprintf("[topic %s (%d)]\n", update.Topic, update.EffectiveMessage().MessageThreadID)
+	return append([]byte(header), formatted...)
+}
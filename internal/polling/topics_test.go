@@ -0,0 +1,46 @@
+package polling
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func TestPollerLearnsTopicNames(t *testing.T) {
+	api := &fakeAPI{updates: [][]telegram.Update{{
+		parseUpdate(t, `{"update_id":1,"message":{"message_id":10,"message_thread_id":10,"is_topic_message":true,"date":1700000000,"chat":{"id":-100,"type":"supergroup","is_forum":true},"forum_topic_created":{"name":"Bugs","icon_color":7322096}}}`),
+		parseUpdate(t, `{"update_id":2,"message":{"message_id":12,"message_thread_id":10,"is_topic_message":true,"date":1700000001,"chat":{"id":-100,"type":"supergroup","is_forum":true},"reply_to_message":{"message_id":11,"message_thread_id":10,"date":1700000000,"chat":{"id":-100,"type":"supergroup"},"text":"crash"},"text":"same here"}}`),
+		parseUpdate(t, `{"update_id":3,"message":{"message_id":13,"message_thread_id":10,"is_topic_message":true,"date":1700000002,"chat":{"id":-100,"type":"supergroup","is_forum":true},"forum_topic_edited":{"name":"Bug reports"}}}`),
+		parseUpdate(t, `{"update_id":4,"message":{"message_id":14,"message_thread_id":10,"is_topic_message":true,"date":1700000003,"chat":{"id":-100,"type":"supergroup","is_forum":true},"reply_to_message":{"message_id":12,"message_thread_id":10,"date":1700000001,"chat":{"id":-100,"type":"supergroup"},"text":"same here"},"text":"fixed"}}`),
+		parseUpdate(t, `{"update_id":5,"message":{"message_id":20,"message_thread_id":10,"is_topic_message":true,"date":1700000004,"chat":{"id":-200,"type":"supergroup","is_forum":true},"reply_to_message":{"message_id":15,"date":1700000000,"chat":{"id":-200,"type":"supergroup"},"text":"hello"},"text":"other forum"}}`),
+	}}}
+	var out strings.Builder
+	p := New(api, Options{Once: true, OutputFormat: "csv", Columns: []string{"update_id", "topic"}, Filter: func(u telegram.Update) bool { return u.UpdateID != 1 }})
+	if err := p.Run(context.Background(), &out, &strings.Builder{}); err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if want := "2,Bugs\n3,Bug reports\n4,Bug reports\n5,\n"; out.String() != want {
+		t.Fatalf("unexpected topics:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWithTopicHeader(t *testing.T) {
+	u := parseUpdate(t, `{"update_id":2,"message":{"message_id":12,"message_thread_id":10,"date":1700000001,"chat":{"id":-100,"type":"supergroup"},"text":"same here"}}`)
+	names := TopicNames{{chatID: -100, threadID: 10}: "Bugs"}
+	names.Learn(&u)
+	pretty, err := FormatUpdate(u.Raw, "pretty")
+	if err != nil {
+		t.Fatalf("FormatUpdate returned error: %v", err)
+	}
+	if got := string(WithTopicHeader(pretty, u, "pretty")); !strings.HasPrefix(got, "[topic Bugs (10)]\n{") {
+		t.Fatalf("expected a topic header, got %q", got)
+	}
+	if got := WithTopicHeader([]byte("{}\n"), u, "jsonl"); string(got) != "{}\n" {
+		t.Fatalf("expected jsonl unchanged, got %q", got)
+	}
+	if env := NewEnvelope(u, SourcePolling, Tag{}); env.Topic != "Bugs" {
+		t.Fatalf("expected the envelope topic, got %+v", env)
+	}
+}
@@ -0,0 +1,184 @@
+// Package session records raw updates with their receive time and replays
+// them later, optionally faster and with rewritten ids.
+package session
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// Entry is one recorded update, stored as a JSON line.
+type Entry struct {
+	ReceivedAt time.Time       `json:"received_at"`
+	Update     json.RawMessage `json:"update"`
+}
+
+// Writer appends entries to a session file. It is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Record writes update with its receive time.
+func (w *Writer) Record(update telegram.Update, receivedAt time.Time) error {
+	line, err := json.Marshal(Entry{ReceivedAt: receivedAt.UTC(), Update: update.Raw})
+	if err != nil {
+		return fmt.Errorf("encode session entry: %w", err)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err = w.w.Write(append(line, '\n'))
+	return err
+}
+
+// Read parses a session file, skipping blank lines.
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(text), &e); err != nil {
+			return nil, fmt.Errorf("session line %d: %w", line, err)
+		}
+		if len(e.Update) == 0 {
+			return nil, fmt.Errorf("session line %d: missing update", line)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// ParseSpeed accepts "10x", "10", "0.5x" or "max" (no delays, returned as 0).
+func ParseSpeed(s string) (float64, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "max" || s == "0" || s == "0x" {
+		return 0, nil
+	}
+	v, err := strconv.ParseFloat(strings.TrimSuffix(s, "x"), 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid speed %q (want e.g. 1x, 10x or max)", s)
+	}
+	return v, nil
+}
+
+// ReplayOptions controls timing and rewriting during Replay.
+type ReplayOptions struct {
+	// Speed divides the original gaps between updates; 0 replays without
+	// waiting.
+	Speed float64
+	// FirstUpdateID, when non-zero, renumbers updates sequentially from it.
+	FirstUpdateID int64
+	// ChatIDs maps recorded chat ids to replacement ids.
+	ChatIDs map[int64]int64
+	// AllChatsTo, when non-zero, replaces every chat id not in ChatIDs.
+	AllChatsTo int64
+}
+
+// Replay calls emit for each entry, waiting the scaled original gap before
+// each one. The update handed to emit has ids rewritten per opts.
+func Replay(ctx context.Context, entries []Entry, opts ReplayOptions, emit func(Entry) error) error {
+	for i, e := range entries {
+		if i > 0 && opts.Speed > 0 {
+			gap := e.ReceivedAt.Sub(entries[i-1].ReceivedAt)
+			if gap > 0 {
+				timer := time.NewTimer(time.Duration(float64(gap) / opts.Speed))
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return ctx.Err()
+				case <-timer.C:
+				}
+			}
+		} else if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rewritten, err := Rewrite(e.Update, i, opts)
+		if err != nil {
+			return fmt.Errorf("rewrite update %d: %w", i+1, err)
+		}
+		if err := emit(Entry{ReceivedAt: e.ReceivedAt, Update: rewritten}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rewrite applies the id rewrites of opts to the index-th update. Updates
+// are returned untouched when no rewrite is configured.
+func Rewrite(raw json.RawMessage, index int, opts ReplayOptions) (json.RawMessage, error) {
+	if opts.FirstUpdateID == 0 && len(opts.ChatIDs) == 0 && opts.AllChatsTo == 0 {
+		return raw, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, errors.New("update is not a JSON object")
+	}
+	if opts.FirstUpdateID != 0 {
+		doc["update_id"] = opts.FirstUpdateID + int64(index)
+	}
+	rewriteChats(doc, opts)
+	return json.Marshal(doc)
+}
+
+// rewriteChats replaces the id of every "chat" and "sender_chat" object
+// anywhere in the update, including replied-to and callback messages.
+func rewriteChats(v any, opts ReplayOptions) {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, child := range val {
+			if key == "chat" || key == "sender_chat" {
+				if chat, ok := child.(map[string]any); ok {
+					rewriteChatID(chat, opts)
+				}
+			}
+			rewriteChats(child, opts)
+		}
+	case []any:
+		for _, child := range val {
+			rewriteChats(child, opts)
+		}
+	}
+}
+
+func rewriteChatID(chat map[string]any, opts ReplayOptions) {
+	num, ok := chat["id"].(json.Number)
+	if !ok {
+		return
+	}
+	id, err := num.Int64()
+	if err != nil {
+		return
+	}
+	if mapped, ok := opts.ChatIDs[id]; ok {
+		chat["id"] = mapped
+	} else if opts.AllChatsTo != 0 {
+		chat["id"] = opts.AllChatsTo
+	}
+}
@@ -0,0 +1,98 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+func TestWriterAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := w.Record(telegram.Update{UpdateID: 1, Raw: []byte(`{"update_id":1}`)}, at); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if err := w.Record(telegram.Update{UpdateID: 2, Raw: []byte(`{"update_id":2}`)}, at.Add(time.Second)); err != nil {
+		t.Fatalf("Record returned error: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"received_at":"2024-01-02T03:04:05Z","update":{"update_id":1}}`+"\n") {
+		t.Fatalf("unexpected session line: %q", buf.String())
+	}
+
+	entries, err := Read(strings.NewReader(buf.String() + "\n"))
+	if err != nil {
+		t.Fatalf("Read returned error: %v", err)
+	}
+	if len(entries) != 2 || !entries[1].ReceivedAt.Equal(at.Add(time.Second)) {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if _, err := Read(strings.NewReader("{not json}\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Fatalf("expected line error, got %v", err)
+	}
+}
+
+func TestParseSpeed(t *testing.T) {
+	cases := map[string]float64{"1x": 1, "10x": 10, "0.5": 0.5, "max": 0}
+	for in, want := range cases {
+		got, err := ParseSpeed(in)
+		if err != nil || got != want {
+			t.Fatalf("ParseSpeed(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseSpeed("fast"); err == nil {
+		t.Fatalf("expected error for invalid speed")
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	raw := json.RawMessage(`{"update_id":5,"callback_query":{"id":"1","message":{"chat":{"id":-1001234567890123,"type":"supergroup"},"reply_to_message":{"chat":{"id":77}}}}}`)
+	out, err := Rewrite(raw, 2, ReplayOptions{FirstUpdateID: 100, ChatIDs: map[int64]int64{-1001234567890123: -1009}, AllChatsTo: 1})
+	if err != nil {
+		t.Fatalf("Rewrite returned error: %v", err)
+	}
+	s := string(out)
+	for _, want := range []string{`"update_id":102`, `"id":-1009`, `"reply_to_message":{"chat":{"id":1}}`} {
+		if !strings.Contains(s, want) {
+			t.Fatalf("rewritten update missing %s: %s", want, s)
+		}
+	}
+
+	same, err := Rewrite(raw, 0, ReplayOptions{})
+	if err != nil || string(same) != string(raw) {
+		t.Fatalf("expected untouched update, got %s, %v", same, err)
+	}
+}
+
+func TestReplayTiming(t *testing.T) {
+	base := time.Now()
+	entries := []Entry{
+		{ReceivedAt: base, Update: []byte(`{"update_id":1}`)},
+		{ReceivedAt: base.Add(time.Second), Update: []byte(`{"update_id":2}`)},
+	}
+
+	var got []string
+	start := time.Now()
+	err := Replay(context.Background(), entries, ReplayOptions{Speed: 10}, func(e Entry) error {
+		got = append(got, string(e.Update))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay returned error: %v", err)
+	}
+	elapsed := time.Since(start)
+	if len(got) != 2 || elapsed < 90*time.Millisecond || elapsed > 900*time.Millisecond {
+		t.Fatalf("expected 2 updates ~100ms apart, got %d in %s", len(got), elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Replay(ctx, entries, ReplayOptions{}, func(Entry) error { return nil }); err == nil {
+		t.Fatalf("expected cancelled replay to fail")
+	}
+}
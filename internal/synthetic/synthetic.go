@@ -0,0 +1,234 @@
+// Package synthetic builds realistic Telegram updates for testing webhook
+// backends without Telegram.
+package synthetic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// Types lists the update types Build can produce.
+var Types = []string{
+	"message", "edited_message", "channel_post", "callback_query", "inline_query",
+	"chat_member", "my_chat_member", "chat_join_request",
+}
+
+// Bot is the bot account used where an update involves the bot itself, such
+// as the message a callback button belongs to.
+var Bot = telegram.User{ID: 100000001, IsBot: true, FirstName: "Test Bot", Username: "test_bot"}
+
+type Options struct {
+	// Type is one of Types; defaults to "message".
+	Type string
+	// UpdateID defaults to a value derived from Now.
+	UpdateID int64
+
+	// Text is the message text, the join request bio for chat_join_request.
+	Text string
+	// Data is the callback_query payload.
+	Data string
+	// Query is the inline_query text.
+	Query string
+
+	FromID       int64
+	FromUsername string
+	FromName     string
+
+	// ChatID defaults to the sender's private chat, or a fixed group id for
+	// group chat types.
+	ChatID    int64
+	ChatType  string
+	ChatTitle string
+	MessageID int64
+
+	// Status is the new member status for chat_member and my_chat_member.
+	Status string
+
+	// Now stamps dates; defaults to time.Now.
+	Now time.Time
+}
+
+// Build returns a typed update with Raw left empty; callers marshal it.
+func Build(opts Options) (telegram.Update, error) {
+	if opts.Type == "" {
+		opts.Type = "message"
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.UpdateID == 0 {
+		opts.UpdateID = opts.Now.UnixMilli() % 1_000_000_000
+	}
+	if opts.MessageID == 0 {
+		opts.MessageID = opts.Now.Unix() % 100_000
+	}
+	if opts.Status == "" {
+		opts.Status = "member"
+	}
+	if opts.Type == "channel_post" && opts.ChatType == "" {
+		opts.ChatType = "channel"
+	}
+
+	from := sender(opts)
+	chat, err := chat(opts, from)
+	if err != nil {
+		return telegram.Update{}, err
+	}
+	date := opts.Now.Unix()
+	u := telegram.Update{UpdateID: opts.UpdateID}
+
+	switch opts.Type {
+	case "message", "edited_message":
+		m := message(opts, chat, &from, date)
+		if opts.Type == "edited_message" {
+			m.EditDate = date
+			m.Date = date - 60
+			u.EditedMessage = m
+		} else {
+			u.Message = m
+		}
+	case "channel_post":
+		if chat.Type != "channel" {
+			return telegram.Update{}, fmt.Errorf("channel_post requires chat type channel, got %q", chat.Type)
+		}
+		m := message(opts, chat, nil, date)
+		m.SenderChat = &chat
+		u.ChannelPost = m
+	case "callback_query":
+		bot := Bot
+		m := message(Options{Text: "Choose an option", MessageID: opts.MessageID}, chat, &bot, date-60)
+		u.CallbackQuery = &telegram.CallbackQuery{
+			ID:           strconv.FormatInt(from.ID, 10) + strconv.FormatInt(opts.UpdateID, 10),
+			From:         from,
+			Message:      m,
+			ChatInstance: strconv.FormatInt(chat.ID*7919, 10),
+			Data:         opts.Data,
+		}
+	case "inline_query":
+		chatType := opts.ChatType
+		if chatType == "" || chatType == "private" && chat.ID == from.ID {
+			chatType = "sender"
+		}
+		u.InlineQuery = &telegram.InlineQuery{
+			ID:       strconv.FormatInt(from.ID, 10) + strconv.FormatInt(opts.UpdateID, 10),
+			From:     from,
+			Query:    opts.Query,
+			ChatType: chatType,
+		}
+	case "chat_member":
+		u.ChatMember = &telegram.ChatMemberUpdated{
+			Chat:          chat,
+			From:          from,
+			Date:          date,
+			OldChatMember: telegram.ChatMember{Status: "left", User: from},
+			NewChatMember: telegram.ChatMember{Status: opts.Status, User: from},
+		}
+	case "my_chat_member":
+		u.MyChatMember = &telegram.ChatMemberUpdated{
+			Chat:          chat,
+			From:          from,
+			Date:          date,
+			OldChatMember: telegram.ChatMember{Status: "left", User: Bot},
+			NewChatMember: telegram.ChatMember{Status: opts.Status, User: Bot},
+		}
+	case "chat_join_request":
+		u.ChatJoinRequest = &telegram.ChatJoinRequest{
+			Chat:       chat,
+			From:       from,
+			UserChatID: from.ID,
+			Date:       date,
+			Bio:        opts.Text,
+		}
+	default:
+		return telegram.Update{}, fmt.Errorf("unsupported update type %q (want one of %s)", opts.Type, strings.Join(Types, ", "))
+	}
+	return u, nil
+}
+
+func sender(opts Options) telegram.User {
+	u := telegram.User{ID: opts.FromID, FirstName: opts.FromName, Username: opts.FromUsername, LanguageCode: "en"}
+	if u.ID == 0 {
+		u.ID = 42
+	}
+	if u.FirstName == "" {
+		u.FirstName = "Test"
+	}
+	if u.Username == "" {
+		u.Username = "user" + strconv.FormatInt(u.ID, 10)
+	}
+	return u
+}
+
+func chat(opts Options, from telegram.User) (telegram.Chat, error) {
+	chatType := opts.ChatType
+	if chatType == "" {
+		chatType = "private"
+		if opts.ChatID < 0 {
+			chatType = "supergroup"
+		}
+	}
+
+	c := telegram.Chat{ID: opts.ChatID, Type: chatType, Title: opts.ChatTitle}
+	switch chatType {
+	case "private":
+		if c.ID == 0 {
+			c.ID = from.ID
+		}
+		if c.ID < 0 {
+			return telegram.Chat{}, fmt.Errorf("private chat id must be positive, got %d", c.ID)
+		}
+		c.Title = ""
+		c.FirstName = from.FirstName
+		c.Username = from.Username
+	case "group", "supergroup", "channel":
+		if c.ID == 0 {
+			c.ID = map[string]int64{"group": -4000000001, "supergroup": -1004000000001, "channel": -1004000000002}[chatType]
+		}
+		if c.ID > 0 {
+			return telegram.Chat{}, fmt.Errorf("%s chat id must be negative, got %d", chatType, c.ID)
+		}
+		if c.Title == "" {
+			c.Title = "Test " + strings.ToUpper(chatType[:1]) + chatType[1:]
+		}
+	default:
+		return telegram.Chat{}, fmt.Errorf("unsupported chat type %q (want private, group, supergroup or channel)", chatType)
+	}
+	return c, nil
+}
+
+// message builds a message and marks a leading /command with a bot_command
+// entity, as Telegram does.
+func message(opts Options, chat telegram.Chat, from *telegram.User, date int64) *telegram.Message {
+	m := &telegram.Message{
+		MessageID: opts.MessageID,
+		From:      from,
+		Date:      date,
+		Chat:      chat,
+		Text:      opts.Text,
+	}
+	if strings.HasPrefix(m.Text, "/") {
+		end := strings.IndexAny(m.Text, " \n")
+		if end < 0 {
+			end = len(m.Text)
+		}
+		m.Entities = []telegram.MessageEntity{{Type: "bot_command", Offset: 0, Length: utf16Len(m.Text[:end])}}
+	}
+	return m
+}
+
+// utf16Len counts UTF-16 code units, the unit of Telegram entity offsets.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,58 @@
+package synthetic
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+var fixedNow = time.Unix(1700000000, 0)
+
+func TestBuildMessageWithCommand(t *testing.T) {
+	u, err := Build(Options{Text: "/start ref123", FromID: 42, UpdateID: 9, Now: fixedNow})
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if u.Message == nil || u.Message.Chat.ID != 42 || u.Message.Chat.Type != "private" {
+		t.Fatalf("expected private message from 42, got %+v", u.Message)
+	}
+	if len(u.Message.Entities) != 1 || u.Message.Entities[0].Type != "bot_command" || u.Message.Entities[0].Length != 6 {
+		t.Fatalf("expected /start bot_command entity, got %+v", u.Message.Entities)
+	}
+	raw, _ := json.Marshal(u)
+	if !strings.HasPrefix(string(raw), `{"update_id":9,"message":{`) {
+		t.Fatalf("unexpected json: %s", raw)
+	}
+}
+
+func TestBuildEveryType(t *testing.T) {
+	for _, typ := range Types {
+		opts := Options{Type: typ, Now: fixedNow, Data: "btn", Query: "cats"}
+		if typ == "chat_member" || typ == "my_chat_member" || typ == "chat_join_request" {
+			opts.ChatType = "supergroup"
+		}
+		u, err := Build(opts)
+		if err != nil {
+			t.Fatalf("%s: Build returned error: %v", typ, err)
+		}
+		if got := u.Type(); got != typ {
+			t.Fatalf("expected %s update, got %s", typ, got)
+		}
+	}
+}
+
+func TestBuildRejectsInvalidCombinations(t *testing.T) {
+	cases := []Options{
+		{Type: "poll"},
+		{ChatType: "private", ChatID: -5},
+		{ChatType: "group", ChatID: 5},
+		{Type: "channel_post", ChatType: "group"},
+		{ChatType: "forum"},
+	}
+	for _, opts := range cases {
+		if _, err := Build(opts); err == nil {
+			t.Fatalf("expected error for %+v", opts)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ChatFullInfo is the result of getChat.
+type ChatFullInfo struct {
+	Chat
+	Description           string           `json:"description,omitempty"`
+	Bio                   string           `json:"bio,omitempty"`
+	InviteLink            string           `json:"invite_link,omitempty"`
+	LinkedChatID          int64            `json:"linked_chat_id,omitempty"`
+	SlowModeDelay         int              `json:"slow_mode_delay,omitempty"`
+	MessageAutoDeleteTime int              `json:"message_auto_delete_time,omitempty"`
+	HasProtectedContent   bool             `json:"has_protected_content,omitempty"`
+	JoinByRequest         bool             `json:"join_by_request,omitempty"`
+	Permissions           *ChatPermissions `json:"permissions,omitempty"`
+	PinnedMessage         *Message         `json:"pinned_message,omitempty"`
+}
+
+// ChatPermissions are the actions non-administrator members may take.
+type ChatPermissions struct {
+	CanSendMessages       bool `json:"can_send_messages"`
+	CanSendAudios         bool `json:"can_send_audios"`
+	CanSendDocuments      bool `json:"can_send_documents"`
+	CanSendPhotos         bool `json:"can_send_photos"`
+	CanSendVideos         bool `json:"can_send_videos"`
+	CanSendVideoNotes     bool `json:"can_send_video_notes"`
+	CanSendVoiceNotes     bool `json:"can_send_voice_notes"`
+	CanSendPolls          bool `json:"can_send_polls"`
+	CanSendOtherMessages  bool `json:"can_send_other_messages"`
+	CanAddWebPagePreviews bool `json:"can_add_web_page_previews"`
+	CanChangeInfo         bool `json:"can_change_info"`
+	CanInviteUsers        bool `json:"can_invite_users"`
+	CanPinMessages        bool `json:"can_pin_messages"`
+	CanManageTopics       bool `json:"can_manage_topics"`
+}
+
+// GetChat returns the raw getChat result; decode it into ChatFullInfo.
+func (c *Client) GetChat(ctx context.Context, chatID string) (json.RawMessage, error) {
+	return c.call(ctx, "getChat", map[string]any{"chat_id": chatID})
+}
+
+func (c *Client) GetChatMemberCount(ctx context.Context, chatID string) (int, error) {
+	res, err := c.call(ctx, "getChatMemberCount", map[string]any{"chat_id": chatID})
+	if err != nil {
+		return 0, err
+	}
+	var n int
+	if err := json.Unmarshal(res, &n); err != nil {
+		return 0, fmt.Errorf("decode getChatMemberCount result: %w", err)
+	}
+	return n, nil
+}
+
+// GetChatAdministrators returns the raw array of ChatMember objects.
+func (c *Client) GetChatAdministrators(ctx context.Context, chatID string) (json.RawMessage, error) {
+	return c.call(ctx, "getChatAdministrators", map[string]any{"chat_id": chatID})
+}
+
+// GetChatMember returns the raw ChatMember of userID in the chat.
+func (c *Client) GetChatMember(ctx context.Context, chatID string, userID int64) (json.RawMessage, error) {
+	return c.call(ctx, "getChatMember", map[string]any{"chat_id": chatID, "user_id": userID})
+}
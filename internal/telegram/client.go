@@ -11,18 +11,16 @@ import (
 	"path"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type Client struct {
-	baseURL string
-	token   string
-	http    *http.Client
-}
-
-type Update struct {
-	UpdateID int64           `json:"update_id"`
-	Raw      json.RawMessage `json:"-"`
+	baseURL  string
+	token    string
+	http     *http.Client
+	timeouts Timeouts
+	localAPI bool
+	testEnv  bool
+	redactor *Redactor
 }
 
 type apiResponse struct {
@@ -43,18 +41,52 @@ type WebhookInfo struct {
 
 func NewClient(apiBase, token string) *Client {
 	return &Client{
-		baseURL: strings.TrimRight(apiBase, "/"),
-		token:   token,
-		http:    &http.Client{Timeout: 60 * time.Second},
+		baseURL:  strings.TrimRight(apiBase, "/"),
+		token:    token,
+		http:     &http.Client{},
+		timeouts: DefaultTimeouts(),
+		redactor: NewRedactor(token),
 	}
 }
 
+// Redactor returns the redactor that scrubs this client's token. Callers
+// printing anything derived from client errors should pass it through.
+func (c *Client) Redactor() *Redactor {
+	return c.redactor
+}
+
+// BotID returns the bot's user id, the numeric prefix of its token, or 0
+// when the token has no such prefix.
+func (c *Client) BotID() int64 {
+	return BotIDFromToken(c.token)
+}
+
+// BotIDFromToken returns the bot id a token starts with, or 0 if it has
+// none.
+func BotIDFromToken(token string) int64 {
+	prefix, _, _ := strings.Cut(token, ":")
+	id, err := strconv.ParseInt(prefix, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
 func (c *Client) GetMe(ctx context.Context) (json.RawMessage, error) {
 	return c.call(ctx, "getMe", nil)
 }
 
 func (c *Client) SendMessage(ctx context.Context, chatID, text string) (json.RawMessage, error) {
+	return c.SendMessageToThread(ctx, chatID, 0, text)
+}
+
+// SendMessageToThread sends text to a forum topic or reply thread; threadID
+// 0 sends to the chat itself.
+func (c *Client) SendMessageToThread(ctx context.Context, chatID string, threadID int64, text string) (json.RawMessage, error) {
 	params := map[string]any{"chat_id": chatID, "text": text}
+	if threadID != 0 {
+		params["message_thread_id"] = threadID
+	}
 	return c.call(ctx, "sendMessage", params)
 }
 
@@ -85,6 +117,22 @@ func (c *Client) GetUpdates(ctx context.Context, offset int64, timeoutSec int) (
 }
 
 func (c *Client) GetUpdatesWithLimit(ctx context.Context, offset int64, timeoutSec int, limit int) ([]Update, error) {
+	updates, err := c.getUpdates(ctx, offset, timeoutSec, limit, nil)
+	return updates, c.redactor.Error(err)
+}
+
+// GetUpdatesAllowed is GetUpdates restricted to the update types in
+// allowed. Telegram keeps the setting for later calls that omit it; an
+// empty, non-nil allowed restores the default types.
+func (c *Client) GetUpdatesAllowed(ctx context.Context, offset int64, timeoutSec int, allowed []string) ([]Update, error) {
+	if allowed == nil {
+		allowed = []string{}
+	}
+	updates, err := c.getUpdates(ctx, offset, timeoutSec, 0, allowed)
+	return updates, c.redactor.Error(err)
+}
+
+func (c *Client) getUpdates(ctx context.Context, offset int64, timeoutSec int, limit int, allowed []string) ([]Update, error) {
 	endpoint, err := c.buildURL("getUpdates")
 	if err != nil {
 		return nil, err
@@ -102,6 +150,13 @@ func (c *Client) GetUpdatesWithLimit(ctx context.Context, offset int64, timeoutS
 	if limit > 0 {
 		q.Set("limit", strconv.Itoa(limit))
 	}
+	if allowed != nil {
+		encoded, err := json.Marshal(allowed)
+		if err != nil {
+			return nil, err
+		}
+		q.Set("allowed_updates", string(encoded))
+	}
 	u.RawQuery = q.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
@@ -109,47 +164,41 @@ func (c *Client) GetUpdatesWithLimit(ctx context.Context, offset int64, timeoutS
 		return nil, err
 	}
 
-	resp, err := c.http.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+	res, err := c.doWithTimeout(req, "getUpdates", c.RequestTimeout("getUpdates", timeoutSec))
 	if err != nil {
 		return nil, err
 	}
-
-	var out struct {
-		OK          bool              `json:"ok"`
-		Description string            `json:"description"`
-		Result      []json.RawMessage `json:"result"`
-	}
-	if err := json.Unmarshal(body, &out); err != nil {
+	var result []json.RawMessage
+	if err := json.Unmarshal(res, &result); err != nil {
 		return nil, fmt.Errorf("decode getUpdates: %w", err)
 	}
-	if !out.OK {
-		if out.Description == "" {
-			out.Description = "unknown telegram api error"
-		}
-		return nil, fmt.Errorf("getUpdates: %s", out.Description)
-	}
 
-	updates := make([]Update, 0, len(out.Result))
-	for _, raw := range out.Result {
-		var idOnly struct {
-			UpdateID int64 `json:"update_id"`
-		}
-		if err := json.Unmarshal(raw, &idOnly); err != nil {
+	updates := make([]Update, 0, len(result))
+	for _, raw := range result {
+		update, err := ParseUpdate(raw)
+		if err != nil {
 			return nil, fmt.Errorf("decode update id: %w", err)
 		}
-		updates = append(updates, Update{UpdateID: idOnly.UpdateID, Raw: raw})
+		updates = append(updates, update)
 	}
 
 	return updates, nil
 }
 
+// Call invokes an arbitrary Bot API method with JSON-encoded params and
+// returns its raw result.
+func (c *Client) Call(ctx context.Context, method string, params map[string]any) (json.RawMessage, error) {
+	return c.call(ctx, method, params)
+}
+
+// call invokes a Bot API method. Every error it returns has the token
+// redacted, since net/http errors embed the full request URL.
 func (c *Client) call(ctx context.Context, method string, params map[string]any) (json.RawMessage, error) {
+	res, err := c.doCall(ctx, method, params)
+	return res, c.redactor.Error(err)
+}
+
+func (c *Client) doCall(ctx context.Context, method string, params map[string]any) (json.RawMessage, error) {
 	endpoint, err := c.buildURL(method)
 	if err != nil {
 		return nil, err
@@ -169,7 +218,11 @@ func (c *Client) call(ctx context.Context, method string, params map[string]any)
 		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	return c.doWithTimeout(req, method, c.RequestTimeout(method, 0))
+}
 
+// do sends a prepared request and decodes the Bot API response envelope.
+func (c *Client) do(req *http.Request, method string) (json.RawMessage, error) {
 	resp, err := c.http.Do(req)
 	if err != nil {
 		return nil, err
@@ -193,11 +246,20 @@ func (c *Client) call(ctx context.Context, method string, params map[string]any)
 	return out.Result, nil
 }
 
+// buildURL returns the endpoint of method: /bot<token>/<method>, or
+// /bot<token>/test/<method> in the test environment.
 func (c *Client) buildURL(method string) (string, error) {
 	u, err := url.Parse(c.baseURL)
 	if err != nil {
 		return "", fmt.Errorf("parse api base: %w", err)
 	}
-	u.Path = path.Join(u.Path, "bot"+c.token, method)
+	u.Path = path.Join(u.Path, "bot"+c.token, c.envSegment(), method)
 	return u.String(), nil
 }
+
+func (c *Client) envSegment() string {
+	if c.testEnv {
+		return "test"
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package telegram
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBuildURL(t *testing.T) {
+	prod := NewClient("https://api.telegram.org/", "1:x")
+	got, err := prod.buildURL("getMe")
+	if err != nil {
+		t.Fatalf("buildURL returned error: %v", err)
+	}
+	if got != "https://api.telegram.org/bot1:x/getMe" {
+		t.Fatalf("unexpected production url %q", got)
+	}
+
+	test, err := NewClientWithOptions(ClientOptions{APIBase: "https://api.telegram.org", Token: "1:x", TestEnv: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if got, _ := test.buildURL("getMe"); got != "https://api.telegram.org/bot1:x/test/getMe" {
+		t.Fatalf("unexpected test env url %q", got)
+	}
+	if got, _ := test.buildFileURL("photos/a.jpg"); got != "https://api.telegram.org/file/bot1:x/test/photos/a.jpg" {
+		t.Fatalf("unexpected test env file url %q", got)
+	}
+}
+
+func TestTestEnvGetUpdatesPath(t *testing.T) {
+	var gotPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+	}))
+	defer srv.Close()
+
+	c, err := NewClientWithOptions(ClientOptions{APIBase: srv.URL, Token: "1:x", TestEnv: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if _, err := c.GetUpdates(context.Background(), 0, 0); err != nil {
+		t.Fatalf("GetUpdates returned error: %v", err)
+	}
+	if gotPath != "/bot1:x/test/getUpdates" {
+		t.Fatalf("unexpected path %q", gotPath)
+	}
+}
+
+func TestBotID(t *testing.T) {
+	if id := NewClient("", "123456:ABC").BotID(); id != 123456 {
+		t.Fatalf("expected bot id 123456, got %d", id)
+	}
+	if id := NewClient("", "not-a-token").BotID(); id != 0 {
+		t.Fatalf("expected 0 for a token without id, got %d", id)
+	}
+}
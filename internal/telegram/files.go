@@ -0,0 +1,139 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MaxUploadSize is the cloud Bot API limit for multipart uploads.
+	MaxUploadSize int64 = 50 << 20
+	// MaxLocalUploadSize is the limit of a local telegram-bot-api server.
+	MaxLocalUploadSize int64 = 2000 << 20
+)
+
+// File is the result of getFile. On a local Bot API server FilePath is an
+// absolute path on the server's disk.
+type File struct {
+	FileID       string `json:"file_id"`
+	FileUniqueID string `json:"file_unique_id"`
+	FileSize     int64  `json:"file_size"`
+	FilePath     string `json:"file_path"`
+}
+
+func (c *Client) GetFile(ctx context.Context, fileID string) (*File, error) {
+	res, err := c.call(ctx, "getFile", map[string]any{"file_id": fileID})
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(res, &f); err != nil {
+		return nil, fmt.Errorf("decode getFile result: %w", err)
+	}
+	return &f, nil
+}
+
+// DownloadFile copies the file at filePath (as returned by GetFile) to w.
+// In local API mode absolute paths are read straight from disk, since the
+// local server stores files there instead of serving them over HTTP. The
+// transfer is bounded by DownloadTimeout unless ctx already has a deadline.
+func (c *Client) DownloadFile(ctx context.Context, filePath string, w io.Writer) (int64, error) {
+	n, err := c.downloadFile(ctx, filePath, w)
+	return n, c.redactor.Error(err)
+}
+
+func (c *Client) downloadFile(ctx context.Context, filePath string, w io.Writer) (int64, error) {
+	if c.localAPI && filepath.IsAbs(filePath) {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return 0, fmt.Errorf("open local file: %w", err)
+		}
+		defer f.Close()
+		return io.Copy(w, f)
+	}
+
+	endpoint, err := c.buildFileURL(filePath)
+	if err != nil {
+		return 0, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.DownloadTimeout())
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("download %s: %s", filePath, resp.Status)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+// LogOut logs the bot out of the cloud Bot API server, which is required
+// before moving it to a local server.
+func (c *Client) LogOut(ctx context.Context) error {
+	_, err := c.call(ctx, "logOut", map[string]any{})
+	return err
+}
+
+// Close calls the Bot API close method, shutting the bot instance down on
+// the current server before moving it to another one. It does not release
+// any client resources.
+func (c *Client) Close(ctx context.Context) error {
+	_, err := c.call(ctx, "close", map[string]any{})
+	return err
+}
+
+// LocalAPI reports whether the client talks to a local Bot API server.
+func (c *Client) LocalAPI() bool {
+	return c.localAPI
+}
+
+// MaxUploadSize returns the upload limit of the server the client talks to.
+func (c *Client) MaxUploadSize() int64 {
+	if c.localAPI {
+		return MaxLocalUploadSize
+	}
+	return MaxUploadSize
+}
+
+// localFileParams turns uploads into file:// URIs, which a local Bot API
+// server reads from its own disk instead of receiving multipart bodies.
+func localFileParams(params map[string]any, files []UploadFile) (map[string]any, error) {
+	out := make(map[string]any, len(params)+len(files))
+	for k, v := range params {
+		out[k] = v
+	}
+	for _, f := range files {
+		abs, err := filepath.Abs(f.Path)
+		if err != nil {
+			return nil, fmt.Errorf("upload %s: %w", f.Field, err)
+		}
+		out[f.Field] = (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+	}
+	return out, nil
+}
+
+func (c *Client) buildFileURL(filePath string) (string, error) {
+	u, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("parse api base: %w", err)
+	}
+	u.Path = path.Join(u.Path, "file", "bot"+c.token, c.envSegment(), strings.TrimPrefix(filePath, "/"))
+	return u.String(), nil
+}
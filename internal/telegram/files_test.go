@@ -0,0 +1,109 @@
+package telegram
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDownloadFileOverHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/file/bot"+testToken+"/photos/file_1.jpg" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("jpeg-bytes"))
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL, testToken)
+	var buf bytes.Buffer
+	n, err := c.DownloadFile(context.Background(), "photos/file_1.jpg", &buf)
+	if err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+	if n != 10 || buf.String() != "jpeg-bytes" {
+		t.Fatalf("unexpected download: %d %q", n, buf.String())
+	}
+
+	_, err = c.DownloadFile(context.Background(), "missing.jpg", io.Discard)
+	if err == nil || strings.Contains(err.Error(), testToken) {
+		t.Fatalf("expected redacted error for missing file, got %v", err)
+	}
+}
+
+func TestDownloadFileLocalAPIReadsDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "doc.txt")
+	if err := os.WriteFile(path, []byte("on disk"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	c, err := NewClientWithOptions(ClientOptions{APIBase: "http://127.0.0.1:1", Token: testToken, LocalAPI: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := c.DownloadFile(context.Background(), path, &buf); err != nil {
+		t.Fatalf("DownloadFile returned error: %v", err)
+	}
+	if buf.String() != "on disk" {
+		t.Fatalf("unexpected content %q", buf.String())
+	}
+}
+
+func TestCallWithFilesLocalAPISendsFileURI(t *testing.T) {
+	var got map[string]any
+	var contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"ok":true,"result":{}}`))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "big.bin")
+	if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	c, err := NewClientWithOptions(ClientOptions{APIBase: srv.URL, Token: testToken, LocalAPI: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if _, err := c.CallWithFiles(context.Background(), "sendDocument", map[string]any{"chat_id": "1"}, []UploadFile{{Field: "document", Path: path}}); err != nil {
+		t.Fatalf("CallWithFiles returned error: %v", err)
+	}
+	if contentType != "application/json" || got["document"] != "file://"+filepath.ToSlash(path) {
+		t.Fatalf("expected file:// uri in json body, got %s %v", contentType, got)
+	}
+}
+
+func TestUploadSizeLimits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "huge.bin")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create file: %v", err)
+	}
+	if err := f.Truncate(MaxUploadSize + 1); err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+	_ = f.Close()
+	files := []UploadFile{{Field: "document", Path: path}}
+
+	cloud := NewClient("http://127.0.0.1:1", testToken)
+	if err := cloud.checkUploads(files); err == nil || !strings.Contains(err.Error(), "over the 50 MB limit") {
+		t.Fatalf("expected cloud size limit error, got %v", err)
+	}
+	local, err := NewClientWithOptions(ClientOptions{APIBase: "http://127.0.0.1:1", Token: testToken, LocalAPI: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if err := local.checkUploads(files); err != nil {
+		t.Fatalf("local api should accept the file, got %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package telegram
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HARRecorder is a round-tripper that records every exchange into a HAR 1.2
+// file. Entries are appended in place of the closing brackets, which are
+// written again after each one, so the file stays valid even if the process
+// exits abruptly and neither memory nor writes grow with the entry count.
+type HARRecorder struct {
+	next     http.RoundTripper
+	redactor *Redactor
+	mu       sync.Mutex
+	file     *os.File
+	// end is the offset of the closing brackets, where the next entry goes.
+	end     int64
+	entries int
+}
+
+type harFile struct {
+	Log harLog `json:"log"`
+}
+
+type harLog struct {
+	Version string     `json:"version"`
+	Creator harCreator `json:"creator"`
+	Entries []harEntry `json:"entries"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+const harTrailer = "\n    ]\n  }\n}\n"
+
+// NewHARRecorder creates (or truncates) the HAR file at path and returns a
+// recorder wrapping next.
+func NewHARRecorder(next http.RoundTripper, path string, redactor *Redactor) (*HARRecorder, error) {
+	creator, err := json.MarshalIndent(harCreator{Name: "tgbot-cli", Version: buildVersion()}, "    ", "  ")
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("{\n  \"log\": {\n    \"version\": \"1.2\",\n    \"creator\": %s,\n    \"entries\": [", creator)
+	if _, err := f.WriteString(header + harTrailer); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &HARRecorder{next: next, redactor: redactor, file: f, end: int64(len(header))}, nil
+}
+
+// buildVersion is the module version the binary was built from, or "devel".
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+func (r *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, ex, err := roundTripCaptured(r.next, req)
+	if ex == nil {
+		return resp, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if werr := r.append(r.entry(ex)); werr != nil && err == nil {
+		return resp, fmt.Errorf("write trace file: %w", werr)
+	}
+	return resp, err
+}
+
+// append writes e over the closing brackets and writes them back after it.
+func (r *HARRecorder) append(e harEntry) error {
+	data, err := json.MarshalIndent(e, "      ", "  ")
+	if err != nil {
+		return err
+	}
+	sep := ",\n      "
+	if r.entries == 0 {
+		sep = "\n      "
+	}
+	chunk := append([]byte(sep), data...)
+	if _, err := r.file.WriteAt(append(chunk, harTrailer...), r.end); err != nil {
+		return err
+	}
+	r.end += int64(len(chunk))
+	r.entries++
+	return nil
+}
+
+// Close closes the trace file. The file is complete after every exchange,
+// so closing is only needed to release it early.
+func (r *HARRecorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+func (r *HARRecorder) entry(ex *exchange) harEntry {
+	ms := float64(ex.elapsed.Microseconds()) / 1000
+	e := harEntry{
+		StartedDateTime: ex.started.Format(time.RFC3339Nano),
+		Time:            ms,
+		Request: harRequest{
+			Method:      ex.req.Method,
+			URL:         r.redactor.String(ex.req.URL.String()),
+			HTTPVersion: "HTTP/1.1",
+			Headers:     harHeaders(ex.req.Header),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    ex.requestBodySize(),
+		},
+		Response: harResponse{
+			HTTPVersion: "HTTP/1.1",
+			Headers:     []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Timings: harTimings{Wait: ms},
+	}
+	for key, values := range ex.req.URL.Query() {
+		for _, v := range values {
+			e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: key, Value: r.redactor.String(v)})
+		}
+	}
+	sort.Slice(e.Request.QueryString, func(i, j int) bool {
+		return e.Request.QueryString[i].Name < e.Request.QueryString[j].Name
+	})
+	if ex.hasRequestBody() {
+		e.Request.PostData = &harPostData{
+			MimeType: ex.req.Header.Get("Content-Type"),
+			Text:     r.redactor.String(ex.requestParams()),
+		}
+	}
+	if ex.err != nil {
+		e.Error = r.redactor.String(ex.err.Error())
+		return e
+	}
+	e.Response.Status = ex.resp.StatusCode
+	e.Response.StatusText = strings.TrimSpace(strings.TrimPrefix(ex.resp.Status, fmt.Sprint(ex.resp.StatusCode)))
+	e.Response.HTTPVersion = ex.resp.Proto
+	e.Response.Headers = harHeaders(ex.resp.Header)
+	if ex.download {
+		size := int(ex.resp.ContentLength)
+		e.Response.BodySize = size
+		e.Response.Content = harContent{
+			Size:     max(size, 0),
+			MimeType: ex.resp.Header.Get("Content-Type"),
+			Text:     ex.responseBody(),
+		}
+		return e
+	}
+	e.Response.BodySize = len(ex.respBody)
+	e.Response.Content = harContent{
+		Size:     len(ex.respBody),
+		MimeType: ex.resp.Header.Get("Content-Type"),
+		Text:     r.redactor.String(string(ex.respBody)),
+	}
+	return e
+}
+
+func harHeaders(h http.Header) []harNameValue {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]harNameValue, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range h[k] {
+			out = append(out, harNameValue{Name: k, Value: v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ChatInviteLink is an invite link of a chat.
+type ChatInviteLink struct {
+	InviteLink              string `json:"invite_link"`
+	Creator                 User   `json:"creator"`
+	CreatesJoinRequest      bool   `json:"creates_join_request"`
+	IsPrimary               bool   `json:"is_primary"`
+	IsRevoked               bool   `json:"is_revoked"`
+	Name                    string `json:"name,omitempty"`
+	ExpireDate              int64  `json:"expire_date,omitempty"`
+	MemberLimit             int    `json:"member_limit,omitempty"`
+	PendingJoinRequestCount int    `json:"pending_join_request_count,omitempty"`
+}
+
+// InviteLinkOptions are the settings of a created or edited invite link.
+// Zero values mean no name, no expiry and no member limit.
+type InviteLinkOptions struct {
+	Name               string
+	ExpireDate         time.Time
+	MemberLimit        int
+	CreatesJoinRequest bool
+}
+
+func (o InviteLinkOptions) params(chatID string) map[string]any {
+	params := map[string]any{"chat_id": chatID}
+	if o.Name != "" {
+		params["name"] = o.Name
+	}
+	if !o.ExpireDate.IsZero() {
+		params["expire_date"] = o.ExpireDate.Unix()
+	}
+	if o.MemberLimit > 0 {
+		params["member_limit"] = o.MemberLimit
+	}
+	if o.CreatesJoinRequest {
+		params["creates_join_request"] = true
+	}
+	return params
+}
+
+// CreateChatInviteLink creates an additional invite link.
+func (c *Client) CreateChatInviteLink(ctx context.Context, chatID string, opts InviteLinkOptions) (*ChatInviteLink, error) {
+	return c.inviteLinkCall(ctx, "createChatInviteLink", opts.params(chatID))
+}
+
+// EditChatInviteLink replaces the settings of a link created by the bot
+// with opts.
+func (c *Client) EditChatInviteLink(ctx context.Context, chatID, link string, opts InviteLinkOptions) (*ChatInviteLink, error) {
+	params := opts.params(chatID)
+	params["invite_link"] = link
+	return c.inviteLinkCall(ctx, "editChatInviteLink", params)
+}
+
+// RevokeChatInviteLink revokes a link created by the bot. Revoking the
+// primary link creates a new one.
+func (c *Client) RevokeChatInviteLink(ctx context.Context, chatID, link string) (*ChatInviteLink, error) {
+	return c.inviteLinkCall(ctx, "revokeChatInviteLink", map[string]any{"chat_id": chatID, "invite_link": link})
+}
+
+// ExportChatInviteLink replaces the chat's primary invite link, revoking
+// the previous one, and returns the new link.
+func (c *Client) ExportChatInviteLink(ctx context.Context, chatID string) (string, error) {
+	res, err := c.call(ctx, "exportChatInviteLink", map[string]any{"chat_id": chatID})
+	if err != nil {
+		return "", err
+	}
+	var link string
+	if err := json.Unmarshal(res, &link); err != nil {
+		return "", fmt.Errorf("decode exportChatInviteLink result: %w", err)
+	}
+	return link, nil
+}
+
+func (c *Client) inviteLinkCall(ctx context.Context, method string, params map[string]any) (*ChatInviteLink, error) {
+	res, err := c.call(ctx, method, params)
+	if err != nil {
+		return nil, err
+	}
+	var link ChatInviteLink
+	if err := json.Unmarshal(res, &link); err != nil {
+		return nil, fmt.Errorf("decode %s result: %w", method, err)
+	}
+	return &link, nil
+}
+
+func (c *Client) ApproveChatJoinRequest(ctx context.Context, chatID string, userID int64) error {
+	_, err := c.call(ctx, "approveChatJoinRequest", map[string]any{"chat_id": chatID, "user_id": userID})
+	return err
+}
+
+func (c *Client) DeclineChatJoinRequest(ctx context.Context, chatID string, userID int64) error {
+	_, err := c.call(ctx, "declineChatJoinRequest", map[string]any{"chat_id": chatID, "user_id": userID})
+	return err
+}
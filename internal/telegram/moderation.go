@@ -0,0 +1,99 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// ChatAdministratorRights are the rights promoteChatMember grants. Every
+// field is sent, so rights left false are taken away.
+type ChatAdministratorRights struct {
+	IsAnonymous         bool `json:"is_anonymous"`
+	CanManageChat       bool `json:"can_manage_chat"`
+	CanDeleteMessages   bool `json:"can_delete_messages"`
+	CanManageVideoChats bool `json:"can_manage_video_chats"`
+	CanRestrictMembers  bool `json:"can_restrict_members"`
+	CanPromoteMembers   bool `json:"can_promote_members"`
+	CanChangeInfo       bool `json:"can_change_info"`
+	CanInviteUsers      bool `json:"can_invite_users"`
+	CanPostStories      bool `json:"can_post_stories"`
+	CanEditStories      bool `json:"can_edit_stories"`
+	CanDeleteStories    bool `json:"can_delete_stories"`
+	CanPostMessages     bool `json:"can_post_messages"`
+	CanEditMessages     bool `json:"can_edit_messages"`
+	CanPinMessages      bool `json:"can_pin_messages"`
+	CanManageTopics     bool `json:"can_manage_topics"`
+}
+
+// BanChatMember bans userID until the given time; a zero until bans
+// forever. revokeMessages also deletes the user's messages in the chat.
+func (c *Client) BanChatMember(ctx context.Context, chatID string, userID int64, until time.Time, revokeMessages bool) error {
+	params := map[string]any{"chat_id": chatID, "user_id": userID}
+	if !until.IsZero() {
+		params["until_date"] = until.Unix()
+	}
+	if revokeMessages {
+		params["revoke_messages"] = true
+	}
+	_, err := c.call(ctx, "banChatMember", params)
+	return err
+}
+
+// UnbanChatMember lifts a ban. Without onlyIfBanned Telegram also removes
+// a user who is currently a member.
+func (c *Client) UnbanChatMember(ctx context.Context, chatID string, userID int64, onlyIfBanned bool) error {
+	_, err := c.call(ctx, "unbanChatMember", map[string]any{"chat_id": chatID, "user_id": userID, "only_if_banned": onlyIfBanned})
+	return err
+}
+
+// RestrictChatMember replaces the permissions of userID until the given
+// time; a zero until restricts forever. Permissions are applied as given,
+// without Telegram deriving one from another.
+func (c *Client) RestrictChatMember(ctx context.Context, chatID string, userID int64, perms ChatPermissions, until time.Time) error {
+	params := map[string]any{
+		"chat_id":                          chatID,
+		"user_id":                          userID,
+		"permissions":                      perms,
+		"use_independent_chat_permissions": true,
+	}
+	if !until.IsZero() {
+		params["until_date"] = until.Unix()
+	}
+	_, err := c.call(ctx, "restrictChatMember", params)
+	return err
+}
+
+// PromoteChatMember sets the administrator rights of userID; all rights
+// false demotes them to a regular member.
+func (c *Client) PromoteChatMember(ctx context.Context, chatID string, userID int64, rights ChatAdministratorRights) error {
+	params, err := flatParams(rights)
+	if err != nil {
+		return err
+	}
+	params["chat_id"] = chatID
+	params["user_id"] = userID
+	_, err = c.call(ctx, "promoteChatMember", params)
+	return err
+}
+
+// SetChatAdministratorCustomTitle sets the title shown next to an
+// administrator promoted by the bot.
+func (c *Client) SetChatAdministratorCustomTitle(ctx context.Context, chatID string, userID int64, title string) error {
+	_, err := c.call(ctx, "setChatAdministratorCustomTitle", map[string]any{"chat_id": chatID, "user_id": userID, "custom_title": title})
+	return err
+}
+
+// flatParams turns the fields of v into top-level method parameters.
+func flatParams(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("encode params: %w", err)
+	}
+	params := map[string]any{}
+	if err := json.Unmarshal(data, &params); err != nil {
+		return nil, fmt.Errorf("encode params: %w", err)
+	}
+	return params, nil
+}
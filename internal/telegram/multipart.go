@@ -0,0 +1,133 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// UploadFile is a local file sent as a multipart form field.
+type UploadFile struct {
+	Field string
+	Path  string
+}
+
+// CallWithFiles invokes a Bot API method as multipart/form-data, streaming
+// the given files from disk. Non-string params are JSON-encoded, as the Bot
+// API expects for fields like reply_markup. In local API mode the files are
+// passed as file:// URIs in a plain JSON call instead.
+func (c *Client) CallWithFiles(ctx context.Context, method string, params map[string]any, files []UploadFile) (json.RawMessage, error) {
+	if err := c.checkUploads(files); err != nil {
+		return nil, err
+	}
+	if c.localAPI {
+		local, err := localFileParams(params, files)
+		if err != nil {
+			return nil, err
+		}
+		return c.call(ctx, method, local)
+	}
+	res, err := c.doMultipart(ctx, method, params, files)
+	return res, c.redactor.Error(err)
+}
+
+func (c *Client) doMultipart(ctx context.Context, method string, params map[string]any, files []UploadFile) (json.RawMessage, error) {
+	endpoint, err := c.buildURL(method)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]string, len(params))
+	for key, value := range params {
+		encoded, err := formValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("encode param %q for %s: %w", key, method, err)
+		}
+		fields[key] = encoded
+	}
+
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeMultipart(mw, fields, files))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, pr)
+	if err != nil {
+		_ = pr.Close()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	res, err := c.doWithTimeout(req, method, c.UploadTimeout())
+	_ = pr.Close()
+	return res, err
+}
+
+// checkUploads fails early on missing files and files over the server's
+// upload limit, rather than after streaming them.
+func (c *Client) checkUploads(files []UploadFile) error {
+	limit := c.MaxUploadSize()
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if err != nil {
+			return fmt.Errorf("upload %s: %w", f.Field, err)
+		}
+		if info.Size() > limit {
+			hint := " (use a local Bot API server with --local-api for larger files)"
+			if c.localAPI {
+				hint = ""
+			}
+			return fmt.Errorf("upload %s: %s is %d bytes, over the %d MB limit%s", f.Field, f.Path, info.Size(), limit>>20, hint)
+		}
+	}
+	return nil
+}
+
+func writeMultipart(mw *multipart.Writer, fields map[string]string, files []UploadFile) error {
+	for key, value := range fields {
+		if err := mw.WriteField(key, value); err != nil {
+			return err
+		}
+	}
+	for _, f := range files {
+		if err := writeFilePart(mw, f); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func writeFilePart(mw *multipart.Writer, f UploadFile) error {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("upload %s: %w", f.Field, err)
+	}
+	defer file.Close()
+
+	part, err := mw.CreateFormFile(f.Field, filepath.Base(f.Path))
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, file)
+	return err
+}
+
+func formValue(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case json.RawMessage:
+		return string(val), nil
+	default:
+		data, err := json.Marshal(val)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
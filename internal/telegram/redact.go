@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const redactedPlaceholder = "[REDACTED]"
+
+// Redactor scrubs bot tokens out of strings and errors before they reach
+// stderr, logs or trace files.
+type Redactor struct {
+	mu      sync.RWMutex
+	secrets []string
+}
+
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, s := range secrets {
+		r.Add(s)
+	}
+	return r
+}
+
+// Add registers a secret together with the encoded forms it may take
+// inside URLs.
+func (r *Redactor) Add(secret string) {
+	if r == nil || secret == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, form := range []string{secret, url.PathEscape(secret), url.QueryEscape(secret)} {
+		if !containsString(r.secrets, form) {
+			r.secrets = append(r.secrets, form)
+		}
+	}
+}
+
+func (r *Redactor) String(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redactedPlaceholder)
+	}
+	return s
+}
+
+func (r *Redactor) Bytes(b []byte) []byte {
+	if r == nil {
+		return b
+	}
+	return []byte(r.String(string(b)))
+}
+
+// Error returns err with every registered secret removed from its message.
+// The original error stays reachable through errors.Is and errors.As.
+func (r *Redactor) Error(err error) error {
+	if err == nil || r == nil {
+		return err
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urlErr.URL = r.String(urlErr.URL)
+	}
+	msg := err.Error()
+	if clean := r.String(msg); clean != msg {
+		return &redactedError{msg: clean, err: err}
+	}
+	return err
+}
+
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string { return e.msg }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testToken = "123456:ABC-secret_token"
+
+func TestRedactorString(t *testing.T) {
+	r := NewRedactor(testToken)
+	in := "Post \"https://api.telegram.org/bot123456:ABC-secret_token/getMe\" and 123456%3AABC-secret_token"
+	out := r.String(in)
+	if strings.Contains(out, "ABC-secret_token") {
+		t.Fatalf("token leaked: %s", out)
+	}
+	if !strings.Contains(out, "/bot[REDACTED]/getMe") {
+		t.Fatalf("unexpected redacted output: %s", out)
+	}
+}
+
+func TestRedactorErrorKeepsChain(t *testing.T) {
+	r := NewRedactor(testToken)
+	base := errors.New("call " + testToken)
+	err := r.Error(&wrapErr{base, context.Canceled})
+	if strings.Contains(err.Error(), testToken) {
+		t.Fatalf("token leaked: %v", err)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled to remain in chain")
+	}
+}
+
+func TestRedactorNilSafe(t *testing.T) {
+	var r *Redactor
+	if got := r.String(testToken); got != testToken {
+		t.Fatalf("nil redactor should pass strings through, got %q", got)
+	}
+	if r.Error(nil) != nil {
+		t.Fatalf("expected nil error")
+	}
+}
+
+func TestClientErrorsAreRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	base := srv.URL
+	srv.Close()
+
+	c := NewClient(base, testToken)
+	ctx := context.Background()
+	checks := map[string]error{}
+	_, checks["getMe"] = c.GetMe(ctx)
+	_, checks["getUpdates"] = c.GetUpdates(ctx, 0, 0)
+	checks["deleteWebhook"] = c.DeleteWebhook(ctx)
+	for name, err := range checks {
+		if err == nil {
+			t.Fatalf("%s: expected error against closed server", name)
+		}
+		if strings.Contains(err.Error(), testToken) {
+			t.Fatalf("%s: token leaked: %v", name, err)
+		}
+	}
+}
+
+func TestClientAPIDescriptionIsRedacted(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"description":"bad path ` + r.URL.Path + `"}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL, testToken).SendMessage(context.Background(), "1", "hi")
+	if err == nil {
+		t.Fatalf("expected api error")
+	}
+	if strings.Contains(err.Error(), testToken) {
+		t.Fatalf("token leaked: %v", err)
+	}
+}
+
+type wrapErr struct {
+	msg  error
+	base error
+}
+
+func (w *wrapErr) Error() string { return w.msg.Error() }
+func (w *wrapErr) Unwrap() error { return w.base }
@@ -0,0 +1,67 @@
+package telegram
+
+import "context"
+
+func (c *Client) SetChatTitle(ctx context.Context, chatID, title string) error {
+	_, err := c.call(ctx, "setChatTitle", map[string]any{"chat_id": chatID, "title": title})
+	return err
+}
+
+// SetChatDescription sets the chat description; an empty one clears it.
+func (c *Client) SetChatDescription(ctx context.Context, chatID, description string) error {
+	_, err := c.call(ctx, "setChatDescription", map[string]any{"chat_id": chatID, "description": description})
+	return err
+}
+
+// SetChatPhoto uploads the image at path as the chat photo.
+func (c *Client) SetChatPhoto(ctx context.Context, chatID, path string) error {
+	_, err := c.CallWithFiles(ctx, "setChatPhoto", map[string]any{"chat_id": chatID}, []UploadFile{{Field: "photo", Path: path}})
+	return err
+}
+
+func (c *Client) DeleteChatPhoto(ctx context.Context, chatID string) error {
+	_, err := c.call(ctx, "deleteChatPhoto", map[string]any{"chat_id": chatID})
+	return err
+}
+
+// SetChatPermissions sets the default permissions of all members, applied
+// as given like RestrictChatMember.
+func (c *Client) SetChatPermissions(ctx context.Context, chatID string, perms ChatPermissions) error {
+	_, err := c.call(ctx, "setChatPermissions", map[string]any{
+		"chat_id":                          chatID,
+		"permissions":                      perms,
+		"use_independent_chat_permissions": true,
+	})
+	return err
+}
+
+// PinChatMessage pins a message; silent pins without notifying members.
+func (c *Client) PinChatMessage(ctx context.Context, chatID string, messageID int64, silent bool) error {
+	params := map[string]any{"chat_id": chatID, "message_id": messageID}
+	if silent {
+		params["disable_notification"] = true
+	}
+	_, err := c.call(ctx, "pinChatMessage", params)
+	return err
+}
+
+// UnpinChatMessage unpins a message, or the most recently pinned one when
+// messageID is 0.
+func (c *Client) UnpinChatMessage(ctx context.Context, chatID string, messageID int64) error {
+	params := map[string]any{"chat_id": chatID}
+	if messageID != 0 {
+		params["message_id"] = messageID
+	}
+	_, err := c.call(ctx, "unpinChatMessage", params)
+	return err
+}
+
+func (c *Client) UnpinAllChatMessages(ctx context.Context, chatID string) error {
+	_, err := c.call(ctx, "unpinAllChatMessages", map[string]any{"chat_id": chatID})
+	return err
+}
+
+func (c *Client) LeaveChat(ctx context.Context, chatID string) error {
+	_, err := c.call(ctx, "leaveChat", map[string]any{"chat_id": chatID})
+	return err
+}
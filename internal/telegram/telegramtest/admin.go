@@ -0,0 +1,92 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// AdminPrefix is the path prefix of the admin API served by AdminHandler.
+const AdminPrefix = "/_admin/"
+
+// InjectRequest describes a user message to inject through the admin API.
+// Update, when set, is enqueued verbatim instead.
+type InjectRequest struct {
+	Text      string          `json:"text"`
+	From      string          `json:"from"`
+	FromID    int64           `json:"from_id"`
+	ChatID    int64           `json:"chat_id"`
+	ChatType  string          `json:"chat_type"`
+	ChatTitle string          `json:"chat_title"`
+	Update    json.RawMessage `json:"update,omitempty"`
+}
+
+// Inject enqueues the update described by req and returns its update_id.
+func (h *Handler) Inject(req InjectRequest) (int64, error) {
+	if len(req.Update) > 0 {
+		return h.EnqueueUpdate(req.Update)
+	}
+
+	var from User
+	switch {
+	case req.FromID != 0 && req.From == "":
+		// A bare id is its own user: renumbering the default user would
+		// change every later message sent as alice.
+		from = h.userByID(req.FromID)
+	case req.FromID != 0:
+		var err error
+		if from, err = h.userNamed(req.From, req.FromID); err != nil {
+			return 0, err
+		}
+	default:
+		from = h.User(req.From)
+	}
+	chat := Chat{ID: from.ID, Type: "private", FirstName: from.FirstName, Username: from.Username}
+	if req.ChatID != 0 {
+		h.mu.Lock()
+		chat = h.chatLocked(strconv.FormatInt(req.ChatID, 10))
+		h.mu.Unlock()
+	}
+	if req.ChatType != "" {
+		chat.Type = req.ChatType
+	}
+	if req.ChatTitle != "" {
+		chat.Title = req.ChatTitle
+	}
+	return h.EnqueueMessage(IncomingMessage{Chat: chat, From: from, Text: req.Text}), nil
+}
+
+// AdminHandler serves the admin API used by mock-server:
+//
+//	POST /_admin/inject  InjectRequest -> {"update_id": n}
+//	GET  /_admin/state   current State
+//	GET  /_admin/sent    messages sent by the bot
+func (h *Handler) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch strings.TrimPrefix(r.URL.Path, AdminPrefix) {
+		case "inject":
+			if r.Method != http.MethodPost {
+				writeError(w, http.StatusMethodNotAllowed, "inject requires POST")
+				return
+			}
+			var req InjectRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+				return
+			}
+			id, err := h.Inject(req)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+				return
+			}
+			writeResult(w, map[string]int64{"update_id": id})
+		case "state":
+			writeResult(w, h.Snapshot())
+		case "sent":
+			writeResult(w, h.SentMessages())
+		default:
+			writeError(w, http.StatusNotFound, "Not Found")
+		}
+	})
+}
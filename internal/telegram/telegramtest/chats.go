@@ -0,0 +1,160 @@
+package telegramtest
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Member is a user's membership of a chat. Fields holds the remaining
+// ChatMember fields of its status, such as can_* rights, until_date or
+// custom_title.
+type Member struct {
+	User   User           `json:"user"`
+	Status string         `json:"status"`
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+// inChat reports whether the member counts towards getChatMemberCount.
+func (m Member) inChat() bool {
+	switch m.Status {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		isMember, _ := m.Fields["is_member"].(bool)
+		return isMember
+	}
+	return false
+}
+
+func (m Member) result() map[string]any {
+	out := map[string]any{"user": m.User, "status": m.Status}
+	for k, v := range m.Fields {
+		out[k] = v
+	}
+	return out
+}
+
+// SetMember adds or replaces a member of chat, registering the chat if it
+// is new.
+func (h *Handler) SetMember(chat Chat, m Member) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.chats[chat.ID]; !ok {
+		h.chats[chat.ID] = chat
+	}
+	h.setMemberLocked(chat.ID, m)
+	h.notifyLocked()
+}
+
+// Member returns the membership of userID in chatID; unknown users have
+// left.
+func (h *Handler) Member(chatID, userID int64) Member {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.memberLocked(chatID, userID)
+}
+
+func (h *Handler) memberLocked(chatID, userID int64) Member {
+	if m, ok := h.members[chatID][userID]; ok {
+		return m
+	}
+	user := User{ID: userID}
+	if userID == h.Bot.ID {
+		user = h.Bot
+	}
+	for _, u := range h.users {
+		if u.ID == userID {
+			user = u
+		}
+	}
+	return Member{User: user, Status: "left"}
+}
+
+func (h *Handler) setMemberLocked(chatID int64, m Member) {
+	if h.members[chatID] == nil {
+		h.members[chatID] = map[int64]Member{}
+	}
+	h.members[chatID][m.User.ID] = m
+}
+
+// knownChatLocked returns the chat addressed by the chat_id param, or a
+// "chat not found" error like Telegram. h.mu must be held.
+func (h *Handler) knownChatLocked(params map[string]string) (Chat, *apiError) {
+	chatID := params["chat_id"]
+	if chatID == "" {
+		return Chat{}, badRequest("chat_id is empty")
+	}
+	chat := h.chatLocked(chatID)
+	if _, ok := h.chats[chat.ID]; !ok || chat.ID == 0 {
+		return Chat{}, badRequest("chat not found")
+	}
+	return chat, nil
+}
+
+func (h *Handler) getChat(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	return h.chatInfoLocked(chat), nil
+}
+
+func (h *Handler) getChatMemberCount(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if chat.Type == "private" {
+		return 2, nil
+	}
+	n := 0
+	for _, m := range h.members[chat.ID] {
+		if m.inChat() {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (h *Handler) getChatAdministrators(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if chat.Type == "private" {
+		return nil, badRequest("there are no administrators in the private chat")
+	}
+	admins := []map[string]any{}
+	ids := make([]int64, 0, len(h.members[chat.ID]))
+	for id := range h.members[chat.ID] {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if m := h.members[chat.ID][id]; m.Status == "creator" || m.Status == "administrator" {
+			admins = append(admins, m.result())
+		}
+	}
+	return admins, nil
+}
+
+func (h *Handler) getChatMember(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	userID, err := strconv.ParseInt(params["user_id"], 10, 64)
+	if err != nil || userID == 0 {
+		return nil, badRequest("invalid user_id specified")
+	}
+	return h.memberLocked(chat.ID, userID).result(), nil
+}
@@ -0,0 +1,238 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// inviteLink is a ChatInviteLink with the chat it belongs to.
+type inviteLink struct {
+	ChatID             int64  `json:"-"`
+	InviteLink         string `json:"invite_link"`
+	Creator            User   `json:"creator"`
+	CreatesJoinRequest bool   `json:"creates_join_request"`
+	IsPrimary          bool   `json:"is_primary"`
+	IsRevoked          bool   `json:"is_revoked"`
+	Name               string `json:"name,omitempty"`
+	ExpireDate         int64  `json:"expire_date,omitempty"`
+	MemberLimit        int64  `json:"member_limit,omitempty"`
+}
+
+// IncomingJoinRequest describes a user asking to join a chat.
+type IncomingJoinRequest struct {
+	Chat Chat
+	From User
+	Bio  string
+	// InviteLink is the URL of a link returned by createChatInviteLink that
+	// the user followed, if any.
+	InviteLink string
+}
+
+// EnqueueJoinRequest queues a chat_join_request update, which stays pending
+// until it is approved or declined.
+func (h *Handler) EnqueueJoinRequest(r IncomingJoinRequest) int64 {
+	if r.From.ID == 0 {
+		r.From = DefaultUser
+	}
+	h.mu.Lock()
+	if _, ok := h.chats[r.Chat.ID]; !ok {
+		h.chats[r.Chat.ID] = r.Chat
+	}
+	if h.joinRequests[r.Chat.ID] == nil {
+		h.joinRequests[r.Chat.ID] = map[int64]User{}
+	}
+	h.joinRequests[r.Chat.ID][r.From.ID] = r.From
+	request := map[string]any{
+		"chat":         r.Chat,
+		"from":         r.From,
+		"user_chat_id": r.From.ID,
+		"date":         h.Now().Unix(),
+	}
+	if r.Bio != "" {
+		request["bio"] = r.Bio
+	}
+	if link, ok := h.invites[r.InviteLink]; ok {
+		request["invite_link"] = link
+	}
+	h.mu.Unlock()
+
+	raw, _ := json.Marshal(map[string]any{"chat_join_request": request})
+	id, _ := h.EnqueueUpdate(raw)
+	return id
+}
+
+// PendingJoinRequests returns the users waiting to join chatID, sorted.
+func (h *Handler) PendingJoinRequests(chatID int64) []int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var out []int64
+	for id := range h.joinRequests[chatID] {
+		out = append(out, id)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// inviteChatLocked returns the chat an invite method acts on, checking the
+// bot may invite users to it. h.mu must be held.
+func (h *Handler) inviteChatLocked(params map[string]string) (Chat, *apiError) {
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return Chat{}, apiErr
+	}
+	if chat.Type == "private" {
+		return Chat{}, badRequest("method is available only for supergroups and channel")
+	}
+	if !h.botHasRightLocked(chat.ID, "can_invite_users") {
+		return Chat{}, badRequest("not enough rights to manage chat invite link")
+	}
+	return chat, nil
+}
+
+// applyInviteParams sets the link options from params, replacing the
+// previous ones like editChatInviteLink.
+func applyInviteParams(link *inviteLink, params map[string]string) *apiError {
+	expire, err := intParam(params, "expire_date", 0)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+	limit, err := intParam(params, "member_limit", 0)
+	if err != nil {
+		return badRequest(err.Error())
+	}
+	if limit < 0 || limit > 99999 {
+		return badRequest("member limit must be between 1 and 99999")
+	}
+	joinRequest := params["creates_join_request"] == "true"
+	if joinRequest && limit > 0 {
+		return badRequest("member limit can't be specified for links requiring administrator approval")
+	}
+	link.Name, link.ExpireDate, link.MemberLimit, link.CreatesJoinRequest = params["name"], expire, limit, joinRequest
+	return nil
+}
+
+// addInviteLinkLocked gives link a new URL and stores it as created by the
+// bot. h.mu must be held.
+func (h *Handler) addInviteLinkLocked(chatID int64, link *inviteLink) *inviteLink {
+	h.nextInviteID++
+	link.ChatID = chatID
+	link.InviteLink = fmt.Sprintf("https://t.me/+fake%d", h.nextInviteID)
+	link.Creator = h.Bot
+	h.invites[link.InviteLink] = link
+	return link
+}
+
+// botInviteLinkLocked returns a link of chat created by the bot, as only
+// those can be edited or revoked. h.mu must be held.
+func (h *Handler) botInviteLinkLocked(chat Chat, params map[string]string) (*inviteLink, *apiError) {
+	link, ok := h.invites[params["invite_link"]]
+	if !ok || link.ChatID != chat.ID {
+		return nil, badRequest("INVITE_HASH_EXPIRED")
+	}
+	if link.Creator.ID != h.Bot.ID {
+		return nil, badRequest("CHAT_ADMIN_REQUIRED")
+	}
+	return link, nil
+}
+
+func (h *Handler) createChatInviteLink(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.inviteChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	link := &inviteLink{}
+	if apiErr := applyInviteParams(link, params); apiErr != nil {
+		return nil, apiErr
+	}
+	h.addInviteLinkLocked(chat.ID, link)
+	h.notifyLocked()
+	return link, nil
+}
+
+func (h *Handler) editChatInviteLink(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.inviteChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	link, apiErr := h.botInviteLinkLocked(chat, params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if link.IsRevoked || link.IsPrimary {
+		return nil, badRequest("INVITE_HASH_EXPIRED")
+	}
+	if apiErr := applyInviteParams(link, params); apiErr != nil {
+		return nil, apiErr
+	}
+	h.notifyLocked()
+	return link, nil
+}
+
+func (h *Handler) revokeChatInviteLink(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.inviteChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	link, apiErr := h.botInviteLinkLocked(chat, params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	link.IsRevoked = true
+	if link.IsPrimary {
+		link.IsPrimary = false
+		h.addInviteLinkLocked(chat.ID, &inviteLink{IsPrimary: true})
+	}
+	h.notifyLocked()
+	return link, nil
+}
+
+func (h *Handler) exportChatInviteLink(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.inviteChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	for _, link := range h.invites {
+		if link.ChatID == chat.ID && link.IsPrimary {
+			link.IsPrimary, link.IsRevoked = false, true
+		}
+	}
+	link := h.addInviteLinkLocked(chat.ID, &inviteLink{IsPrimary: true})
+	h.notifyLocked()
+	return link.InviteLink, nil
+}
+
+// answerChatJoinRequest handles approveChatJoinRequest and
+// declineChatJoinRequest; approved users become members.
+func (h *Handler) answerChatJoinRequest(_ *http.Request, method string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.inviteChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	userID, err := strconv.ParseInt(params["user_id"], 10, 64)
+	if err != nil || userID == 0 {
+		return nil, badRequest("invalid user_id specified")
+	}
+	user, ok := h.joinRequests[chat.ID][userID]
+	if !ok {
+		return nil, badRequest("HIDE_REQUESTER_MISSING")
+	}
+	delete(h.joinRequests[chat.ID], userID)
+	if method == "approveChatJoinRequest" {
+		h.setMemberLocked(chat.ID, Member{User: user, Status: "member"})
+	}
+	h.notifyLocked()
+	return true, nil
+}
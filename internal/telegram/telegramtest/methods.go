@@ -0,0 +1,408 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type methodFunc func(h *Handler, r *http.Request, method string, params map[string]string) (any, *apiError)
+
+func (h *Handler) methods() map[string]methodFunc {
+	return map[string]methodFunc{
+		"getMe":          (*Handler).getMe,
+		"getUpdates":     (*Handler).getUpdates,
+		"sendMessage":    (*Handler).sendMessage,
+		"setWebhook":     (*Handler).setWebhook,
+		"deleteWebhook":  (*Handler).deleteWebhook,
+		"getWebhookInfo": (*Handler).getWebhookInfo,
+		"getFile":        (*Handler).getFile,
+		"logOut":         returnTrue,
+		"close":          returnTrue,
+
+		"getChat":               (*Handler).getChat,
+		"getChatMemberCount":    (*Handler).getChatMemberCount,
+		"getChatAdministrators": (*Handler).getChatAdministrators,
+		"getChatMember":         (*Handler).getChatMember,
+
+		"banChatMember":                   (*Handler).banChatMember,
+		"unbanChatMember":                 (*Handler).unbanChatMember,
+		"restrictChatMember":              (*Handler).restrictChatMember,
+		"promoteChatMember":               (*Handler).promoteChatMember,
+		"setChatAdministratorCustomTitle": (*Handler).setChatAdministratorCustomTitle,
+
+		"createChatInviteLink":   (*Handler).createChatInviteLink,
+		"editChatInviteLink":     (*Handler).editChatInviteLink,
+		"revokeChatInviteLink":   (*Handler).revokeChatInviteLink,
+		"exportChatInviteLink":   (*Handler).exportChatInviteLink,
+		"approveChatJoinRequest": (*Handler).answerChatJoinRequest,
+		"declineChatJoinRequest": (*Handler).answerChatJoinRequest,
+
+		"setChatTitle":         (*Handler).setChatTitle,
+		"setChatDescription":   (*Handler).setChatDescription,
+		"setChatPhoto":         (*Handler).setChatPhoto,
+		"deleteChatPhoto":      (*Handler).deleteChatPhoto,
+		"setChatPermissions":   (*Handler).setChatPermissions,
+		"pinChatMessage":       (*Handler).pinChatMessage,
+		"unpinChatMessage":     (*Handler).unpinChatMessage,
+		"unpinAllChatMessages": (*Handler).unpinAllChatMessages,
+		"leaveChat":            (*Handler).leaveChat,
+
+		"createForumTopic":          (*Handler).createForumTopic,
+		"editForumTopic":            (*Handler).editForumTopic,
+		"closeForumTopic":           (*Handler).setTopicClosed,
+		"reopenForumTopic":          (*Handler).setTopicClosed,
+		"deleteForumTopic":          (*Handler).deleteForumTopic,
+		"getForumTopicIconStickers": (*Handler).getForumTopicIconStickers,
+		"editGeneralForumTopic":     (*Handler).editGeneralForumTopic,
+		"closeGeneralForumTopic":    (*Handler).setGeneralTopicState,
+		"reopenGeneralForumTopic":   (*Handler).setGeneralTopicState,
+		"hideGeneralForumTopic":     (*Handler).setGeneralTopicState,
+		"unhideGeneralForumTopic":   (*Handler).setGeneralTopicState,
+	}
+}
+
+func returnTrue(*Handler, *http.Request, string, map[string]string) (any, *apiError) {
+	return true, nil
+}
+
+func (h *Handler) getMe(*http.Request, string, map[string]string) (any, *apiError) {
+	return h.Bot, nil
+}
+
+// getUpdates follows the Bot API semantics: a positive offset confirms every
+// earlier update, a negative one keeps only the last -offset updates, and
+// timeout long-polls until an update arrives or the timeout elapses.
+// allowed_updates is kept for later calls; updates of other types stay
+// pending rather than being dropped, so tests can see they were not taken.
+func (h *Handler) getUpdates(r *http.Request, _ string, params map[string]string) (any, *apiError) {
+	offset, err := intParam(params, "offset", 0)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	limit, err := intParam(params, "limit", 100)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 100
+	}
+	timeout, err := intParam(params, "timeout", 0)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	var allowed []string
+	if raw, ok := params["allowed_updates"]; ok {
+		if err := json.Unmarshal([]byte(raw), &allowed); err != nil {
+			return nil, badRequest("can't parse allowed updates")
+		}
+	}
+	deadline := time.NewTimer(time.Duration(timeout) * time.Second)
+	defer deadline.Stop()
+
+	for {
+		h.mu.Lock()
+		if h.webhook.URL != "" {
+			h.mu.Unlock()
+			return nil, &apiError{code: http.StatusConflict, description: "Conflict: can't use getUpdates method while webhook is active; use deleteWebhook to delete the webhook first"}
+		}
+		if allowed != nil {
+			h.allowedUpdates = allowed
+		}
+		h.confirmLocked(offset)
+		var out []json.RawMessage
+		for _, u := range h.updates {
+			if len(out) < int(limit) && h.allowedLocked(u.raw) {
+				out = append(out, u.raw)
+			}
+		}
+		if len(out) > 0 || timeout <= 0 {
+			h.mu.Unlock()
+			return append([]json.RawMessage{}, out...), nil
+		}
+		changed := h.changed
+		h.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-deadline.C:
+			timeout = 0
+		case <-r.Context().Done():
+			return []json.RawMessage{}, nil
+		}
+	}
+}
+
+// allowedLocked reports whether the update type of raw passes the last
+// allowed_updates; an empty list allows everything. h.mu must be held.
+func (h *Handler) allowedLocked(raw json.RawMessage) bool {
+	if len(h.allowedUpdates) == 0 {
+		return true
+	}
+	var fields map[string]json.RawMessage
+	_ = json.Unmarshal(raw, &fields)
+	for _, t := range h.allowedUpdates {
+		if _, ok := fields[t]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// confirmLocked drops updates confirmed by offset. h.mu must be held.
+func (h *Handler) confirmLocked(offset int64) {
+	switch {
+	case offset > 0:
+		kept := h.updates[:0]
+		for _, u := range h.updates {
+			if u.id >= offset {
+				kept = append(kept, u)
+			}
+		}
+		if len(kept) != len(h.updates) {
+			h.updates = kept
+			h.notifyLocked()
+		}
+	case offset < 0:
+		if keep := int(-offset); keep < len(h.updates) {
+			h.updates = append([]pendingUpdate(nil), h.updates[len(h.updates)-keep:]...)
+			h.notifyLocked()
+		}
+	}
+}
+
+// sendMessage handles sendMessage and every other send* method, answering
+// with a message from the bot to the target chat.
+func (h *Handler) sendMessage(_ *http.Request, method string, params map[string]string) (any, *apiError) {
+	chatID := params["chat_id"]
+	if chatID == "" {
+		return nil, badRequest("chat_id is empty")
+	}
+	text := params["text"]
+	if method == "sendMessage" && text == "" {
+		return nil, badRequest("message text is empty")
+	}
+	threadID, err := intParam(params, "message_thread_id", 0)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat := h.chatLocked(chatID)
+	msg := map[string]any{
+		"message_id": h.nextMessageID,
+		"from":       h.Bot,
+		"chat":       chat,
+		"date":       h.Now().Unix(),
+	}
+	if apiErr := h.threadLocked(chat, threadID, msg); apiErr != nil {
+		return nil, apiErr
+	}
+	id := h.nextMessageID
+	h.nextMessageID++
+	h.sent = append(h.sent, SentMessage{Method: method, ChatID: chatID, Text: text, MessageID: id, Params: params})
+
+	if text != "" {
+		msg["text"] = text
+	}
+	if caption := params["caption"]; caption != "" {
+		msg["caption"] = caption
+	}
+	h.rememberLocked(chat, h.Bot, msg)
+	h.notifyLocked()
+	return msg, nil
+}
+
+// chatLocked returns the known chat for chatID, or one guessed from the id.
+// h.mu must be held.
+func (h *Handler) chatLocked(chatID string) Chat {
+	guess := chatFromID(chatID)
+	if known, ok := h.chats[guess.ID]; ok && guess.ID != 0 {
+		return known
+	}
+	if guess.Username != "" {
+		for _, c := range h.chats {
+			if strings.EqualFold(c.Username, guess.Username) {
+				return c
+			}
+		}
+	}
+	return guess
+}
+
+// chatFromID guesses a chat from its id the way Telegram numbers them:
+// positive ids are users, -100… are supergroups and channels.
+func chatFromID(chatID string) Chat {
+	if strings.HasPrefix(chatID, "@") {
+		return Chat{Type: "channel", Username: strings.TrimPrefix(chatID, "@")}
+	}
+	id, _ := strconv.ParseInt(chatID, 10, 64)
+	switch {
+	case id > 0:
+		return Chat{ID: id, Type: "private"}
+	case strings.HasPrefix(chatID, "-100"):
+		return Chat{ID: id, Type: "supergroup"}
+	default:
+		return Chat{ID: id, Type: "group"}
+	}
+}
+
+func (h *Handler) setWebhook(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.webhook = WebhookState{URL: params["url"], SecretToken: params["secret_token"]}
+	h.webhookError = webhookError{}
+	if params["drop_pending_updates"] == "true" {
+		h.updates = nil
+	}
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) deleteWebhook(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.webhook = WebhookState{}
+	h.webhookError = webhookError{}
+	if params["drop_pending_updates"] == "true" {
+		h.updates = nil
+	}
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) getWebhookInfo(*http.Request, string, map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	info := map[string]any{
+		"url":                    h.webhook.URL,
+		"has_custom_certificate": false,
+		"pending_update_count":   len(h.updates),
+	}
+	if h.webhookError.date != 0 {
+		info["last_error_date"] = h.webhookError.date
+		info["last_error_message"] = h.webhookError.message
+	}
+	return info, nil
+}
+
+func (h *Handler) getFile(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	fileID := params["file_id"]
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, ok := h.files[fileID]
+	if !ok {
+		return nil, badRequest("invalid file_id")
+	}
+	return map[string]any{
+		"file_id":        fileID,
+		"file_unique_id": "u" + fileID,
+		"file_size":      len(f.data),
+		"file_path":      f.path,
+	}, nil
+}
+
+// readParams collects request parameters from the query string and a JSON,
+// urlencoded or multipart body. Non-string JSON values are kept in their
+// JSON encoding, matching how multipart clients send them. Uploaded files
+// are registered so getFile can serve them back.
+func readParams(r *http.Request, h *Handler) (map[string]string, error) {
+	params := map[string]string{}
+	for k, v := range r.URL.Query() {
+		params[k] = v[0]
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case "application/json":
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(body))) == 0 {
+			return params, nil
+		}
+		fields, err := jsonParams(body)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range fields {
+			params[k] = v
+		}
+	case "application/x-www-form-urlencoded":
+		if err := r.ParseForm(); err != nil {
+			return nil, err
+		}
+		for k, v := range r.PostForm {
+			params[k] = v[0]
+		}
+	case "multipart/form-data":
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return nil, err
+		}
+		for k, v := range r.MultipartForm.Value {
+			params[k] = v[0]
+		}
+		for field, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				return nil, err
+			}
+			data, err := io.ReadAll(f)
+			_ = f.Close()
+			if err != nil {
+				return nil, err
+			}
+			params[field] = h.storeUpload(field, headers[0].Filename, data)
+		}
+	}
+	return params, nil
+}
+
+// jsonParams flattens a JSON object into string params, keeping non-string
+// values in their JSON encoding.
+func jsonParams(body []byte) (map[string]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("can't parse JSON object: %v", err)
+	}
+	params := make(map[string]string, len(fields))
+	for k, raw := range fields {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			params[k] = s
+		} else {
+			params[k] = string(raw)
+		}
+	}
+	return params, nil
+}
+
+// storeUpload keeps an uploaded file and returns its new file_id.
+func (h *Handler) storeUpload(field, name string, data []byte) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextFileID++
+	id := fmt.Sprintf("file-%d", h.nextFileID)
+	h.files[id] = file{path: field + "s/" + name, data: data}
+	h.notifyLocked()
+	return id
+}
+
+func intParam(params map[string]string, key string, def int64) (int64, error) {
+	v, ok := params[key]
+	if !ok || v == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, v)
+	}
+	return n, nil
+}
@@ -0,0 +1,139 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// moderationTargetLocked returns the group and user a moderation method
+// acts on, checking that the bot is an administrator holding right and
+// that the user is not the chat's owner. h.mu must be held.
+func (h *Handler) moderationTargetLocked(params map[string]string, right string) (Chat, Member, *apiError) {
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return Chat{}, Member{}, apiErr
+	}
+	if chat.Type == "private" {
+		return Chat{}, Member{}, badRequest("method is available only for supergroups and channel")
+	}
+	userID, err := strconv.ParseInt(params["user_id"], 10, 64)
+	if err != nil || userID == 0 {
+		return Chat{}, Member{}, badRequest("invalid user_id specified")
+	}
+	if !h.botHasRightLocked(chat.ID, right) {
+		return Chat{}, Member{}, badRequest("not enough rights to manage chat members")
+	}
+	target := h.memberLocked(chat.ID, userID)
+	if target.Status == "creator" {
+		return Chat{}, Member{}, badRequest("can't remove chat owner")
+	}
+	return chat, target, nil
+}
+
+// botHasRightLocked reports whether the bot owns chatID or administers it
+// with right. h.mu must be held.
+func (h *Handler) botHasRightLocked(chatID int64, right string) bool {
+	bot := h.memberLocked(chatID, h.Bot.ID)
+	allowed, _ := bot.Fields[right].(bool)
+	return bot.Status == "creator" || (bot.Status == "administrator" && allowed)
+}
+
+func (h *Handler) banChatMember(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, target, apiErr := h.moderationTargetLocked(params, "can_restrict_members")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	until, err := intParam(params, "until_date", 0)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	h.setMemberLocked(chat.ID, Member{User: target.User, Status: "kicked", Fields: map[string]any{"until_date": until}})
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) unbanChatMember(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, target, apiErr := h.moderationTargetLocked(params, "can_restrict_members")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if target.Status != "kicked" && params["only_if_banned"] == "true" {
+		return true, nil
+	}
+	h.setMemberLocked(chat.ID, Member{User: target.User, Status: "left"})
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) restrictChatMember(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, target, apiErr := h.moderationTargetLocked(params, "can_restrict_members")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	until, err := intParam(params, "until_date", 0)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	fields := map[string]any{}
+	if err := json.Unmarshal([]byte(params["permissions"]), &fields); err != nil {
+		return nil, badRequest("can't parse permissions JSON object")
+	}
+	fields["until_date"] = until
+	fields["is_member"] = target.inChat()
+	h.setMemberLocked(chat.ID, Member{User: target.User, Status: "restricted", Fields: fields})
+	h.notifyLocked()
+	return true, nil
+}
+
+// promoteChatMember makes the user an administrator with the rights set
+// to true, or a plain member when none are.
+func (h *Handler) promoteChatMember(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, target, apiErr := h.moderationTargetLocked(params, "can_promote_members")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	fields := map[string]any{}
+	for k, v := range params {
+		if (strings.HasPrefix(k, "can_") || k == "is_anonymous") && v == "true" {
+			fields[k] = true
+		}
+	}
+	m := Member{User: target.User, Status: "member"}
+	if len(fields) > 0 {
+		fields["can_be_edited"] = true
+		m = Member{User: target.User, Status: "administrator", Fields: fields}
+	}
+	h.setMemberLocked(chat.ID, m)
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) setChatAdministratorCustomTitle(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, target, apiErr := h.moderationTargetLocked(params, "can_promote_members")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if target.Status != "administrator" {
+		return nil, badRequest("user is not an administrator")
+	}
+	fields := map[string]any{}
+	for k, v := range target.Fields {
+		fields[k] = v
+	}
+	fields["custom_title"] = params["custom_title"]
+	h.setMemberLocked(chat.ID, Member{User: target.User, Status: target.Status, Fields: fields})
+	h.notifyLocked()
+	return true, nil
+}
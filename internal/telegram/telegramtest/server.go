@@ -0,0 +1,379 @@
+// Package telegramtest provides an in-process fake of the Telegram Bot API
+// for tests, in the spirit of net/http/httptest.
+//
+// The fake keeps all state in memory. Tests enqueue incoming updates, run the
+// code under test against Server.URL, then assert on the messages the bot
+// sent and the methods it called.
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultToken is the token accepted by NewServer.
+const DefaultToken = "123456:TEST-token"
+
+// User is a Telegram user or bot account.
+type User struct {
+	ID        int64  `json:"id"`
+	IsBot     bool   `json:"is_bot"`
+	FirstName string `json:"first_name"`
+	Username  string `json:"username,omitempty"`
+}
+
+// Chat is a Telegram chat.
+type Chat struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title,omitempty"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	IsForum   bool   `json:"is_forum,omitempty"`
+}
+
+var (
+	// DefaultBot is the identity returned by getMe.
+	DefaultBot = User{ID: 123456, IsBot: true, FirstName: "Test Bot", Username: "test_bot"}
+	// DefaultUser sends messages enqueued without an explicit sender.
+	DefaultUser = User{ID: 42, FirstName: "Alice", Username: "alice"}
+)
+
+// Call is one Bot API request received by the fake.
+type Call struct {
+	Method string
+	Params map[string]string
+	// Path is the request's URL path, token included; for a method a
+	// webhook answered with, the path of the webhook request.
+	Path string
+}
+
+// SentMessage is a message the bot sent through a send* method.
+type SentMessage struct {
+	Method    string            `json:"method"`
+	ChatID    string            `json:"chat_id"`
+	Text      string            `json:"text,omitempty"`
+	MessageID int64             `json:"message_id"`
+	Params    map[string]string `json:"params"`
+}
+
+// WebhookState mirrors what setWebhook configured.
+type WebhookState struct {
+	URL         string `json:"url"`
+	SecretToken string `json:"secret_token,omitempty"`
+}
+
+type file struct {
+	path string
+	data []byte
+}
+
+// Handler is the fake Bot API. It serves /bot<token>/<method> (and the
+// /test/ variant) plus /file/bot<token>/<path> downloads.
+type Handler struct {
+	// Token is required in request paths; empty accepts any token.
+	Token string
+	// Bot is the identity returned by getMe.
+	Bot User
+	// Now stamps message dates; defaults to time.Now.
+	Now func() time.Time
+
+	mu            sync.Mutex
+	changed       chan struct{}
+	nextUpdateID  int64
+	nextMessageID int64
+	nextFileID    int64
+	updates       []pendingUpdate
+	sent          []SentMessage
+	calls         []Call
+	webhook       WebhookState
+	webhookError  webhookError
+	// allowedUpdates is the last allowed_updates passed to getUpdates.
+	allowedUpdates []string
+	files          map[string]file
+	chats          map[int64]Chat
+	members        map[int64]map[int64]Member
+	invites        map[string]*inviteLink
+	settings       map[int64]*ChatSettings
+	joinRequests   map[int64]map[int64]User
+	topics         map[int64]map[int64]*Topic
+	generalTopics  map[int64]*Topic
+	nextInviteID   int64
+	users          map[string]User
+	messages       []json.RawMessage
+	revision       int64
+}
+
+type pendingUpdate struct {
+	id  int64
+	raw json.RawMessage
+}
+
+// NewHandler returns an empty fake accepting token (any token when empty).
+func NewHandler(token string) *Handler {
+	return &Handler{
+		Token:         token,
+		Bot:           DefaultBot,
+		Now:           time.Now,
+		changed:       make(chan struct{}),
+		nextUpdateID:  1,
+		nextMessageID: 1,
+		files:         map[string]file{},
+		chats:         map[int64]Chat{},
+		members:       map[int64]map[int64]Member{},
+		invites:       map[string]*inviteLink{},
+		settings:      map[int64]*ChatSettings{},
+		joinRequests:  map[int64]map[int64]User{},
+		topics:        map[int64]map[int64]*Topic{},
+		generalTopics: map[int64]*Topic{},
+		users:         map[string]User{},
+	}
+}
+
+// Server is a Handler listening on a local httptest server.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts a fake accepting DefaultToken. Callers must Close it.
+func NewServer() *Server {
+	h := NewHandler(DefaultToken)
+	return &Server{Server: httptest.NewServer(h), Handler: h}
+}
+
+// EnqueueUpdate queues a raw update for getUpdates. A missing or zero
+// update_id is assigned; the final id is returned.
+func (h *Handler) EnqueueUpdate(raw json.RawMessage) (int64, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return 0, err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var id int64
+	if existing, ok := fields["update_id"]; ok {
+		_ = json.Unmarshal(existing, &id)
+	}
+	if id == 0 {
+		id = h.nextUpdateID
+		fields["update_id"] = json.RawMessage(strconv.FormatInt(id, 10))
+		encoded, err := json.Marshal(fields)
+		if err != nil {
+			return 0, err
+		}
+		raw = encoded
+	}
+	if id >= h.nextUpdateID {
+		h.nextUpdateID = id + 1
+	}
+	h.updates = append(h.updates, pendingUpdate{id: id, raw: raw})
+	h.notifyLocked()
+	return id, nil
+}
+
+// IncomingMessage describes a message a user sends to the bot.
+type IncomingMessage struct {
+	Chat Chat
+	From User
+	Text string
+	// ThreadID is the forum topic or reply thread the message is sent to.
+	ThreadID int64
+}
+
+// EnqueueMessage queues a message update. An empty sender defaults to
+// DefaultUser and an empty chat to the sender's private chat.
+func (h *Handler) EnqueueMessage(m IncomingMessage) int64 {
+	if m.From.ID == 0 {
+		m.From = DefaultUser
+	}
+	if m.Chat.ID == 0 {
+		m.Chat = Chat{ID: m.From.ID, Type: "private", FirstName: m.From.FirstName, Username: m.From.Username}
+	}
+	h.mu.Lock()
+	msg := map[string]any{
+		"message_id": h.nextMessageID,
+		"from":       m.From,
+		"chat":       m.Chat,
+		"date":       h.Now().Unix(),
+		"text":       m.Text,
+	}
+	h.nextMessageID++
+	// An unknown topic is kept as a plain thread id rather than failing.
+	_ = h.threadLocked(m.Chat, m.ThreadID, msg)
+	h.rememberLocked(m.Chat, m.From, msg)
+	h.mu.Unlock()
+
+	raw, _ := json.Marshal(map[string]any{"message": msg})
+	id, _ := h.EnqueueUpdate(raw)
+	return id
+}
+
+// EnqueueText queues a text message from DefaultUser in its private chat.
+func (h *Handler) EnqueueText(text string) int64 {
+	return h.EnqueueMessage(IncomingMessage{Text: text})
+}
+
+// AddFile registers a file served by getFile and the file endpoint.
+func (h *Handler) AddFile(fileID, filePath string, data []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.files[fileID] = file{path: filePath, data: data}
+	h.notifyLocked()
+}
+
+// SentMessages returns every message sent by the bot, oldest first.
+func (h *Handler) SentMessages() []SentMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]SentMessage(nil), h.sent...)
+}
+
+// Calls returns every request received, oldest first.
+func (h *Handler) Calls() []Call {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Call(nil), h.calls...)
+}
+
+// CallCount returns how many times method was called.
+func (h *Handler) CallCount(method string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := 0
+	for _, c := range h.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// PendingUpdates returns the ids of updates not yet confirmed by an offset.
+func (h *Handler) PendingUpdates() []int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ids := make([]int64, 0, len(h.updates))
+	for _, u := range h.updates {
+		ids = append(ids, u.id)
+	}
+	return ids
+}
+
+// Webhook returns the current webhook configuration.
+func (h *Handler) Webhook() WebhookState {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.webhook
+}
+
+// SetWebhook configures a webhook as if setWebhook had been called.
+func (h *Handler) SetWebhook(state WebhookState) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.webhook = state
+	h.notifyLocked()
+}
+
+// notifyLocked records a state change and wakes long-polling getUpdates
+// calls and webhook delivery. h.mu must be held.
+func (h *Handler) notifyLocked() {
+	h.revision++
+	close(h.changed)
+	h.changed = make(chan struct{})
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) >= 3 && parts[0] == "file" && h.tokenOK(parts[1]) {
+		rest := parts[2:]
+		if len(rest) > 0 && rest[0] == "test" {
+			rest = rest[1:]
+		}
+		h.serveFile(w, strings.Join(rest, "/"))
+		return
+	}
+	if len(parts) < 2 || !h.tokenOK(parts[0]) {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	method := parts[len(parts)-1]
+	if len(parts) == 3 && parts[1] != "test" || len(parts) > 3 {
+		writeError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+
+	params, err := readParams(r, h)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Bad Request: "+err.Error())
+		return
+	}
+	result, apiErr := h.dispatch(r, method, params)
+	if apiErr != nil {
+		writeError(w, apiErr.code, apiErr.description)
+		return
+	}
+	writeResult(w, result)
+}
+
+// dispatch records and executes one Bot API call.
+func (h *Handler) dispatch(r *http.Request, method string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	h.calls = append(h.calls, Call{Method: method, Params: params, Path: r.URL.Path})
+	h.mu.Unlock()
+
+	fn, ok := h.methods()[method]
+	if !ok && strings.HasPrefix(method, "send") {
+		fn, ok = (*Handler).sendMessage, true
+	}
+	if !ok {
+		return nil, &apiError{code: http.StatusNotFound, description: "Not Found"}
+	}
+	return fn(h, r, method, params)
+}
+
+func (h *Handler) tokenOK(segment string) bool {
+	if !strings.HasPrefix(segment, "bot") {
+		return false
+	}
+	return h.Token == "" || strings.TrimPrefix(segment, "bot") == h.Token
+}
+
+func (h *Handler) serveFile(w http.ResponseWriter, filePath string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, f := range h.files {
+		if f.path == filePath {
+			_, _ = w.Write(f.data)
+			return
+		}
+	}
+	http.NotFound(w, nil)
+}
+
+// apiError is a Bot API error response.
+type apiError struct {
+	code        int
+	description string
+}
+
+func badRequest(description string) *apiError {
+	return &apiError{code: http.StatusBadRequest, description: "Bad Request: " + description}
+}
+
+func writeResult(w http.ResponseWriter, result any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "result": result})
+}
+
+func writeError(w http.ResponseWriter, code int, description string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error_code": code, "description": description})
+}
@@ -0,0 +1,354 @@
+package telegramtest_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func newClient(t *testing.T) (*telegramtest.Server, *telegram.Client) {
+	t.Helper()
+	srv := telegramtest.NewServer()
+	t.Cleanup(srv.Close)
+	return srv, telegram.NewClient(srv.URL, telegramtest.DefaultToken)
+}
+
+func TestGetMeAndUnauthorized(t *testing.T) {
+	srv, c := newClient(t)
+	res, err := c.GetMe(context.Background())
+	if err != nil {
+		t.Fatalf("GetMe returned error: %v", err)
+	}
+	if !strings.Contains(string(res), `"username":"test_bot"`) {
+		t.Fatalf("unexpected getMe result: %s", res)
+	}
+
+	wrong := telegram.NewClient(srv.URL, "1:wrong")
+	if _, err := wrong.GetMe(context.Background()); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Fatalf("expected Unauthorized, got %v", err)
+	}
+}
+
+func TestGetUpdatesOffsetSemantics(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	for _, text := range []string{"one", "two", "three"} {
+		srv.EnqueueText(text)
+	}
+
+	updates, err := c.GetUpdates(ctx, 0, 0)
+	if err != nil {
+		t.Fatalf("GetUpdates returned error: %v", err)
+	}
+	if len(updates) != 3 || updates[0].UpdateID != 1 {
+		t.Fatalf("unexpected updates: %+v", updates)
+	}
+
+	if _, err := c.GetUpdates(ctx, 3, 0); err != nil {
+		t.Fatalf("GetUpdates returned error: %v", err)
+	}
+	if pending := srv.PendingUpdates(); len(pending) != 1 || pending[0] != 3 {
+		t.Fatalf("offset 3 should confirm updates 1 and 2, pending %v", pending)
+	}
+
+	limited, err := c.GetUpdatesWithLimit(ctx, 0, 0, 1)
+	if err != nil || len(limited) != 1 {
+		t.Fatalf("expected one update with limit 1, got %v %v", limited, err)
+	}
+}
+
+func TestGetUpdatesLongPoll(t *testing.T) {
+	srv, c := newClient(t)
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		srv.EnqueueText("late")
+	}()
+
+	start := time.Now()
+	updates, err := c.GetUpdates(context.Background(), 0, 5)
+	if err != nil {
+		t.Fatalf("GetUpdates returned error: %v", err)
+	}
+	if len(updates) != 1 || !bytes.Contains(updates[0].Raw, []byte(`"late"`)) {
+		t.Fatalf("expected the late update, got %+v", updates)
+	}
+	if time.Since(start) > 3*time.Second {
+		t.Fatalf("long poll should return as soon as an update arrives")
+	}
+
+	start = time.Now()
+	updates, err = c.GetUpdates(context.Background(), 2, 1)
+	if err != nil || len(updates) != 0 {
+		t.Fatalf("expected empty poll, got %v %v", updates, err)
+	}
+	if time.Since(start) < 900*time.Millisecond {
+		t.Fatalf("empty long poll returned before its timeout")
+	}
+}
+
+func TestWebhookBlocksGetUpdates(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	if err := c.SetWebhook(ctx, "https://example.com/hook"); err != nil {
+		t.Fatalf("SetWebhook returned error: %v", err)
+	}
+	if srv.Webhook().URL != "https://example.com/hook" {
+		t.Fatalf("webhook not recorded: %+v", srv.Webhook())
+	}
+	if _, err := c.GetUpdates(ctx, 0, 0); err == nil || !strings.Contains(err.Error(), "Conflict") {
+		t.Fatalf("expected conflict while webhook is set, got %v", err)
+	}
+	if err := c.DeleteWebhook(ctx); err != nil {
+		t.Fatalf("DeleteWebhook returned error: %v", err)
+	}
+	info, err := c.GetWebhookInfo(ctx)
+	if err != nil || info.URL != "" {
+		t.Fatalf("expected cleared webhook, got %+v %v", info, err)
+	}
+}
+
+func TestSendMessageAndFiles(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	if _, err := c.SendMessage(ctx, "42", "hello"); err != nil {
+		t.Fatalf("SendMessage returned error: %v", err)
+	}
+	if _, err := c.SendMessage(ctx, "42", ""); err == nil {
+		t.Fatalf("expected error for empty text")
+	}
+	sent := srv.SentMessages()
+	if len(sent) != 1 || sent[0].ChatID != "42" || sent[0].Text != "hello" {
+		t.Fatalf("unexpected sent messages: %+v", sent)
+	}
+
+	srv.AddFile("f1", "documents/a.txt", []byte("content"))
+	f, err := c.GetFile(ctx, "f1")
+	if err != nil {
+		t.Fatalf("GetFile returned error: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := c.DownloadFile(ctx, f.FilePath, &buf); err != nil || buf.String() != "content" {
+		t.Fatalf("unexpected download %q: %v", buf.String(), err)
+	}
+	if srv.CallCount("getFile") != 1 {
+		t.Fatalf("expected one getFile call, got %d", srv.CallCount("getFile"))
+	}
+}
+
+func TestChatMembers(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	group := telegramtest.Chat{ID: -100, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultUser, Status: "creator"})
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_pin_messages": true}})
+
+	if _, err := c.GetChat(ctx, "-999"); err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Fatalf("expected chat not found, got %v", err)
+	}
+	if n, err := c.GetChatMemberCount(ctx, "-100"); err != nil || n != 2 {
+		t.Fatalf("unexpected member count %d: %v", n, err)
+	}
+	admins, err := c.GetChatAdministrators(ctx, "-100")
+	if err != nil || !strings.Contains(string(admins), `"can_pin_messages":true`) {
+		t.Fatalf("unexpected admins %s: %v", admins, err)
+	}
+	member, err := c.GetChatMember(ctx, "-100", 7)
+	if err != nil || !strings.Contains(string(member), `"status":"left"`) {
+		t.Fatalf("unexpected unknown member %s: %v", member, err)
+	}
+}
+
+func TestModeration(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	group := telegramtest.Chat{ID: -100, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultUser, Status: "creator"})
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.User{ID: 7}, Status: "member"})
+
+	if err := c.BanChatMember(ctx, "-100", 7, time.Time{}, false); err == nil || !strings.Contains(err.Error(), "not enough rights") {
+		t.Fatalf("expected missing rights error, got %v", err)
+	}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_restrict_members": true, "can_promote_members": true}})
+
+	until := time.Unix(1700000000, 0)
+	if err := c.RestrictChatMember(ctx, "-100", 7, telegram.ChatPermissions{CanSendMessages: true}, until); err != nil {
+		t.Fatalf("RestrictChatMember returned error: %v", err)
+	}
+	if m := srv.Member(-100, 7); m.Status != "restricted" || m.Fields["can_send_messages"] != true || m.Fields["can_send_photos"] != false {
+		t.Fatalf("unexpected restricted member: %+v", m)
+	}
+	if err := c.PromoteChatMember(ctx, "-100", 7, telegram.ChatAdministratorRights{CanPinMessages: true}); err != nil {
+		t.Fatalf("PromoteChatMember returned error: %v", err)
+	}
+	if err := c.SetChatAdministratorCustomTitle(ctx, "-100", 7, "mod"); err != nil {
+		t.Fatalf("SetChatAdministratorCustomTitle returned error: %v", err)
+	}
+	if m := srv.Member(-100, 7); m.Status != "administrator" || m.Fields["custom_title"] != "mod" {
+		t.Fatalf("unexpected promoted member: %+v", m)
+	}
+	if err := c.BanChatMember(ctx, "-100", 7, until, true); err != nil {
+		t.Fatalf("BanChatMember returned error: %v", err)
+	}
+	if m := srv.Member(-100, 7); m.Status != "kicked" {
+		t.Fatalf("unexpected banned member: %+v", m)
+	}
+	if err := c.UnbanChatMember(ctx, "-100", 7, true); err != nil || srv.Member(-100, 7).Status != "left" {
+		t.Fatalf("unexpected unban: %+v %v", srv.Member(-100, 7), err)
+	}
+	if err := c.BanChatMember(ctx, "-100", 42, time.Time{}, false); err == nil || !strings.Contains(err.Error(), "chat owner") {
+		t.Fatalf("expected chat owner error, got %v", err)
+	}
+}
+
+func TestInviteLinksAndJoinRequests(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	group := telegramtest.Chat{ID: -100, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_invite_users": true}})
+
+	if _, err := c.CreateChatInviteLink(ctx, "-100", telegram.InviteLinkOptions{MemberLimit: 5, CreatesJoinRequest: true}); err == nil {
+		t.Fatal("expected error for member limit on a join request link")
+	}
+	link, err := c.CreateChatInviteLink(ctx, "-100", telegram.InviteLinkOptions{Name: "partners", CreatesJoinRequest: true})
+	if err != nil || link.Name != "partners" || !link.CreatesJoinRequest || link.Creator.ID != telegramtest.DefaultBot.ID {
+		t.Fatalf("unexpected created link %+v: %v", link, err)
+	}
+	edited, err := c.EditChatInviteLink(ctx, "-100", link.InviteLink, telegram.InviteLinkOptions{MemberLimit: 10})
+	if err != nil || edited.MemberLimit != 10 || edited.CreatesJoinRequest || edited.Name != "" {
+		t.Fatalf("unexpected edited link %+v: %v", edited, err)
+	}
+	revoked, err := c.RevokeChatInviteLink(ctx, "-100", link.InviteLink)
+	if err != nil || !revoked.IsRevoked {
+		t.Fatalf("unexpected revoked link %+v: %v", revoked, err)
+	}
+	primary, err := c.ExportChatInviteLink(ctx, "-100")
+	if err != nil || primary == "" || primary == link.InviteLink {
+		t.Fatalf("unexpected exported link %q: %v", primary, err)
+	}
+
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group, From: telegramtest.User{ID: 7, FirstName: "Bob"}})
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group})
+	if err := c.ApproveChatJoinRequest(ctx, "-100", 7); err != nil {
+		t.Fatalf("ApproveChatJoinRequest returned error: %v", err)
+	}
+	if err := c.DeclineChatJoinRequest(ctx, "-100", 7); err == nil {
+		t.Fatal("expected error answering a request twice")
+	}
+	if m := srv.Member(-100, 7); m.Status != "member" || m.User.FirstName != "Bob" {
+		t.Fatalf("unexpected approved member: %+v", m)
+	}
+	if pending := srv.PendingJoinRequests(-100); len(pending) != 1 || pending[0] != telegramtest.DefaultUser.ID {
+		t.Fatalf("unexpected pending requests: %v", pending)
+	}
+}
+
+func TestChatSettings(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	group := telegramtest.Chat{ID: -100, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_change_info": true, "can_pin_messages": true}})
+
+	if err := c.SetChatTitle(ctx, "-100", "Ops Team"); err != nil {
+		t.Fatalf("SetChatTitle returned error: %v", err)
+	}
+	if err := c.SetChatDescription(ctx, "-100", "on call"); err != nil {
+		t.Fatalf("SetChatDescription returned error: %v", err)
+	}
+	if err := c.SetChatPermissions(ctx, "-100", telegram.ChatPermissions{}); err == nil || !strings.Contains(err.Error(), "not enough rights") {
+		t.Fatalf("expected missing rights error, got %v", err)
+	}
+	msg, err := c.SendMessage(ctx, "-100", "announcement")
+	if err != nil {
+		t.Fatalf("SendMessage returned error: %v", err)
+	}
+	var sent telegram.Message
+	_ = json.Unmarshal(msg, &sent)
+	if err := c.PinChatMessage(ctx, "-100", sent.MessageID, true); err != nil {
+		t.Fatalf("PinChatMessage returned error: %v", err)
+	}
+	if err := c.PinChatMessage(ctx, "-100", 999, false); err == nil {
+		t.Fatal("expected error pinning an unknown message")
+	}
+
+	raw, err := c.GetChat(ctx, "-100")
+	if err != nil {
+		t.Fatalf("GetChat returned error: %v", err)
+	}
+	var info telegram.ChatFullInfo
+	if err := json.Unmarshal(raw, &info); err != nil || info.Title != "Ops Team" || info.Description != "on call" || info.PinnedMessage == nil || info.PinnedMessage.Text != "announcement" {
+		t.Fatalf("unexpected chat info %s: %v", raw, err)
+	}
+	if err := c.UnpinChatMessage(ctx, "-100", 0); err != nil || len(srv.Settings(-100).Pinned) != 0 {
+		t.Fatalf("unexpected unpin: %+v %v", srv.Settings(-100), err)
+	}
+	if err := c.LeaveChat(ctx, "-100"); err != nil || srv.Member(-100, telegramtest.DefaultBot.ID).Status != "left" {
+		t.Fatalf("unexpected leave: %v", err)
+	}
+}
+
+func TestForumTopics(t *testing.T) {
+	srv, c := newClient(t)
+	ctx := context.Background()
+	forum := telegramtest.Chat{ID: -100, Type: "supergroup", Title: "Support", IsForum: true}
+	srv.SetMember(forum, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_manage_topics": true}})
+	srv.SetMember(telegramtest.Chat{ID: -200, Type: "supergroup", Title: "Plain"}, telegramtest.Member{User: telegramtest.DefaultBot, Status: "creator"})
+
+	if _, err := c.CreateForumTopic(ctx, "-200", "Bugs", 0, ""); err == nil || !strings.Contains(err.Error(), "not a forum") {
+		t.Fatalf("expected not a forum error, got %v", err)
+	}
+	if _, err := c.CreateForumTopic(ctx, "-100", "Bugs", 0x123456, ""); err == nil {
+		t.Fatal("expected error for an invalid icon color")
+	}
+	topic, err := c.CreateForumTopic(ctx, "-100", "Bugs", telegram.TopicIconColors["red"], "")
+	if err != nil || topic.Name != "Bugs" || topic.MessageThreadID == 0 {
+		t.Fatalf("unexpected topic %+v: %v", topic, err)
+	}
+
+	raw, err := c.SendMessageToThread(ctx, "-100", topic.MessageThreadID, "crash on start")
+	if err != nil {
+		t.Fatalf("SendMessageToThread returned error: %v", err)
+	}
+	var sent telegram.Message
+	if err := json.Unmarshal(raw, &sent); err != nil || sent.MessageThreadID != topic.MessageThreadID || sent.TopicName() != "Bugs" {
+		t.Fatalf("unexpected topic message %s: %v", raw, err)
+	}
+	if _, err := c.SendMessageToThread(ctx, "-100", 999, "lost"); err == nil || !strings.Contains(err.Error(), "message thread not found") {
+		t.Fatalf("expected unknown thread error, got %v", err)
+	}
+
+	if err := c.EditForumTopic(ctx, "-100", topic.MessageThreadID, "Bug reports", nil); err != nil {
+		t.Fatalf("EditForumTopic returned error: %v", err)
+	}
+	if err := c.CloseForumTopic(ctx, "-100", topic.MessageThreadID); err != nil {
+		t.Fatalf("CloseForumTopic returned error: %v", err)
+	}
+	if err := c.CloseForumTopic(ctx, "-100", topic.MessageThreadID); err == nil || !strings.Contains(err.Error(), "TOPIC_NOT_MODIFIED") {
+		t.Fatalf("expected not modified error, got %v", err)
+	}
+	if got := srv.Topics(-100)[topic.MessageThreadID]; got.Name != "Bug reports" || !got.Closed {
+		t.Fatalf("unexpected topic state %+v", got)
+	}
+	if err := c.DeleteForumTopic(ctx, "-100", topic.MessageThreadID); err != nil || len(srv.Topics(-100)) != 0 {
+		t.Fatalf("unexpected delete: %v", err)
+	}
+
+	if err := c.HideGeneralForumTopic(ctx, "-100"); err != nil {
+		t.Fatalf("HideGeneralForumTopic returned error: %v", err)
+	}
+	if err := c.EditGeneralForumTopic(ctx, "-100", "Lobby"); err != nil {
+		t.Fatalf("EditGeneralForumTopic returned error: %v", err)
+	}
+	if got := srv.GeneralTopic(-100); got != (telegramtest.Topic{Name: "Lobby", Closed: true, Hidden: true}) {
+		t.Fatalf("unexpected general topic %+v", got)
+	}
+	stickers, err := c.GetForumTopicIconStickers(ctx)
+	if err != nil || len(stickers) == 0 || stickers[0].CustomEmojiID == "" {
+		t.Fatalf("unexpected icon stickers %+v: %v", stickers, err)
+	}
+}
@@ -0,0 +1,254 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// ChatSettings are the settings of a chat changed through the Bot API.
+type ChatSettings struct {
+	Description string
+	// PhotoFileID is the uploaded file of the chat photo, empty when none.
+	PhotoFileID string
+	Permissions map[string]any
+	// Pinned are the pinned message ids, most recently pinned last.
+	Pinned []int64
+}
+
+// Settings returns the settings of chatID.
+func (h *Handler) Settings(chatID int64) ChatSettings {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.settings[chatID]
+	if !ok {
+		return ChatSettings{}
+	}
+	return ChatSettings{Description: s.Description, PhotoFileID: s.PhotoFileID, Permissions: s.Permissions, Pinned: append([]int64(nil), s.Pinned...)}
+}
+
+func (h *Handler) settingsLocked(chatID int64) *ChatSettings {
+	s := h.settings[chatID]
+	if s == nil {
+		s = &ChatSettings{}
+		h.settings[chatID] = s
+	}
+	return s
+}
+
+// settingsChatLocked returns the group a settings method changes, checking
+// that the bot holds right there. h.mu must be held.
+func (h *Handler) settingsChatLocked(params map[string]string, right string) (Chat, *ChatSettings, *apiError) {
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return Chat{}, nil, apiErr
+	}
+	if chat.Type == "private" {
+		return Chat{}, nil, badRequest("method is available only for supergroups and channel")
+	}
+	if !h.botHasRightLocked(chat.ID, right) {
+		return Chat{}, nil, badRequest("not enough rights to change chat settings")
+	}
+	return chat, h.settingsLocked(chat.ID), nil
+}
+
+// chatInfoLocked is the getChat result: the chat with its settings.
+// h.mu must be held.
+func (h *Handler) chatInfoLocked(chat Chat) any {
+	s, ok := h.settings[chat.ID]
+	if !ok {
+		return chat
+	}
+	raw, _ := json.Marshal(chat)
+	info := map[string]any{}
+	_ = json.Unmarshal(raw, &info)
+	if s.Description != "" {
+		info["description"] = s.Description
+	}
+	if s.PhotoFileID != "" {
+		info["photo"] = map[string]any{
+			"small_file_id": s.PhotoFileID, "small_file_unique_id": s.PhotoFileID,
+			"big_file_id": s.PhotoFileID, "big_file_unique_id": s.PhotoFileID,
+		}
+	}
+	if s.Permissions != nil {
+		info["permissions"] = s.Permissions
+	}
+	if n := len(s.Pinned); n > 0 {
+		if msg := h.messageLocked(chat.ID, s.Pinned[n-1]); msg != nil {
+			info["pinned_message"] = msg
+		}
+	}
+	return info
+}
+
+// messageLocked returns a message of chatID sent or received by the fake.
+// h.mu must be held.
+func (h *Handler) messageLocked(chatID, messageID int64) json.RawMessage {
+	for _, raw := range h.messages {
+		var msg struct {
+			MessageID int64 `json:"message_id"`
+			Chat      Chat  `json:"chat"`
+		}
+		if json.Unmarshal(raw, &msg) == nil && msg.MessageID == messageID && msg.Chat.ID == chatID {
+			return raw
+		}
+	}
+	return nil
+}
+
+func (h *Handler) setChatTitle(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, _, apiErr := h.settingsChatLocked(params, "can_change_info")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if params["title"] == "" {
+		return nil, badRequest("chat title is empty")
+	}
+	chat.Title = params["title"]
+	h.chats[chat.ID] = chat
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) setChatDescription(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, s, apiErr := h.settingsChatLocked(params, "can_change_info")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if s.Description == params["description"] {
+		return nil, badRequest("chat description is not modified")
+	}
+	s.Description = params["description"]
+	h.notifyLocked()
+	return true, nil
+}
+
+// setChatPhoto needs a multipart upload; readParams has already replaced
+// the photo field with the stored file's id.
+func (h *Handler) setChatPhoto(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, s, apiErr := h.settingsChatLocked(params, "can_change_info")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if _, ok := h.files[params["photo"]]; !ok {
+		return nil, badRequest("there is no photo in the request")
+	}
+	s.PhotoFileID = params["photo"]
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) deleteChatPhoto(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, s, apiErr := h.settingsChatLocked(params, "can_change_info")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if s.PhotoFileID == "" {
+		return nil, badRequest("CHAT_NOT_MODIFIED")
+	}
+	s.PhotoFileID = ""
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) setChatPermissions(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, s, apiErr := h.settingsChatLocked(params, "can_restrict_members")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	perms := map[string]any{}
+	if err := json.Unmarshal([]byte(params["permissions"]), &perms); err != nil {
+		return nil, badRequest("can't parse permissions JSON object")
+	}
+	s.Permissions = perms
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) pinChatMessage(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, s, apiErr := h.settingsChatLocked(params, "can_pin_messages")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	id, err := strconv.ParseInt(params["message_id"], 10, 64)
+	if err != nil || h.messageLocked(chat.ID, id) == nil {
+		return nil, badRequest("message to pin not found")
+	}
+	s.Pinned = append(removeID(s.Pinned, id), id)
+	h.notifyLocked()
+	return true, nil
+}
+
+// unpinChatMessage unpins message_id, or the most recent pin without one.
+func (h *Handler) unpinChatMessage(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, s, apiErr := h.settingsChatLocked(params, "can_pin_messages")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	id, err := intParam(params, "message_id", 0)
+	if err != nil {
+		return nil, badRequest(err.Error())
+	}
+	if id == 0 && len(s.Pinned) > 0 {
+		id = s.Pinned[len(s.Pinned)-1]
+	}
+	kept := removeID(s.Pinned, id)
+	if len(kept) == len(s.Pinned) {
+		return nil, badRequest("message to unpin not found")
+	}
+	s.Pinned = kept
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) unpinAllChatMessages(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, s, apiErr := h.settingsChatLocked(params, "can_pin_messages")
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	s.Pinned = nil
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) leaveChat(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	if chat.Type == "private" {
+		return nil, badRequest("chat member status can't be changed in private chats")
+	}
+	h.setMemberLocked(chat.ID, Member{User: h.Bot, Status: "left"})
+	h.notifyLocked()
+	return true, nil
+}
+
+func removeID(ids []int64, id int64) []int64 {
+	out := make([]int64, 0, len(ids))
+	for _, v := range ids {
+		if v != id {
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// State is a serializable snapshot of a Handler's chats, users, messages and
+// pending updates. Uploaded files, chat members and settings, invite links,
+// join requests and forum topics are not included.
+type State struct {
+	NextUpdateID  int64             `json:"next_update_id"`
+	NextMessageID int64             `json:"next_message_id"`
+	Updates       []StoredUpdate    `json:"pending_updates"`
+	Chats         []Chat            `json:"chats"`
+	Users         []User            `json:"users"`
+	Messages      []json.RawMessage `json:"messages"`
+	Webhook       WebhookState      `json:"webhook"`
+}
+
+// StoredUpdate is a pending update in a State.
+type StoredUpdate struct {
+	ID     int64           `json:"update_id"`
+	Update json.RawMessage `json:"update"`
+}
+
+// Snapshot returns a copy of the handler state.
+func (h *Handler) Snapshot() State {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	st := State{
+		NextUpdateID:  h.nextUpdateID,
+		NextMessageID: h.nextMessageID,
+		Updates:       make([]StoredUpdate, 0, len(h.updates)),
+		Chats:         make([]Chat, 0, len(h.chats)),
+		Users:         make([]User, 0, len(h.users)),
+		Messages:      append([]json.RawMessage{}, h.messages...),
+		Webhook:       h.webhook,
+	}
+	for _, u := range h.updates {
+		st.Updates = append(st.Updates, StoredUpdate{ID: u.id, Update: u.raw})
+	}
+	for _, c := range h.chats {
+		st.Chats = append(st.Chats, c)
+	}
+	sort.Slice(st.Chats, func(i, j int) bool { return st.Chats[i].ID < st.Chats[j].ID })
+	for _, u := range h.users {
+		st.Users = append(st.Users, u)
+	}
+	sort.Slice(st.Users, func(i, j int) bool { return st.Users[i].ID < st.Users[j].ID })
+	return st
+}
+
+// Restore replaces the handler state with st.
+func (h *Handler) Restore(st State) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nextUpdateID = max(st.NextUpdateID, 1)
+	h.nextMessageID = max(st.NextMessageID, 1)
+	h.updates = nil
+	for _, u := range st.Updates {
+		h.updates = append(h.updates, pendingUpdate{id: u.ID, raw: u.Update})
+	}
+	h.chats = map[int64]Chat{}
+	h.members = map[int64]map[int64]Member{}
+	h.invites = map[string]*inviteLink{}
+	h.settings = map[int64]*ChatSettings{}
+	h.joinRequests = map[int64]map[int64]User{}
+	h.topics = map[int64]map[int64]*Topic{}
+	h.generalTopics = map[int64]*Topic{}
+	for _, c := range st.Chats {
+		h.chats[c.ID] = c
+	}
+	h.users = map[string]User{}
+	for _, u := range st.Users {
+		h.users[strings.ToLower(u.Username)] = u
+	}
+	h.messages = append([]json.RawMessage{}, st.Messages...)
+	h.webhook = st.Webhook
+	h.allowedUpdates = nil
+	h.notifyLocked()
+}
+
+// Revision increases on every state change; persisting callers compare it
+// to skip unchanged snapshots.
+func (h *Handler) Revision() int64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.revision
+}
+
+// Messages returns every message seen in either direction, oldest first.
+func (h *Handler) Messages() []json.RawMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]json.RawMessage(nil), h.messages...)
+}
+
+// User returns the user with the given username, creating one with a fresh
+// id the first time a name is seen.
+func (h *Handler) User(username string) User {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if u, ok := h.knownUserLocked(username); ok {
+		return u
+	}
+	var maxID int64 = 1000
+	for _, u := range h.users {
+		maxID = max(maxID, u.ID)
+	}
+	return h.newUserLocked(username, maxID+1)
+}
+
+// userNamed returns the user with the given username and id, creating it
+// if the name is new. A known name with another id is an error rather than
+// renumbering that user for every later message.
+func (h *Handler) userNamed(username string, id int64) (User, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if u, ok := h.knownUserLocked(username); ok {
+		if u.ID != id {
+			return User{}, fmt.Errorf("@%s is user %d, not %d", u.Username, u.ID, id)
+		}
+		return u, nil
+	}
+	return h.newUserLocked(username, id), nil
+}
+
+// knownUserLocked looks a username up; an empty one is DefaultUser. h.mu
+// must be held.
+func (h *Handler) knownUserLocked(username string) (User, bool) {
+	key := strings.ToLower(strings.TrimPrefix(username, "@"))
+	if u, ok := h.users[key]; ok {
+		return u, true
+	}
+	if key == "" || key == strings.ToLower(DefaultUser.Username) {
+		return DefaultUser, true
+	}
+	return User{}, false
+}
+
+// newUserLocked records a user named after its username. h.mu must be held.
+func (h *Handler) newUserLocked(username string, id int64) User {
+	name := strings.TrimPrefix(username, "@")
+	first, size := utf8.DecodeRuneInString(name)
+	u := User{ID: id, FirstName: string(unicode.ToUpper(first)) + name[size:], Username: name}
+	h.users[strings.ToLower(name)] = u
+	h.notifyLocked()
+	return u
+}
+
+// userByID returns the known user with id, or a new one without a
+// username, which is therefore not remembered.
+func (h *Handler) userByID(id int64) User {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, u := range h.users {
+		if u.ID == id {
+			return u
+		}
+	}
+	if id == DefaultUser.ID {
+		return DefaultUser
+	}
+	return User{ID: id, FirstName: "User " + strconv.FormatInt(id, 10)}
+}
+
+// rememberLocked records a message and the chat and sender it involves.
+// h.mu must be held.
+func (h *Handler) rememberLocked(chat Chat, from User, msg map[string]any) {
+	if chat.ID != 0 {
+		h.chats[chat.ID] = chat
+	}
+	if from.Username != "" && !from.IsBot {
+		h.users[strings.ToLower(from.Username)] = from
+	}
+	if raw, err := json.Marshal(msg); err == nil {
+		h.messages = append(h.messages, raw)
+	}
+}
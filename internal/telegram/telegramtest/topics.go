@@ -0,0 +1,283 @@
+package telegramtest
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Topic is a forum topic of a chat, or its General topic.
+type Topic struct {
+	Name              string `json:"name"`
+	IconColor         int64  `json:"icon_color"`
+	IconCustomEmojiID string `json:"icon_custom_emoji_id,omitempty"`
+	Closed            bool   `json:"-"`
+	// Hidden is only ever set on the General topic.
+	Hidden bool `json:"-"`
+}
+
+// IconStickers are the stickers getForumTopicIconStickers returns.
+var IconStickers = []map[string]any{
+	{"file_id": "icon-1", "file_unique_id": "icon-u1", "type": "custom_emoji", "emoji": "💬", "custom_emoji_id": "5417915203100613993"},
+	{"file_id": "icon-2", "file_unique_id": "icon-u2", "type": "custom_emoji", "emoji": "🐞", "custom_emoji_id": "5312536423851630001"},
+}
+
+var topicIconColors = map[int64]bool{0x6FB9F0: true, 0xFFD67E: true, 0xCB86DB: true, 0x8EEE98: true, 0xFF93B2: true, 0xFB6F5F: true}
+
+// Topics returns the open and closed topics of chatID by message_thread_id.
+func (h *Handler) Topics(chatID int64) map[int64]Topic {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := map[int64]Topic{}
+	for id, t := range h.topics[chatID] {
+		out[id] = *t
+	}
+	return out
+}
+
+// GeneralTopic returns the General topic of chatID.
+func (h *Handler) GeneralTopic(chatID int64) Topic {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if t, ok := h.generalTopics[chatID]; ok {
+		return *t
+	}
+	return Topic{Name: "General"}
+}
+
+// forumChatLocked returns the forum addressed by params, checking that the
+// bot may manage its topics. h.mu must be held.
+func (h *Handler) forumChatLocked(params map[string]string) (Chat, *apiError) {
+	chat, apiErr := h.knownChatLocked(params)
+	if apiErr != nil {
+		return Chat{}, apiErr
+	}
+	if !chat.IsForum {
+		return Chat{}, badRequest("the chat is not a forum")
+	}
+	if !h.botHasRightLocked(chat.ID, "can_manage_topics") {
+		return Chat{}, badRequest("not enough rights to manage topics")
+	}
+	return chat, nil
+}
+
+// topicLocked returns the topic addressed by params. h.mu must be held.
+func (h *Handler) topicLocked(params map[string]string) (Chat, int64, *Topic, *apiError) {
+	chat, apiErr := h.forumChatLocked(params)
+	if apiErr != nil {
+		return Chat{}, 0, nil, apiErr
+	}
+	id, err := strconv.ParseInt(params["message_thread_id"], 10, 64)
+	topic := h.topics[chat.ID][id]
+	if err != nil || topic == nil {
+		return Chat{}, 0, nil, badRequest("TOPIC_ID_INVALID")
+	}
+	return chat, id, topic, nil
+}
+
+func (h *Handler) generalTopicLocked(params map[string]string) (*Topic, *apiError) {
+	chat, apiErr := h.forumChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	t := h.generalTopics[chat.ID]
+	if t == nil {
+		t = &Topic{Name: "General"}
+		h.generalTopics[chat.ID] = t
+	}
+	return t, nil
+}
+
+// serviceMessageLocked records a service message of the bot in a topic.
+// h.mu must be held.
+func (h *Handler) serviceMessageLocked(chat Chat, threadID int64, field string, value any) map[string]any {
+	id := h.nextMessageID
+	h.nextMessageID++
+	if threadID == 0 {
+		threadID = id
+	}
+	msg := map[string]any{
+		"message_id":        id,
+		"message_thread_id": threadID,
+		"from":              h.Bot,
+		"chat":              chat,
+		"date":              h.Now().Unix(),
+		"is_topic_message":  true,
+		field:               value,
+	}
+	h.rememberLocked(chat, h.Bot, msg)
+	return msg
+}
+
+// threadLocked adds message_thread_id to a message sent to threadID. In a
+// forum the topic must exist, and the message replies to the topic's
+// creation message like Telegram's. h.mu must be held.
+func (h *Handler) threadLocked(chat Chat, threadID int64, msg map[string]any) *apiError {
+	if threadID == 0 {
+		return nil
+	}
+	msg["message_thread_id"] = threadID
+	if !chat.IsForum {
+		return nil
+	}
+	if h.topics[chat.ID][threadID] == nil {
+		return badRequest("message thread not found")
+	}
+	msg["is_topic_message"] = true
+	if created := h.messageLocked(chat.ID, threadID); created != nil {
+		msg["reply_to_message"] = created
+	}
+	return nil
+}
+
+func (h *Handler) createForumTopic(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, apiErr := h.forumChatLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	name := params["name"]
+	if name == "" || len([]rune(name)) > 128 {
+		return nil, badRequest("TOPIC_NAME_INVALID")
+	}
+	color, err := intParam(params, "icon_color", 0x6FB9F0)
+	if err != nil || !topicIconColors[color] {
+		return nil, badRequest("TOPIC_ICON_COLOR_INVALID")
+	}
+	topic := &Topic{Name: name, IconColor: color, IconCustomEmojiID: params["icon_custom_emoji_id"]}
+	msg := h.serviceMessageLocked(chat, 0, "forum_topic_created", topic)
+	id := msg["message_id"].(int64)
+	if h.topics[chat.ID] == nil {
+		h.topics[chat.ID] = map[int64]*Topic{}
+	}
+	h.topics[chat.ID][id] = topic
+	h.notifyLocked()
+	return map[string]any{
+		"message_thread_id":    id,
+		"name":                 topic.Name,
+		"icon_color":           topic.IconColor,
+		"icon_custom_emoji_id": topic.IconCustomEmojiID,
+	}, nil
+}
+
+func (h *Handler) editForumTopic(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, id, topic, apiErr := h.topicLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	name, hasName := params["name"]
+	icon, hasIcon := params["icon_custom_emoji_id"]
+	if (!hasName || name == topic.Name) && (!hasIcon || icon == topic.IconCustomEmojiID) {
+		return nil, badRequest("TOPIC_NOT_MODIFIED")
+	}
+	edited := map[string]any{}
+	if hasName {
+		topic.Name = name
+		edited["name"] = name
+	}
+	if hasIcon {
+		topic.IconCustomEmojiID = icon
+		edited["icon_custom_emoji_id"] = icon
+	}
+	h.serviceMessageLocked(chat, id, "forum_topic_edited", edited)
+	h.notifyLocked()
+	return true, nil
+}
+
+// setTopicClosed handles closeForumTopic and reopenForumTopic.
+func (h *Handler) setTopicClosed(_ *http.Request, method string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, id, topic, apiErr := h.topicLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	closed := method == "closeForumTopic"
+	if topic.Closed == closed {
+		return nil, badRequest("TOPIC_NOT_MODIFIED")
+	}
+	topic.Closed = closed
+	field := "forum_topic_reopened"
+	if closed {
+		field = "forum_topic_closed"
+	}
+	h.serviceMessageLocked(chat, id, field, map[string]any{})
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) deleteForumTopic(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	chat, id, _, apiErr := h.topicLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	delete(h.topics[chat.ID], id)
+	kept := h.messages[:0]
+	for _, raw := range h.messages {
+		var msg struct {
+			ThreadID int64 `json:"message_thread_id"`
+			Chat     Chat  `json:"chat"`
+		}
+		if json.Unmarshal(raw, &msg) == nil && msg.Chat.ID == chat.ID && msg.ThreadID == id {
+			continue
+		}
+		kept = append(kept, raw)
+	}
+	h.messages = kept
+	h.notifyLocked()
+	return true, nil
+}
+
+func (h *Handler) getForumTopicIconStickers(*http.Request, string, map[string]string) (any, *apiError) {
+	return IconStickers, nil
+}
+
+func (h *Handler) editGeneralForumTopic(_ *http.Request, _ string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	topic, apiErr := h.generalTopicLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	switch params["name"] {
+	case "":
+		return nil, badRequest("TOPIC_NAME_INVALID")
+	case topic.Name:
+		return nil, badRequest("TOPIC_NOT_MODIFIED")
+	}
+	topic.Name = params["name"]
+	h.notifyLocked()
+	return true, nil
+}
+
+// setGeneralTopicState handles closing, reopening, hiding and unhiding the
+// General topic. Hiding also closes it; unhiding leaves it closed.
+func (h *Handler) setGeneralTopicState(_ *http.Request, method string, params map[string]string) (any, *apiError) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	topic, apiErr := h.generalTopicLocked(params)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	before := *topic
+	switch method {
+	case "closeGeneralForumTopic":
+		topic.Closed = true
+	case "reopenGeneralForumTopic":
+		topic.Closed, topic.Hidden = false, false
+	case "hideGeneralForumTopic":
+		topic.Closed, topic.Hidden = true, true
+	case "unhideGeneralForumTopic":
+		topic.Hidden = false
+	}
+	if *topic == before {
+		return nil, badRequest("TOPIC_NOT_MODIFIED")
+	}
+	h.notifyLocked()
+	return true, nil
+}
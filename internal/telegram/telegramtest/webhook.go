@@ -0,0 +1,103 @@
+package telegramtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SecretTokenHeader carries setWebhook's secret_token on webhook requests.
+const SecretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+type webhookError struct {
+	date    int64
+	message string
+}
+
+// RunWebhookDelivery posts pending updates to the configured webhook in
+// order, one at a time, until ctx is done. A 2xx response confirms the
+// update; any other outcome is reported by getWebhookInfo and retried after
+// retryDelay. A response body naming a "method" is executed as a Bot API
+// call, like Telegram does with webhook replies.
+func (h *Handler) RunWebhookDelivery(ctx context.Context, client *http.Client, retryDelay time.Duration) error {
+	for {
+		h.mu.Lock()
+		hook := h.webhook
+		var next *pendingUpdate
+		if hook.URL != "" && len(h.updates) > 0 {
+			u := h.updates[0]
+			next = &u
+		}
+		changed := h.changed
+		h.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-changed:
+			}
+			continue
+		}
+
+		if err := h.deliver(ctx, client, hook, *next); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			h.mu.Lock()
+			h.webhookError = webhookError{date: h.Now().Unix(), message: err.Error()}
+			h.mu.Unlock()
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(retryDelay):
+			}
+			continue
+		}
+
+		h.mu.Lock()
+		h.confirmLocked(next.id + 1)
+		h.webhookError = webhookError{}
+		h.mu.Unlock()
+	}
+}
+
+func (h *Handler) deliver(ctx context.Context, client *http.Client, hook WebhookState, u pendingUpdate) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(u.raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if hook.SecretToken != "" {
+		req.Header.Set(SecretTokenHeader, hook.SecretToken)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Connection failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("Wrong response from the webhook: %s", resp.Status)
+	}
+	h.executeReply(req, body)
+	return nil
+}
+
+// executeReply runs the Bot API method a webhook answered with, if any.
+func (h *Handler) executeReply(req *http.Request, body []byte) {
+	if !strings.HasPrefix(strings.TrimSpace(string(body)), "{") {
+		return
+	}
+	params, err := jsonParams(body)
+	if err != nil || params["method"] == "" {
+		return
+	}
+	method := params["method"]
+	delete(params, "method")
+	_, _ = h.dispatch(req, method, params)
+}
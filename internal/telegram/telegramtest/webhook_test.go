@@ -0,0 +1,127 @@
+package telegramtest_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	var failures atomic.Int32
+	failures.Store(1)
+	var gotSecret atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures.Add(-1) >= 0 {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		gotSecret.Store(r.Header.Get(telegramtest.SecretTokenHeader))
+		body, _ := io.ReadAll(r.Body)
+		var update struct {
+			Message struct {
+				Chat struct{ ID int64 } `json:"chat"`
+				Text string             `json:"text"`
+			} `json:"message"`
+		}
+		_ = json.Unmarshal(body, &update)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"method": "sendMessage", "chat_id": update.Message.Chat.ID, "text": "echo: " + update.Message.Text,
+		})
+	}))
+	defer backend.Close()
+
+	h := telegramtest.NewHandler("")
+	h.SetWebhook(telegramtest.WebhookState{URL: backend.URL, SecretToken: "s3cret"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = h.RunWebhookDelivery(ctx, backend.Client(), 20*time.Millisecond) }()
+
+	h.EnqueueText("hi")
+	waitFor(t, func() bool { return len(h.SentMessages()) == 1 })
+
+	sent := h.SentMessages()[0]
+	if sent.ChatID != "42" || sent.Text != "echo: hi" {
+		t.Fatalf("unexpected webhook reply: %+v", sent)
+	}
+	if gotSecret.Load() != "s3cret" {
+		t.Fatalf("expected secret token header, got %v", gotSecret.Load())
+	}
+	if len(h.PendingUpdates()) != 0 {
+		t.Fatalf("delivered update should be confirmed")
+	}
+}
+
+func TestInjectAndSnapshot(t *testing.T) {
+	h := telegramtest.NewHandler("")
+	admin := httptest.NewServer(h.AdminHandler())
+	defer admin.Close()
+
+	resp, err := http.Post(admin.URL+telegramtest.AdminPrefix+"inject", "application/json",
+		strings.NewReader(`{"text":"hello","from":"bob","chat_id":-100500,"chat_type":"supergroup","chat_title":"Devs"}`))
+	if err != nil {
+		t.Fatalf("inject: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("inject status %s", resp.Status)
+	}
+
+	st := h.Snapshot()
+	if len(st.Updates) != 1 || len(st.Chats) != 1 || st.Chats[0].Title != "Devs" {
+		t.Fatalf("unexpected snapshot: %+v", st)
+	}
+	if len(st.Users) != 1 || st.Users[0].Username != "bob" {
+		t.Fatalf("expected bob to be created, got %+v", st.Users)
+	}
+
+	restored := telegramtest.NewHandler("")
+	restored.Restore(st)
+	if bob := restored.User("bob"); bob.ID != st.Users[0].ID {
+		t.Fatalf("restored user id mismatch: %+v", bob)
+	}
+	if len(restored.PendingUpdates()) != 1 || len(restored.Messages()) != 1 {
+		t.Fatalf("restore lost updates or messages")
+	}
+
+	if _, err := h.Inject(telegramtest.InjectRequest{Text: "hi", FromID: 7}); err != nil {
+		t.Fatalf("Inject returned error: %v", err)
+	}
+	if alice := h.User("alice"); alice != telegramtest.DefaultUser {
+		t.Fatalf("--from-id alone must not renumber the default user, got %+v", alice)
+	}
+	if _, err := h.Inject(telegramtest.InjectRequest{Text: "hi", From: "alice", FromID: 99}); err == nil {
+		t.Fatalf("Inject renumbering alice did not fail")
+	}
+	if alice := h.User("alice"); alice.ID != telegramtest.DefaultUser.ID {
+		t.Fatalf("--from alice --from-id 99 changed alice's id to %d", alice.ID)
+	}
+	if _, err := h.Inject(telegramtest.InjectRequest{Text: "hi", From: "carol", FromID: 99}); err != nil {
+		t.Fatalf("Inject returned error: %v", err)
+	}
+	if carol := h.User("carol"); carol.ID != 99 {
+		t.Fatalf("new user carol got id %d, want 99", carol.ID)
+	}
+	if elodie := h.User("élodie"); elodie.FirstName != "Élodie" {
+		t.Fatalf("unexpected first name %q", elodie.FirstName)
+	}
+}
@@ -0,0 +1,97 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultRequestTimeout  = 30 * time.Second
+	DefaultUploadTimeout   = 10 * time.Minute
+	DefaultDownloadTimeout = 30 * time.Minute
+	DefaultLongPollMargin  = 15 * time.Second
+)
+
+// Timeouts bounds each API request by what the method is expected to take,
+// instead of one client-wide timeout that is either too short for long
+// polling or too long for a hung getMe.
+type Timeouts struct {
+	// Request bounds ordinary calls.
+	Request time.Duration
+	// Upload bounds multipart calls; it is never shorter than Request.
+	Upload time.Duration
+	// Download bounds file downloads; it is never shorter than Request.
+	Download time.Duration
+	// LongPollMargin is added to the getUpdates long-poll timeout so the
+	// server can answer an empty poll before the client gives up.
+	LongPollMargin time.Duration
+}
+
+func DefaultTimeouts() Timeouts {
+	return Timeouts{
+		Request:        DefaultRequestTimeout,
+		Upload:         DefaultUploadTimeout,
+		Download:       DefaultDownloadTimeout,
+		LongPollMargin: DefaultLongPollMargin,
+	}
+}
+
+// withDefaults fills unset fields from DefaultTimeouts.
+func (t Timeouts) withDefaults() Timeouts {
+	def := DefaultTimeouts()
+	if t.Request <= 0 {
+		t.Request = def.Request
+	}
+	if t.Upload <= 0 {
+		t.Upload = def.Upload
+	}
+	if t.Upload < t.Request {
+		t.Upload = t.Request
+	}
+	if t.Download <= 0 {
+		t.Download = def.Download
+	}
+	if t.Download < t.Request {
+		t.Download = t.Request
+	}
+	if t.LongPollMargin <= 0 {
+		t.LongPollMargin = def.LongPollMargin
+	}
+	return t
+}
+
+// RequestTimeout returns the deadline applied to a non-upload call of
+// method. longPollSec is only used for getUpdates.
+func (c *Client) RequestTimeout(method string, longPollSec int) time.Duration {
+	if method == "getUpdates" {
+		return time.Duration(longPollSec)*time.Second + c.timeouts.LongPollMargin
+	}
+	return c.timeouts.Request
+}
+
+// UploadTimeout returns the deadline applied to multipart calls.
+func (c *Client) UploadTimeout() time.Duration {
+	return c.timeouts.Upload
+}
+
+// DownloadTimeout returns the deadline applied to file downloads.
+func (c *Client) DownloadTimeout() time.Duration {
+	return c.timeouts.Download
+}
+
+// doWithTimeout sends req under a per-request deadline and turns a deadline
+// hit into an error naming the method and the limit.
+func (c *Client) doWithTimeout(req *http.Request, method string, timeout time.Duration) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	defer cancel()
+
+	res, err := c.do(req.WithContext(ctx), method)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && req.Context().Err() == nil {
+		return nil, fmt.Errorf("%s: no response within %s: %w", method, timeout, err)
+	}
+	return res, err
+}
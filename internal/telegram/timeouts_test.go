@@ -0,0 +1,71 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRequestTimeoutPerMethod(t *testing.T) {
+	c, err := NewClientWithOptions(ClientOptions{APIBase: "http://x", Token: "t", Timeouts: Timeouts{Request: 5 * time.Second}})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if got := c.RequestTimeout("getMe", 0); got != 5*time.Second {
+		t.Fatalf("expected 5s for getMe, got %s", got)
+	}
+	if got := c.RequestTimeout("getUpdates", 90); got != 90*time.Second+DefaultLongPollMargin {
+		t.Fatalf("expected long-poll timeout plus margin, got %s", got)
+	}
+	if got := c.UploadTimeout(); got != DefaultUploadTimeout {
+		t.Fatalf("expected default upload timeout, got %s", got)
+	}
+	if got := c.DownloadTimeout(); got != DefaultDownloadTimeout {
+		t.Fatalf("expected default download timeout, got %s", got)
+	}
+
+	long, err := NewClientWithOptions(ClientOptions{APIBase: "http://x", Token: "t", Timeouts: Timeouts{Request: time.Hour}})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if got := long.UploadTimeout(); got != time.Hour {
+		t.Fatalf("upload timeout should never be shorter than request timeout, got %s", got)
+	}
+	if got := long.DownloadTimeout(); got != time.Hour {
+		t.Fatalf("download timeout should never be shorter than request timeout, got %s", got)
+	}
+}
+
+func TestRequestDeadlineExceeded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	}))
+	defer srv.Close()
+
+	c, err := NewClientWithOptions(ClientOptions{
+		APIBase:  srv.URL,
+		Token:    testToken,
+		Timeouts: Timeouts{Request: 50 * time.Millisecond, LongPollMargin: 50 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+
+	_, err = c.GetMe(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "getMe: no response within 50ms") {
+		t.Fatalf("expected deadline error for getMe, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded in chain, got %v", err)
+	}
+	if _, err := c.GetUpdates(context.Background(), 0, 0); err == nil || !strings.Contains(err.Error(), "getUpdates: no response within") {
+		t.Fatalf("expected deadline error for getUpdates, got %v", err)
+	}
+}
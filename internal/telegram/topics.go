@@ -0,0 +1,162 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ForumTopic is a topic of a forum supergroup.
+type ForumTopic struct {
+	MessageThreadID   int64  `json:"message_thread_id"`
+	Name              string `json:"name"`
+	IconColor         int    `json:"icon_color"`
+	IconCustomEmojiID string `json:"icon_custom_emoji_id,omitempty"`
+}
+
+// ForumTopicCreated is the service message starting a topic; its message id
+// is the topic's message_thread_id.
+type ForumTopicCreated struct {
+	Name              string `json:"name"`
+	IconColor         int    `json:"icon_color"`
+	IconCustomEmojiID string `json:"icon_custom_emoji_id,omitempty"`
+}
+
+// ForumTopicEdited is the service message of a renamed topic or a changed
+// icon. Name is empty when the name did not change.
+type ForumTopicEdited struct {
+	Name              string  `json:"name,omitempty"`
+	IconCustomEmojiID *string `json:"icon_custom_emoji_id,omitempty"`
+}
+
+// Sticker is the subset of a sticker used for topic icons.
+type Sticker struct {
+	FileID        string `json:"file_id"`
+	FileUniqueID  string `json:"file_unique_id"`
+	Type          string `json:"type"`
+	Emoji         string `json:"emoji,omitempty"`
+	CustomEmojiID string `json:"custom_emoji_id,omitempty"`
+}
+
+// TopicIconColors are the colors createForumTopic accepts, by the names the
+// Telegram apps use.
+var TopicIconColors = map[string]int{
+	"blue":   0x6FB9F0,
+	"yellow": 0xFFD67E,
+	"violet": 0xCB86DB,
+	"green":  0x8EEE98,
+	"rose":   0xFF93B2,
+	"red":    0xFB6F5F,
+}
+
+// CreateForumTopic creates a topic. A zero iconColor and an empty
+// iconCustomEmojiID leave the choice to Telegram.
+func (c *Client) CreateForumTopic(ctx context.Context, chatID, name string, iconColor int, iconCustomEmojiID string) (*ForumTopic, error) {
+	params := map[string]any{"chat_id": chatID, "name": name}
+	if iconColor != 0 {
+		params["icon_color"] = iconColor
+	}
+	if iconCustomEmojiID != "" {
+		params["icon_custom_emoji_id"] = iconCustomEmojiID
+	}
+	res, err := c.call(ctx, "createForumTopic", params)
+	if err != nil {
+		return nil, err
+	}
+	var topic ForumTopic
+	if err := json.Unmarshal(res, &topic); err != nil {
+		return nil, fmt.Errorf("decode createForumTopic result: %w", err)
+	}
+	return &topic, nil
+}
+
+// EditForumTopic renames a topic or changes its icon. An empty name keeps
+// the name, a nil iconCustomEmojiID keeps the icon and an empty one removes
+// it.
+func (c *Client) EditForumTopic(ctx context.Context, chatID string, threadID int64, name string, iconCustomEmojiID *string) error {
+	params := map[string]any{"chat_id": chatID, "message_thread_id": threadID}
+	if name != "" {
+		params["name"] = name
+	}
+	if iconCustomEmojiID != nil {
+		params["icon_custom_emoji_id"] = *iconCustomEmojiID
+	}
+	_, err := c.call(ctx, "editForumTopic", params)
+	return err
+}
+
+func (c *Client) CloseForumTopic(ctx context.Context, chatID string, threadID int64) error {
+	return c.topicCall(ctx, "closeForumTopic", chatID, threadID)
+}
+
+func (c *Client) ReopenForumTopic(ctx context.Context, chatID string, threadID int64) error {
+	return c.topicCall(ctx, "reopenForumTopic", chatID, threadID)
+}
+
+// DeleteForumTopic deletes a topic along with all its messages.
+func (c *Client) DeleteForumTopic(ctx context.Context, chatID string, threadID int64) error {
+	return c.topicCall(ctx, "deleteForumTopic", chatID, threadID)
+}
+
+func (c *Client) topicCall(ctx context.Context, method, chatID string, threadID int64) error {
+	_, err := c.call(ctx, method, map[string]any{"chat_id": chatID, "message_thread_id": threadID})
+	return err
+}
+
+// GetForumTopicIconStickers returns the custom emoji any user may use as a
+// topic icon.
+func (c *Client) GetForumTopicIconStickers(ctx context.Context) ([]Sticker, error) {
+	res, err := c.call(ctx, "getForumTopicIconStickers", nil)
+	if err != nil {
+		return nil, err
+	}
+	var stickers []Sticker
+	if err := json.Unmarshal(res, &stickers); err != nil {
+		return nil, fmt.Errorf("decode getForumTopicIconStickers result: %w", err)
+	}
+	return stickers, nil
+}
+
+func (c *Client) EditGeneralForumTopic(ctx context.Context, chatID, name string) error {
+	_, err := c.call(ctx, "editGeneralForumTopic", map[string]any{"chat_id": chatID, "name": name})
+	return err
+}
+
+func (c *Client) CloseGeneralForumTopic(ctx context.Context, chatID string) error {
+	return c.generalTopicCall(ctx, "closeGeneralForumTopic", chatID)
+}
+
+func (c *Client) ReopenGeneralForumTopic(ctx context.Context, chatID string) error {
+	return c.generalTopicCall(ctx, "reopenGeneralForumTopic", chatID)
+}
+
+// HideGeneralForumTopic hides the General topic, closing it if it is open.
+func (c *Client) HideGeneralForumTopic(ctx context.Context, chatID string) error {
+	return c.generalTopicCall(ctx, "hideGeneralForumTopic", chatID)
+}
+
+func (c *Client) UnhideGeneralForumTopic(ctx context.Context, chatID string) error {
+	return c.generalTopicCall(ctx, "unhideGeneralForumTopic", chatID)
+}
+
+func (c *Client) generalTopicCall(ctx context.Context, method, chatID string) error {
+	_, err := c.call(ctx, method, map[string]any{"chat_id": chatID})
+	return err
+}
+
+// TopicName returns the name of the forum topic m belongs to when m itself
+// tells: the topic's creation or rename, or a topic message without an
+// explicit reply, which Telegram marks as replying to the creation message.
+func (m *Message) TopicName() string {
+	switch {
+	case m == nil:
+		return ""
+	case m.ForumTopicCreated != nil:
+		return m.ForumTopicCreated.Name
+	case m.ForumTopicEdited != nil && m.ForumTopicEdited.Name != "":
+		return m.ForumTopicEdited.Name
+	case m.IsTopicMessage && m.ReplyToMessage != nil && m.ReplyToMessage.ForumTopicCreated != nil:
+		return m.ReplyToMessage.ForumTopicCreated.Name
+	}
+	return ""
+}
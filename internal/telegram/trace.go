@@ -0,0 +1,213 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WrapTransport installs a round-tripper around the client's current
+// transport. Wrappers compose: the last one installed sees requests first.
+func (c *Client) WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) {
+	next := c.http.Transport
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	c.http.Transport = wrap(next)
+}
+
+// exchange is one request/response pair captured by a tracing transport.
+// Bodies are buffered so the wrapped transport and the caller still see them,
+// except for multipart uploads, which stream through counted, and file
+// downloads, whose body streams through untouched and elapsed only covers
+// the response headers.
+type exchange struct {
+	started  time.Time
+	elapsed  time.Duration
+	req      *http.Request
+	reqBody  []byte
+	upload   *countingReader
+	resp     *http.Response
+	respBody []byte
+	download bool
+	err      error
+}
+
+// countingReader counts the bytes of a request body the wrapped transport
+// reads, which it may do from another goroutine.
+type countingReader struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// isMultipart reports whether req uploads files as a multipart form.
+func isMultipart(req *http.Request) bool {
+	return strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/")
+}
+
+// isFileDownload reports whether req fetches a file body from the
+// /file/bot<token>/ endpoint rather than calling a Bot API method.
+func isFileDownload(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, "/file/")
+}
+
+func roundTripCaptured(next http.RoundTripper, req *http.Request) (*http.Response, *exchange, error) {
+	ex := &exchange{started: time.Now(), req: req}
+	if req.Body != nil && req.Body != http.NoBody && isMultipart(req) {
+		ex.upload = &countingReader{ReadCloser: req.Body}
+		req.Body = ex.upload
+	} else if req.Body != nil && req.Body != http.NoBody {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+		ex.reqBody = body
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		ex.elapsed = time.Since(ex.started)
+		ex.err = err
+		return nil, ex, err
+	}
+	if isFileDownload(req) {
+		ex.elapsed = time.Since(ex.started)
+		ex.resp = resp
+		ex.download = true
+		return resp, ex, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	ex.elapsed = time.Since(ex.started)
+	if err != nil {
+		ex.err = err
+		return nil, ex, err
+	}
+	ex.resp = resp
+	ex.respBody = body
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, ex, nil
+}
+
+// apiMethod extracts the Bot API method name from a request URL.
+func apiMethod(req *http.Request) string {
+	return path.Base(req.URL.Path)
+}
+
+// hasRequestBody reports whether the request sent a body.
+func (ex *exchange) hasRequestBody() bool {
+	return ex.upload != nil || len(ex.reqBody) > 0
+}
+
+// requestBodySize is the size of the request body, as far as it was sent
+// for multipart uploads.
+func (ex *exchange) requestBodySize() int {
+	if ex.upload != nil {
+		return int(ex.upload.n.Load())
+	}
+	return len(ex.reqBody)
+}
+
+// requestParams renders request parameters for logging: the query string for
+// GET requests, the size of multipart uploads and the body otherwise.
+func (ex *exchange) requestParams() string {
+	switch {
+	case ex.upload != nil:
+		return fmt.Sprintf("[multipart body, %d bytes]", ex.requestBodySize())
+	case len(ex.reqBody) == 0:
+		return ex.req.URL.RawQuery
+	}
+	return string(ex.reqBody)
+}
+
+// responseBody renders the response body for logging; file downloads are
+// shown by size only.
+func (ex *exchange) responseBody() string {
+	if !ex.download {
+		return string(bytes.TrimSpace(ex.respBody))
+	}
+	if ex.resp.ContentLength < 0 {
+		return "[file body, size unknown]"
+	}
+	return fmt.Sprintf("[file body, %d bytes]", ex.resp.ContentLength)
+}
+
+// DebugTransport logs every Bot API exchange to a writer with the token
+// redacted: method, params, status, latency and response body.
+type DebugTransport struct {
+	next     http.RoundTripper
+	out      io.Writer
+	redactor *Redactor
+	mu       sync.Mutex
+}
+
+func NewDebugTransport(next http.RoundTripper, out io.Writer, redactor *Redactor) *DebugTransport {
+	return &DebugTransport{next: next, out: out, redactor: redactor}
+}
+
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, ex, err := roundTripCaptured(t.next, req)
+	if ex == nil {
+		return resp, err
+	}
+
+	var line string
+	if ex.err != nil {
+		line = fmt.Sprintf("[debug] %s %s params=%s -> error after %s: %v",
+			req.Method, apiMethod(req), ex.requestParams(), ex.elapsed.Round(time.Millisecond), ex.err)
+	} else {
+		line = fmt.Sprintf("[debug] %s %s params=%s -> %s (%s) body=%s",
+			req.Method, apiMethod(req), ex.requestParams(), ex.resp.Status, ex.elapsed.Round(time.Millisecond), ex.responseBody())
+	}
+
+	t.mu.Lock()
+	_, _ = fmt.Fprintln(t.out, t.redactor.String(line))
+	t.mu.Unlock()
+	return resp, err
+}
+
+// ResultTransport passes the result of every successful Bot API call to a
+// callback, e.g. to archive sent messages. Unlike the tracing transports it
+// only buffers responses, so large uploads are not held in memory.
+type ResultTransport struct {
+	next     http.RoundTripper
+	onResult func(method string, result json.RawMessage)
+}
+
+func NewResultTransport(next http.RoundTripper, onResult func(method string, result json.RawMessage)) *ResultTransport {
+	return &ResultTransport{next: next, onResult: onResult}
+}
+
+func (t *ResultTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK || isFileDownload(req) {
+		return resp, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var envelope apiResponse
+	if json.Unmarshal(body, &envelope) == nil && envelope.OK {
+		t.onResult(apiMethod(req), envelope.Result)
+	}
+	return resp, nil
+}
@@ -0,0 +1,218 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/getUpdates") {
+			_, _ = w.Write([]byte(`{"ok":true,"result":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"path":"` + r.URL.Path + `"}}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDebugTransportLogsRedacted(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient(srv.URL, testToken)
+	var log strings.Builder
+	c.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		return NewDebugTransport(next, &log, c.Redactor())
+	})
+
+	res, err := c.SendMessage(context.Background(), "42", "hello")
+	if err != nil {
+		t.Fatalf("SendMessage returned error: %v", err)
+	}
+	if !strings.Contains(string(res), testToken) {
+		t.Fatalf("caller should still receive the untouched body, got %s", res)
+	}
+
+	out := log.String()
+	if strings.Contains(out, testToken) {
+		t.Fatalf("token leaked into debug log: %s", out)
+	}
+	for _, want := range []string{"POST sendMessage", `"chat_id":"42"`, "200 OK", `"ok":true`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("debug log missing %q: %s", want, out)
+		}
+	}
+}
+
+func TestHARRecorderWritesEntries(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient(srv.URL, testToken)
+	harPath := filepath.Join(t.TempDir(), "out.har")
+	c.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		rec, err := NewHARRecorder(next, harPath, c.Redactor())
+		if err != nil {
+			t.Fatalf("NewHARRecorder returned error: %v", err)
+		}
+		return rec
+	})
+
+	if _, err := c.GetMe(context.Background()); err != nil {
+		t.Fatalf("GetMe returned error: %v", err)
+	}
+	if _, err := c.GetUpdatesWithLimit(context.Background(), 5, 0, 10); err != nil {
+		t.Fatalf("GetUpdates returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatalf("read har: %v", err)
+	}
+	if strings.Contains(string(data), testToken) {
+		t.Fatalf("token leaked into har file")
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("har is not valid json: %v", err)
+	}
+	if len(har.Log.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(har.Log.Entries))
+	}
+	if har.Log.Creator.Version != buildVersion() || har.Log.Creator.Version == "" {
+		t.Fatalf("unexpected creator %+v", har.Log.Creator)
+	}
+	getMe := har.Log.Entries[0]
+	if getMe.Response.Status != http.StatusOK || !strings.Contains(getMe.Response.Content.Text, "[REDACTED]") {
+		t.Fatalf("unexpected getMe response entry: %+v", getMe.Response)
+	}
+	updates := har.Log.Entries[1]
+	if updates.Request.Method != http.MethodGet || len(updates.Request.QueryString) != 3 {
+		t.Fatalf("unexpected getUpdates request entry: %+v", updates.Request)
+	}
+}
+
+func TestTracingTransportsSkipFileBodies(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		_, _ = w.Write([]byte("jpeg-bytes"))
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL, testToken)
+	var log strings.Builder
+	harPath := filepath.Join(t.TempDir(), "out.har")
+	c.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		rec, err := NewHARRecorder(next, harPath, c.Redactor())
+		if err != nil {
+			t.Fatalf("NewHARRecorder returned error: %v", err)
+		}
+		return NewDebugTransport(rec, &log, c.Redactor())
+	})
+
+	var buf strings.Builder
+	if _, err := c.DownloadFile(context.Background(), "photos/file_1.jpg", &buf); err != nil || buf.String() != "jpeg-bytes" {
+		t.Fatalf("DownloadFile = %q, %v", buf.String(), err)
+	}
+	if out := log.String(); strings.Contains(out, "jpeg-bytes") || !strings.Contains(out, "[file body, 10 bytes]") {
+		t.Fatalf("debug log should show the size only: %s", out)
+	}
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatalf("read har: %v", err)
+	}
+	if strings.Contains(string(data), "jpeg-bytes") {
+		t.Fatalf("file body recorded in har: %s", data)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil || len(har.Log.Entries) != 1 || har.Log.Entries[0].Response.Content.Size != 10 {
+		t.Fatalf("unexpected har %s: %v", data, err)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTracingTransportsStreamUploads(t *testing.T) {
+	var received int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		_, _ = w.Write([]byte(`{"ok":true,"result":true}`))
+	}))
+	defer srv.Close()
+	doc := filepath.Join(t.TempDir(), "report.txt")
+	if err := os.WriteFile(doc, []byte(strings.Repeat("report body ", 1000)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(srv.URL, testToken)
+	var log strings.Builder
+	harPath := filepath.Join(t.TempDir(), "out.har")
+	var uploads []*countingReader
+	c.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		rec, err := NewHARRecorder(next, harPath, c.Redactor())
+		if err != nil {
+			t.Fatalf("NewHARRecorder returned error: %v", err)
+		}
+		debug := NewDebugTransport(rec, &log, c.Redactor())
+		return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := debug.RoundTrip(req)
+			if cr, ok := req.Body.(*countingReader); ok {
+				uploads = append(uploads, cr)
+			}
+			return resp, err
+		})
+	})
+
+	if _, err := c.CallWithFiles(context.Background(), "sendDocument", map[string]any{"chat_id": 1}, []UploadFile{{Field: "document", Path: doc}}); err != nil {
+		t.Fatalf("CallWithFiles returned error: %v", err)
+	}
+	if len(uploads) != 1 {
+		t.Fatalf("expected the upload body streamed through a counter, not buffered")
+	}
+	want := fmt.Sprintf("[multipart body, %d bytes]", received)
+	if received < 12000 || !strings.Contains(log.String(), want) {
+		t.Fatalf("expected %s in the debug log: %s", want, log.String())
+	}
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatalf("read har: %v", err)
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil || len(har.Log.Entries) != 1 || har.Log.Entries[0].Request.BodySize != received || har.Log.Entries[0].Request.PostData.Text != want {
+		t.Fatalf("unexpected har %s: %v", data, err)
+	}
+}
+
+func TestResultTransportReportsSuccessfulResults(t *testing.T) {
+	srv := newEchoServer(t)
+	c := NewClient(srv.URL, testToken)
+	var methods []string
+	c.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+		return NewResultTransport(next, func(method string, result json.RawMessage) {
+			methods = append(methods, method+"="+string(result))
+		})
+	})
+
+	res, err := c.SendMessage(context.Background(), "42", "hello")
+	if err != nil {
+		t.Fatalf("SendMessage returned error: %v", err)
+	}
+	if !strings.Contains(string(res), `"path"`) {
+		t.Fatalf("caller should still receive the result, got %s", res)
+	}
+	if _, err := c.GetUpdates(context.Background(), 0, 0); err != nil {
+		t.Fatalf("GetUpdates returned error: %v", err)
+	}
+	if len(methods) != 2 || !strings.HasPrefix(methods[0], `sendMessage={"path":`) || methods[1] != "getUpdates=[]" {
+		t.Fatalf("unexpected observed results: %v", methods)
+	}
+}
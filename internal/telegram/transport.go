@@ -0,0 +1,150 @@
+package telegram
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultDialTimeout     = 30 * time.Second
+	defaultIdleConnTimeout = 90 * time.Second
+)
+
+// ClientOptions configures a Client beyond the API base and token: proxying,
+// TLS trust and connection timeouts.
+type ClientOptions struct {
+	APIBase string
+	Token   string
+
+	// ProxyURL routes requests through an http, https or socks5 proxy. When
+	// empty, the standard HTTP_PROXY/HTTPS_PROXY environment is honoured.
+	ProxyURL string
+	// CAFile is a PEM bundle trusted in addition to the system roots.
+	CAFile string
+	// ClientCertFile and ClientKeyFile enable mutual TLS when both are set.
+	ClientCertFile     string
+	ClientKeyFile      string
+	InsecureSkipVerify bool
+
+	DialTimeout     time.Duration
+	IdleConnTimeout time.Duration
+
+	// Timeouts bounds individual requests; zero fields use the defaults.
+	Timeouts Timeouts
+
+	// LocalAPI targets a local telegram-bot-api server started with --local:
+	// files are exchanged through the shared disk and upload limits are
+	// relaxed.
+	LocalAPI bool
+
+	// TestEnv targets Telegram's test environment, whose endpoints live under
+	// /bot<token>/test/.
+	TestEnv bool
+}
+
+// NewClientWithOptions builds a client with a transport configured from opts.
+func NewClientWithOptions(opts ClientOptions) (*Client, error) {
+	transport, err := newTransport(opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Timeouts.Request < 0 || opts.Timeouts.Upload < 0 || opts.Timeouts.Download < 0 || opts.Timeouts.LongPollMargin < 0 {
+		return nil, errors.New("request timeouts must not be negative")
+	}
+	c := NewClient(opts.APIBase, opts.Token)
+	c.http.Transport = transport
+	c.timeouts = opts.Timeouts.withDefaults()
+	c.localAPI = opts.LocalAPI
+	c.testEnv = opts.TestEnv
+	return c, nil
+}
+
+func newTransport(opts ClientOptions) (*http.Transport, error) {
+	dialTimeout := opts.DialTimeout
+	if dialTimeout <= 0 {
+		dialTimeout = defaultDialTimeout
+	}
+	idleTimeout := opts.IdleConnTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleConnTimeout
+	}
+
+	proxy, err := proxyFunc(opts.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := tlsConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: 30 * time.Second}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		TLSClientConfig:       tlsConfig,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       idleTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+func proxyFunc(raw string) (func(*http.Request) (*url.URL, error), error) {
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse proxy url: %w", err)
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (want http, https or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy url %q has no host", raw)
+	}
+	return http.ProxyURL(u), nil
+}
+
+func tlsConfig(opts ClientOptions) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: opts.InsecureSkipVerify}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read ca file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca file %s contains no PEM certificates", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if (opts.ClientCertFile == "") != (opts.ClientKeyFile == "") {
+		return nil, errors.New("client certificate and key must be set together")
+	}
+	if opts.ClientCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.ClientCertFile, opts.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,81 @@
+package telegram
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func okHandler(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte(`{"ok":true,"result":{"id":1}}`))
+}
+
+func TestNewClientWithOptionsRejectsBadProxy(t *testing.T) {
+	for _, proxy := range []string{"ftp://proxy:21", "socks5://"} {
+		if _, err := NewClientWithOptions(ClientOptions{APIBase: "http://x", Token: "t", ProxyURL: proxy}); err == nil {
+			t.Fatalf("expected error for proxy %q", proxy)
+		}
+	}
+	if _, err := NewClientWithOptions(ClientOptions{APIBase: "http://x", Token: "t", ClientCertFile: "cert.pem"}); err == nil {
+		t.Fatalf("expected error for certificate without key")
+	}
+}
+
+func TestClientUsesHTTPProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		okHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	c, err := NewClientWithOptions(ClientOptions{APIBase: "http://bot-api.internal", Token: testToken, ProxyURL: proxy.URL})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if _, err := c.GetMe(context.Background()); err != nil {
+		t.Fatalf("GetMe returned error: %v", err)
+	}
+	if !strings.HasPrefix(proxied, "http://bot-api.internal/bot") {
+		t.Fatalf("expected absolute-form request through proxy, got %q", proxied)
+	}
+}
+
+func TestClientTrustsCAFile(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer srv.Close()
+
+	untrusted, err := NewClientWithOptions(ClientOptions{APIBase: srv.URL, Token: testToken})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if _, err := untrusted.GetMe(context.Background()); err == nil {
+		t.Fatalf("expected certificate error without ca file")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0o600); err != nil {
+		t.Fatalf("write ca: %v", err)
+	}
+	trusted, err := NewClientWithOptions(ClientOptions{APIBase: srv.URL, Token: testToken, CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if _, err := trusted.GetMe(context.Background()); err != nil {
+		t.Fatalf("GetMe with ca file returned error: %v", err)
+	}
+
+	insecure, err := NewClientWithOptions(ClientOptions{APIBase: srv.URL, Token: testToken, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatalf("NewClientWithOptions returned error: %v", err)
+	}
+	if _, err := insecure.GetMe(context.Background()); err != nil {
+		t.Fatalf("GetMe with insecure-skip-verify returned error: %v", err)
+	}
+}
@@ -0,0 +1,215 @@
+package telegram
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// The types below model the subset of the Bot API used by the CLI. Update
+// keeps the raw JSON alongside, which stays the source of truth for output.
+
+type User struct {
+	ID           int64  `json:"id"`
+	IsBot        bool   `json:"is_bot"`
+	FirstName    string `json:"first_name"`
+	LastName     string `json:"last_name,omitempty"`
+	Username     string `json:"username,omitempty"`
+	LanguageCode string `json:"language_code,omitempty"`
+}
+
+type Chat struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	Title     string `json:"title,omitempty"`
+	Username  string `json:"username,omitempty"`
+	FirstName string `json:"first_name,omitempty"`
+	LastName  string `json:"last_name,omitempty"`
+	IsForum   bool   `json:"is_forum,omitempty"`
+}
+
+type MessageEntity struct {
+	Type   string `json:"type"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+type Message struct {
+	MessageID       int64           `json:"message_id"`
+	MessageThreadID int64           `json:"message_thread_id,omitempty"`
+	From            *User           `json:"from,omitempty"`
+	SenderChat      *Chat           `json:"sender_chat,omitempty"`
+	Date            int64           `json:"date"`
+	Chat            Chat            `json:"chat"`
+	ReplyToMessage  *Message        `json:"reply_to_message,omitempty"`
+	EditDate        int64           `json:"edit_date,omitempty"`
+	Text            string          `json:"text,omitempty"`
+	Entities        []MessageEntity `json:"entities,omitempty"`
+	Caption         string          `json:"caption,omitempty"`
+	IsTopicMessage  bool            `json:"is_topic_message,omitempty"`
+
+	ForumTopicCreated *ForumTopicCreated `json:"forum_topic_created,omitempty"`
+	ForumTopicEdited  *ForumTopicEdited  `json:"forum_topic_edited,omitempty"`
+}
+
+type CallbackQuery struct {
+	ID           string   `json:"id"`
+	From         User     `json:"from"`
+	Message      *Message `json:"message,omitempty"`
+	ChatInstance string   `json:"chat_instance"`
+	Data         string   `json:"data,omitempty"`
+}
+
+type InlineQuery struct {
+	ID       string `json:"id"`
+	From     User   `json:"from"`
+	Query    string `json:"query"`
+	Offset   string `json:"offset"`
+	ChatType string `json:"chat_type,omitempty"`
+}
+
+// ChatMember is a member of a chat. The can_* rights and permissions of
+// administrators and restricted members are left in the raw JSON.
+type ChatMember struct {
+	Status      string `json:"status"`
+	User        User   `json:"user"`
+	CustomTitle string `json:"custom_title,omitempty"`
+	IsAnonymous bool   `json:"is_anonymous,omitempty"`
+	IsMember    bool   `json:"is_member,omitempty"`
+	UntilDate   int64  `json:"until_date,omitempty"`
+}
+
+type ChatMemberUpdated struct {
+	Chat          Chat       `json:"chat"`
+	From          User       `json:"from"`
+	Date          int64      `json:"date"`
+	OldChatMember ChatMember `json:"old_chat_member"`
+	NewChatMember ChatMember `json:"new_chat_member"`
+}
+
+type ChatJoinRequest struct {
+	Chat       Chat            `json:"chat"`
+	From       User            `json:"from"`
+	UserChatID int64           `json:"user_chat_id"`
+	Date       int64           `json:"date"`
+	Bio        string          `json:"bio,omitempty"`
+	InviteLink *ChatInviteLink `json:"invite_link,omitempty"`
+}
+
+// Update is one incoming update. Only one of the typed fields is set.
+type Update struct {
+	UpdateID          int64              `json:"update_id"`
+	Message           *Message           `json:"message,omitempty"`
+	EditedMessage     *Message           `json:"edited_message,omitempty"`
+	ChannelPost       *Message           `json:"channel_post,omitempty"`
+	EditedChannelPost *Message           `json:"edited_channel_post,omitempty"`
+	CallbackQuery     *CallbackQuery     `json:"callback_query,omitempty"`
+	InlineQuery       *InlineQuery       `json:"inline_query,omitempty"`
+	MyChatMember      *ChatMemberUpdated `json:"my_chat_member,omitempty"`
+	ChatMember        *ChatMemberUpdated `json:"chat_member,omitempty"`
+	ChatJoinRequest   *ChatJoinRequest   `json:"chat_join_request,omitempty"`
+	Raw               json.RawMessage    `json:"-"`
+	// ReceivedAt is when the CLI received the update; zero when unknown.
+	ReceivedAt time.Time `json:"-"`
+	// Topic is the name of the forum topic of the update's message when the
+	// receiver learned it from earlier updates; empty when unknown.
+	Topic string `json:"-"`
+}
+
+// Type returns the name of the update's payload field, e.g. "message" or
+// "callback_query", or "unknown" for payloads the model does not cover.
+func (u Update) Type() string {
+	switch {
+	case u.Message != nil:
+		return "message"
+	case u.EditedMessage != nil:
+		return "edited_message"
+	case u.ChannelPost != nil:
+		return "channel_post"
+	case u.EditedChannelPost != nil:
+		return "edited_channel_post"
+	case u.CallbackQuery != nil:
+		return "callback_query"
+	case u.InlineQuery != nil:
+		return "inline_query"
+	case u.MyChatMember != nil:
+		return "my_chat_member"
+	case u.ChatMember != nil:
+		return "chat_member"
+	case u.ChatJoinRequest != nil:
+		return "chat_join_request"
+	}
+	return "unknown"
+}
+
+// EffectiveMessage returns the message carried by the update, if any,
+// including the message a callback query is attached to.
+func (u Update) EffectiveMessage() *Message {
+	switch {
+	case u.Message != nil:
+		return u.Message
+	case u.EditedMessage != nil:
+		return u.EditedMessage
+	case u.ChannelPost != nil:
+		return u.ChannelPost
+	case u.EditedChannelPost != nil:
+		return u.EditedChannelPost
+	case u.CallbackQuery != nil:
+		return u.CallbackQuery.Message
+	}
+	return nil
+}
+
+// EffectiveChat returns the chat the update happened in, if any.
+func (u Update) EffectiveChat() *Chat {
+	if m := u.EffectiveMessage(); m != nil {
+		return &m.Chat
+	}
+	switch {
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.Chat
+	case u.ChatMember != nil:
+		return &u.ChatMember.Chat
+	case u.ChatJoinRequest != nil:
+		return &u.ChatJoinRequest.Chat
+	}
+	return nil
+}
+
+// EffectiveUser returns the user who triggered the update, if any.
+func (u Update) EffectiveUser() *User {
+	switch {
+	case u.CallbackQuery != nil:
+		return &u.CallbackQuery.From
+	case u.InlineQuery != nil:
+		return &u.InlineQuery.From
+	case u.MyChatMember != nil:
+		return &u.MyChatMember.From
+	case u.ChatMember != nil:
+		return &u.ChatMember.From
+	case u.ChatJoinRequest != nil:
+		return &u.ChatJoinRequest.From
+	}
+	if m := u.EffectiveMessage(); m != nil {
+		return m.From
+	}
+	return nil
+}
+
+// ParseUpdate decodes a raw update. Only update_id is required; payloads
+// that do not match the typed model are still returned with Raw set, so
+// unusual updates are never dropped.
+func ParseUpdate(raw json.RawMessage) (Update, error) {
+	var idOnly struct {
+		UpdateID int64 `json:"update_id"`
+	}
+	if err := json.Unmarshal(raw, &idOnly); err != nil {
+		return Update{}, err
+	}
+	var u Update
+	if err := json.Unmarshal(raw, &u); err != nil {
+		u = Update{}
+	}
+	u.UpdateID = idOnly.UpdateID
+	u.Raw = raw
+	return u, nil
+}
@@ -0,0 +1,34 @@
+package telegram
+
+import "testing"
+
+func TestParseUpdate(t *testing.T) {
+	raw := []byte(`{"update_id":7,"callback_query":{"id":"1","from":{"id":5,"is_bot":false,"first_name":"A"},"chat_instance":"x","data":"ok","message":{"message_id":3,"date":1,"chat":{"id":-100,"type":"supergroup","title":"G"}}}}`)
+	u, err := ParseUpdate(raw)
+	if err != nil {
+		t.Fatalf("ParseUpdate returned error: %v", err)
+	}
+	if u.UpdateID != 7 || u.Type() != "callback_query" || string(u.Raw) != string(raw) {
+		t.Fatalf("unexpected update: %+v", u)
+	}
+	if chat := u.EffectiveChat(); chat == nil || chat.Title != "G" {
+		t.Fatalf("unexpected effective chat: %+v", chat)
+	}
+	if user := u.EffectiveUser(); user == nil || user.ID != 5 {
+		t.Fatalf("unexpected effective user: %+v", user)
+	}
+}
+
+func TestParseUpdateKeepsUnmodelledPayloads(t *testing.T) {
+	raw := []byte(`{"update_id":8,"message":{"message_id":"not-a-number"}}`)
+	u, err := ParseUpdate(raw)
+	if err != nil {
+		t.Fatalf("ParseUpdate returned error: %v", err)
+	}
+	if u.UpdateID != 8 || u.Type() != "unknown" || string(u.Raw) != string(raw) {
+		t.Fatalf("expected raw-only update, got %+v", u)
+	}
+	if _, err := ParseUpdate([]byte(`{"update_id":"x"}`)); err == nil {
+		t.Fatalf("expected error for invalid update_id")
+	}
+}
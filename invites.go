@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/joinrules"
+	"github.com/example/tgbot-cli/internal/polling"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const inviteUsage = "usage: tgbot chat invite <create|edit|revoke|export|list-export> <chat> [flags]"
+
+func runChatInvite(args []string) {
+	if len(args) == 0 {
+		fatal(inviteUsage)
+	}
+	switch args[0] {
+	case "create":
+		runChatInviteCreate(args[1:])
+	case "edit":
+		runChatInviteEdit(args[1:])
+	case "revoke":
+		runChatInviteRevoke(args[1:])
+	case "export", "list-export":
+		// list-export is an alias: the Bot API cannot list links, and
+		// exportChatInviteLink only replaces the primary one.
+		runChatInviteExport(args[0], args[1:])
+	default:
+		fatal(inviteUsage)
+	}
+}
+
+// inviteFlags are the settings of a created or edited link.
+// editChatInviteLink replaces every setting, so on edit an omitted flag
+// resets its setting rather than keeping it.
+type inviteFlags struct {
+	name        *string
+	expire      *string
+	memberLimit *int
+	joinRequest *bool
+}
+
+func registerInviteFlags(fs *flag.FlagSet, edit bool) inviteFlags {
+	note := ""
+	if edit {
+		note = "; omitted on edit, the setting is cleared"
+	}
+	return inviteFlags{
+		name:        fs.String("name", "", "link name shown to administrators (up to 32 characters)"+note),
+		expire:      fs.String("expire", "", "expire after a duration (24h) or at an RFC 3339 time; default never"+note),
+		memberLimit: fs.Int("member-limit", 0, "max users joining through the link, 1-99999; default unlimited"+note),
+		joinRequest: fs.Bool("join-request", false, "users joining through the link must be approved (see chat join-requests watch)"+note),
+	}
+}
+
+func (f inviteFlags) mustOptions() telegram.InviteLinkOptions {
+	if *f.memberLimit < 0 || *f.memberLimit > 99999 {
+		fatal("--member-limit must be between 1 and 99999")
+	}
+	if *f.joinRequest && *f.memberLimit > 0 {
+		fatal("--member-limit cannot be combined with --join-request")
+	}
+	return telegram.InviteLinkOptions{
+		Name:               *f.name,
+		ExpireDate:         mustParseUntil("expire", *f.expire, parseUntil),
+		MemberLimit:        *f.memberLimit,
+		CreatesJoinRequest: *f.joinRequest,
+	}
+}
+
+func runChatInviteCreate(args []string) {
+	var f inviteFlags
+	cmd := parseChatCommand("chat invite create", "usage: tgbot chat invite create <chat> [--name n] [--expire 24h] [--member-limit n|--join-request] [flags]", args, 0, func(fs *flag.FlagSet) {
+		f = registerInviteFlags(fs, false)
+	})
+	link, err := cmd.client.CreateChatInviteLink(context.Background(), cmd.chatID, f.mustOptions())
+	if err != nil {
+		fatalf("chat invite create failed: %v", err)
+	}
+	printInviteLink(cmd.format, link)
+}
+
+func runChatInviteEdit(args []string) {
+	var f inviteFlags
+	cmd := parseChatCommand("chat invite edit", "usage: tgbot chat invite edit <chat> <link> [--name n] [--expire 24h] [--member-limit n|--join-request] [flags] (settings not given are cleared)", args, 1, func(fs *flag.FlagSet) {
+		f = registerInviteFlags(fs, true)
+	})
+	link, err := cmd.client.EditChatInviteLink(context.Background(), cmd.chatID, cmd.args[0], f.mustOptions())
+	if err != nil {
+		fatalf("chat invite edit failed: %v", err)
+	}
+	printInviteLink(cmd.format, link)
+}
+
+func runChatInviteRevoke(args []string) {
+	cmd := parseModerationCommand("chat invite revoke", "usage: tgbot chat invite revoke <chat> <link> [flags]", args, 1, nil)
+	link, err := cmd.client.RevokeChatInviteLink(context.Background(), cmd.chatID, cmd.args[0])
+	if err != nil {
+		fatalf("chat invite revoke failed: %v", err)
+	}
+	printInviteLink(cmd.format, link)
+}
+
+func runChatInviteExport(sub string, args []string) {
+	var yes *bool
+	cmd := parseModerationCommand("chat invite "+sub, "usage: tgbot chat invite "+sub+" <chat> --yes [flags]", args, 0, func(fs *flag.FlagSet) {
+		yes = fs.Bool("yes", false, "confirm the call")
+	})
+	if !*yes {
+		fatal("chat invite " + sub + " revokes the chat's primary invite link and creates a new one; rerun with --yes")
+	}
+	link, err := cmd.client.ExportChatInviteLink(context.Background(), cmd.chatID)
+	if err != nil {
+		fatalf("chat invite export failed: %v", err)
+	}
+	if cmd.format == "json" {
+		printJSON(mustMarshal(map[string]any{"chat_id": cmd.chatID, "invite_link": link}))
+		return
+	}
+	fmt.Println(link)
+}
+
+func printInviteLink(format string, link *telegram.ChatInviteLink) {
+	if format == "json" {
+		printJSON(mustMarshal(link))
+		return
+	}
+	rows := [][2]string{
+		{"link", link.InviteLink},
+		{"name", link.Name},
+		{"primary", strconv.FormatBool(link.IsPrimary)},
+		{"revoked", strconv.FormatBool(link.IsRevoked)},
+		{"join request", strconv.FormatBool(link.CreatesJoinRequest)},
+		{"expires", "never"},
+		{"member limit", "unlimited"},
+	}
+	if link.ExpireDate != 0 {
+		rows[5][1] = time.Unix(link.ExpireDate, 0).Local().Format("2006-01-02 15:04")
+	}
+	if link.MemberLimit != 0 {
+		rows[6][1] = strconv.Itoa(link.MemberLimit)
+	}
+	if link.PendingJoinRequestCount > 0 {
+		rows = append(rows, [2]string{"pending requests", strconv.Itoa(link.PendingJoinRequestCount)})
+	}
+	writeKeyValues(rows)
+}
+
+func runChatJoinRequestsWatch(args []string) {
+	fs := baseFlagSet("chat join-requests watch")
+	rulesPath := fs.String("rules", "", "JSON rule file deciding each join request (required)")
+	dryRun := fs.Bool("dry-run", false, "print decisions without approving or declining; requests stay pending and unconfirmed for a later run")
+	interval := fs.Duration("interval", 2*time.Second, "polling interval between requests")
+	timeout := fs.Int("timeout", 20, "getUpdates long-poll timeout in seconds")
+	once := fs.Bool("once", false, "run only one polling cycle")
+	deleteWebhook := fs.Bool("delete-webhook", false, "delete the bot's webhook before polling; it is not restored afterwards")
+	maxAttempts := fs.Int("max-attempts", 3, "try to answer a join request up to this many times before giving up; 1 with --once")
+	retryBackoff := fs.Duration("retry-backoff", polling.DefaultRetryBackoff, "wait before the first retry, doubling after each failure")
+	deadLetter := fs.String("dead-letter", "", "append join requests that could not be answered to this jsonl file and carry on; without it such a request stops the watcher unconfirmed")
+	tokenOpt := registerTokenFlags(fs)
+	_ = fs.Parse(args)
+	if *rulesPath == "" {
+		fatal("usage: tgbot chat join-requests watch --rules rules.json [--dry-run] [flags]")
+	}
+	if *maxAttempts <= 0 {
+		fatal("--max-attempts must be greater than 0")
+	}
+	rules, err := joinrules.Load(*rulesPath)
+	if err != nil {
+		fatal(err.Error())
+	}
+	var deadLetters polling.DeadLetterSink
+	if *deadLetter != "" {
+		f := mustOpenAppend(*deadLetter, "dead-letter")
+		defer f.Close()
+		deadLetters = polling.NewDeadLetterWriter(f)
+	}
+
+	client := mustClient(tokenOpt)
+	poller := polling.New(client, polling.Options{
+		Interval:      *interval,
+		TimeoutSecond: *timeout,
+		DeleteWebhook: *deleteWebhook,
+		Once:          *once,
+		OutputFormat:  "jsonl",
+		// Only join requests are fetched, so the bot's other updates are
+		// left for its own backend instead of being confirmed here.
+		AllowedUpdates: []string{"chat_join_request"},
+		Recorder:       newChatLearner(tokenOpt, client),
+		Handler:        joinRequestHandler{client: client, rules: rules, dryRun: *dryRun, out: os.Stdout},
+		Retry:          polling.RetryPolicy{MaxAttempts: retryAttempts(fs, *maxAttempts, *once), Backoff: *retryBackoff},
+		DeadLetter:     deadLetters,
+		// A dry run confirms nothing, so a later real run still sees and
+		// decides every request.
+		KeepOffset: *dryRun,
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// Updates themselves are not printed, only the decisions.
+	err = poller.Run(ctx, io.Discard, os.Stderr)
+	restoreAllowedUpdates(client)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		fatalf("polling failed: %v", err)
+	}
+}
+
+// restoreAllowedUpdates undoes the watcher's allowed_updates, which
+// Telegram would otherwise keep for the bot's next getUpdates caller. The
+// call passes no offset, so it confirms nothing.
+func restoreAllowedUpdates(client *telegram.Client) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := client.GetUpdatesAllowed(ctx, 0, 0, []string{}); err != nil {
+		fmt.Fprintf(os.Stderr, "[warn] could not restore allowed updates: %v\n", err)
+	}
+}
+
+// joinRequestHandler answers chat_join_request updates as the rules decide
+// and prints one JSON line per request. Other updates are skipped.
+type joinRequestHandler struct {
+	client *telegram.Client
+	rules  *joinrules.Rules
+	dryRun bool
+	out    io.Writer
+}
+
+// joinDecision is the line printed for each join request.
+type joinDecision struct {
+	UpdateID  int64            `json:"update_id"`
+	ChatID    int64            `json:"chat_id"`
+	ChatTitle string           `json:"chat_title,omitempty"`
+	UserID    int64            `json:"user_id"`
+	Username  string           `json:"username,omitempty"`
+	Action    joinrules.Action `json:"action"`
+	Rule      string           `json:"rule"`
+	// Reason is Telegram's error for a skipped request.
+	Reason string `json:"reason,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"`
+}
+
+// joinSkipped is the action printed for a request that was settled before
+// the watcher answered it.
+const joinSkipped joinrules.Action = "skipped"
+
+// settledJoinRequestErrors are the errors Telegram returns for a request
+// that is no longer pending: answered elsewhere, cancelled by the user, or
+// from a user who already joined. Retrying them cannot succeed.
+var settledJoinRequestErrors = []string{"HIDE_REQUESTER_MISSING", "USER_ALREADY_PARTICIPANT"}
+
+func joinRequestSettled(err error) (string, bool) {
+	for _, code := range settledJoinRequestErrors {
+		if strings.Contains(err.Error(), code) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+func (h joinRequestHandler) HandleUpdate(ctx context.Context, update telegram.Update) error {
+	req := update.ChatJoinRequest
+	if req == nil {
+		return nil
+	}
+	decision := h.rules.Decide(*req)
+	action, reason := decision.Action, ""
+	if !h.dryRun {
+		chatID := strconv.FormatInt(req.Chat.ID, 10)
+		var err error
+		switch decision.Action {
+		case joinrules.Approve:
+			err = h.client.ApproveChatJoinRequest(ctx, chatID, req.From.ID)
+		case joinrules.Decline:
+			err = h.client.DeclineChatJoinRequest(ctx, chatID, req.From.ID)
+		}
+		if err != nil {
+			code, settled := joinRequestSettled(err)
+			if !settled {
+				return fmt.Errorf("%s join request of user %d (rule %s): %w", decision.Action, req.From.ID, decision.Rule, err)
+			}
+			action, reason = joinSkipped, code
+		}
+	}
+	line, err := json.Marshal(joinDecision{
+		UpdateID:  update.UpdateID,
+		ChatID:    req.Chat.ID,
+		ChatTitle: req.Chat.Title,
+		UserID:    req.From.ID,
+		Username:  req.From.Username,
+		Action:    action,
+		Rule:      decision.Rule,
+		Reason:    reason,
+		DryRun:    h.dryRun,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(h.out, "%s\n", line)
+	return err
+}
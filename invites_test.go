@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func TestCommandChatInvite(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_invite_users": true}})
+
+	res := runOK(t, append([]string{"chat", "invite", "create", "-1001", "--name", "launch", "--expire", "24h", "--member-limit", "50", "--format", "json"}, flags...)...)
+	var link struct {
+		InviteLink  string `json:"invite_link"`
+		Name        string `json:"name"`
+		ExpireDate  int64  `json:"expire_date"`
+		MemberLimit int    `json:"member_limit"`
+	}
+	if err := json.Unmarshal([]byte(res.stdout), &link); err != nil {
+		t.Fatalf("decode invite %q: %v", res.stdout, err)
+	}
+	if link.Name != "launch" || link.ExpireDate == 0 || link.MemberLimit != 50 {
+		t.Fatalf("unexpected invite: %s", res.stdout)
+	}
+
+	res = runOK(t, append([]string{"chat", "invite", "edit", "-1001", link.InviteLink, "--join-request"}, flags...)...)
+	if !strings.Contains(res.stdout, "join request  true") || !strings.Contains(res.stdout, "unlimited") {
+		t.Fatalf("unexpected edited invite:\n%s", res.stdout)
+	}
+	res = runOK(t, append([]string{"chat", "invite", "revoke", "-1001", link.InviteLink}, flags...)...)
+	if !strings.Contains(res.stdout, "revoked       true") {
+		t.Fatalf("unexpected revoked invite:\n%s", res.stdout)
+	}
+	bad := runCLI(t, nil, append([]string{"chat", "invite", "export", "-1001"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "--yes") {
+		t.Fatalf("expected confirmation error, got %d: %s", bad.code, bad.stderr)
+	}
+	res = runOK(t, append([]string{"chat", "invite", "list-export", "-1001", "--yes"}, flags...)...)
+	if !strings.HasPrefix(res.stdout, "https://t.me/+") {
+		t.Fatalf("unexpected exported link %q", res.stdout)
+	}
+
+	bad = runCLI(t, nil, append([]string{"chat", "invite", "create", "-1001", "--member-limit", "5", "--join-request"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "cannot be combined") {
+		t.Fatalf("expected usage error, got %d: %s", bad.code, bad.stderr)
+	}
+}
+
+func TestCommandChatJoinRequestsWatch(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_invite_users": true}})
+	rules := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(rules, []byte(`{"default": "ignore", "rules": [
+		{"name": "spam", "action": "decline", "bio_regex": "(?i)crypto"},
+		{"name": "team", "action": "approve", "username_regex": "^acme_"}
+	]}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group, From: telegramtest.User{ID: 7, Username: "acme_bob"}})
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group, From: telegramtest.User{ID: 8}, Bio: "Crypto signals"})
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group, From: telegramtest.User{ID: 9}})
+	srv.EnqueueText("not a join request")
+
+	res := runOK(t, append([]string{"chat", "join-requests", "watch", "--rules", rules, "--once", "--timeout", "0"}, flags...)...)
+	lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 decisions, got:\n%s", res.stdout)
+	}
+	type decision struct {
+		UserID int64  `json:"user_id"`
+		Action string `json:"action"`
+		Rule   string `json:"rule"`
+	}
+	var decisions []decision
+	for _, line := range lines {
+		var d decision
+		if err := json.Unmarshal([]byte(line), &d); err != nil {
+			t.Fatalf("decode decision %q: %v", line, err)
+		}
+		decisions = append(decisions, d)
+	}
+	if decisions[0].Action != "approve" || decisions[1].Rule != "spam" || decisions[2].Action != "ignore" {
+		t.Fatalf("unexpected decisions: %+v", decisions)
+	}
+	if m := srv.Member(-1001, 7); m.Status != "member" {
+		t.Fatalf("expected approved member, got %+v", m)
+	}
+	if pending := srv.PendingJoinRequests(-1001); len(pending) != 1 || pending[0] != 9 {
+		t.Fatalf("unexpected pending requests: %v", pending)
+	}
+	// The watcher fetched join requests only, leaving the message for the
+	// bot's own backend, and restored the default update types on exit.
+	res = runOK(t, append([]string{"updates", "list", "--format", "jsonl"}, flags...)...)
+	if !strings.Contains(res.stdout, "not a join request") {
+		t.Fatalf("expected the message to still be pending, got:\n%s", res.stdout)
+	}
+
+	bad := runCLI(t, nil, append([]string{"chat", "join-requests", "watch", "--rules", filepath.Join(t.TempDir(), "missing.json")}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "read rule file") {
+		t.Fatalf("expected rule file error, got %d: %s", bad.code, bad.stderr)
+	}
+}
+
+func TestCommandChatJoinRequestsWatchFailures(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_invite_users": true}})
+	rules := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(rules, []byte(`{"default": "approve"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// A request answered in the app before the watcher gets to it is
+	// skipped, not retried forever.
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group, From: telegramtest.User{ID: 7}})
+	runOK(t, append([]string{"api", "call", "approveChatJoinRequest", "--param", "chat_id=-1001", "--param", "user_id=7"}, flags...)...)
+	res := runOK(t, append([]string{"chat", "join-requests", "watch", "--rules", rules, "--once", "--timeout", "0"}, flags...)...)
+	if !strings.Contains(res.stdout, `"action":"skipped"`) || !strings.Contains(res.stdout, `"reason":"HIDE_REQUESTER_MISSING"`) {
+		t.Fatalf("expected a skipped decision, got:\n%s", res.stdout)
+	}
+
+	// Other failures stop the watcher unless they can be dead-lettered.
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator"})
+	srv.EnqueueJoinRequest(telegramtest.IncomingJoinRequest{Chat: group, From: telegramtest.User{ID: 8}})
+	watch := append([]string{"chat", "join-requests", "watch", "--rules", rules, "--once", "--timeout", "0", "--max-attempts", "1"}, flags...)
+	bad := runCLI(t, nil, watch...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "not enough rights") {
+		t.Fatalf("expected the watcher to stop, got %d: %s", bad.code, bad.stderr)
+	}
+	dead := filepath.Join(t.TempDir(), "dead.jsonl")
+	runOK(t, append(watch, "--dead-letter", dead)...)
+	data, err := os.ReadFile(dead)
+	if err != nil || !strings.Contains(string(data), `"user_chat_id":8`) {
+		t.Fatalf("expected the request dead-lettered, got %q: %v", data, err)
+	}
+}
@@ -6,16 +6,25 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/example/tgbot-cli/internal/archive"
 	"github.com/example/tgbot-cli/internal/config"
+	"github.com/example/tgbot-cli/internal/exechook"
 	"github.com/example/tgbot-cli/internal/polling"
+	"github.com/example/tgbot-cli/internal/session"
 	"github.com/example/tgbot-cli/internal/telegram"
 )
 
+// redactor scrubs every resolved token from messages written by fatal and
+// fatalf, so no error path can print a token to the terminal.
+var redactor = telegram.NewRedactor()
+
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
@@ -29,6 +38,20 @@ func main() {
 		runBot(os.Args[2:])
 	case "message":
 		runMessage(os.Args[2:])
+	case "api":
+		runAPI(os.Args[2:])
+	case "file":
+		runFile(os.Args[2:])
+	case "mock-server":
+		runMockServer(os.Args[2:])
+	case "archive":
+		runArchive(os.Args[2:])
+	case "chats":
+		runChats(os.Args[2:])
+	case "chat":
+		runChat(os.Args[2:])
+	case "topic":
+		runTopic(os.Args[2:])
 	case "help", "-h", "--help":
 		printUsage()
 	default:
@@ -40,7 +63,7 @@ func main() {
 
 func runUpdates(args []string) {
 	if len(args) == 0 {
-		fatal("usage: tgbot updates <listen|list> [flags]")
+		fatal("usage: tgbot updates <listen|list|inject|replay> [flags]")
 	}
 
 	switch args[0] {
@@ -48,8 +71,12 @@ func runUpdates(args []string) {
 		runUpdatesListen(args[1:])
 	case "list":
 		runUpdatesList(args[1:])
+	case "inject":
+		runUpdatesInject(args[1:])
+	case "replay":
+		runUpdatesReplay(args[1:])
 	default:
-		fatal("usage: tgbot updates <listen|list> [flags]")
+		fatal("usage: tgbot updates <listen|list|inject|replay> [flags]")
 	}
 }
 
@@ -60,24 +87,125 @@ func runUpdatesListen(args []string) {
 	offset := fs.Int64("offset", 0, "initial update offset")
 	once := fs.Bool("once", false, "run only one polling cycle")
 	deleteWebhook := fs.Bool("delete-webhook", true, "delete webhook before polling")
-	outputFormat := fs.String("format", "pretty", "updates output format: pretty|jsonl")
+	outputFormat := fs.String("format", "pretty", "updates output format: pretty|jsonl|csv|tsv")
+	columns := fs.String("columns", "", "comma-separated csv/tsv columns: named columns or JSON paths such as message.from.language_code")
+	record := fs.String("record", "", "append every received update with its receive time to this jsonl session file")
+	execCmd := fs.String("exec", "", "run this shell command per update with the raw update on stdin and TG_* env vars")
+	execConcurrency := fs.Int("exec-concurrency", 1, "max --exec commands running at once; each chat's updates still run in order")
+	execTimeout := fs.Duration("exec-timeout", 30*time.Second, "kill an --exec command running longer than this (0 disables)")
+	execReply := fs.Bool("exec-reply", false, "send each --exec command's stdout back to the update's chat")
+	maxAttempts := fs.Int("max-attempts", 3, "run --exec up to this many times per update before giving up; 1 with --once")
+	retryBackoff := fs.Duration("retry-backoff", polling.DefaultRetryBackoff, "wait before the first --exec retry, doubling after each failure")
+	deadLetter := fs.String("dead-letter", "", "append updates whose --exec failed every attempt to this jsonl file and carry on; without it such an update stops listen unconfirmed")
+	allProfiles := fs.Bool("all-profiles", false, "listen with every profile in the config file (see also --profile dev,staging)")
+	envelope := fs.Bool("envelope", false, "wrap each update as {received_at,bot,profile,source,update}")
+	archivePath := fs.String("archive", "", "store updates and sent messages in this SQLite database (see tgbot archive)")
+	threadID := fs.Int64("thread-id", 0, "only print and --exec updates from this forum topic (message_thread_id)")
 	tokenOpt := registerTokenFlags(fs)
 
 	_ = fs.Parse(args)
-	client := mustClient(tokenOpt)
-	poller := polling.New(client, polling.Options{
+	profiles := listenProfiles(tokenOpt, *allProfiles)
+	if profiles != nil && *offset != 0 {
+		fatal("--offset cannot be combined with several profiles")
+	}
+	if *execCmd == "" && (*execReply || flagGiven(fs, "max-attempts") || *deadLetter != "") {
+		fatal("--exec-reply, --max-attempts and --dead-letter require --exec")
+	}
+	if *execConcurrency <= 0 {
+		fatal("--exec-concurrency must be greater than 0")
+	}
+	if *maxAttempts <= 0 {
+		fatal("--max-attempts must be greater than 0")
+	}
+	tableColumns := mustTableColumns(*outputFormat, *columns, *envelope)
+	if tableColumns == nil && profiles != nil && polling.IsTableFormat(*outputFormat) {
+		tableColumns = append([]string{"profile", "bot"}, polling.DefaultColumns...)
+	}
+
+	base := polling.Options{
 		Interval:      *interval,
 		TimeoutSecond: *timeout,
 		InitialOffset: *offset,
 		DeleteWebhook: *deleteWebhook,
 		Once:          *once,
 		OutputFormat:  *outputFormat,
-	})
+		Columns:       tableColumns,
+		Envelope:      *envelope,
+		Filter:        threadFilter(*threadID),
+	}
+	if *record != "" {
+		f := mustOpenAppend(*record, "record")
+		defer f.Close()
+		base.Recorder = session.NewWriter(f)
+	}
+	if *deadLetter != "" {
+		f := mustOpenAppend(*deadLetter, "dead-letter")
+		defer f.Close()
+		base.DeadLetter = polling.NewDeadLetterWriter(f)
+	}
+	var arch *archive.Archive
+	if *archivePath != "" {
+		arch = mustOpenArchive(*archivePath)
+		defer arch.Close()
+	}
+
+	// newPoller gives each bot its own exec hook, so --exec-reply answers
+	// through the bot that received the update.
+	newPoller := func(client *telegram.Client, tag polling.Tag) *polling.Poller {
+		opts := base
+		opts.Tag = tag
+		recorders := polling.MultiRecorder{newChatLearner(tokenOpt, client)}
+		if base.Recorder != nil {
+			recorders = append(recorders, base.Recorder)
+		}
+		if arch != nil {
+			recorders = append(recorders, attachArchive(arch, client))
+		}
+		opts.Recorder = recorders
+		if *execCmd != "" {
+			hookOpts := exechook.Options{
+				Command: *execCmd,
+				Timeout: *execTimeout,
+				Stdout:  os.Stderr,
+				Stderr:  os.Stderr,
+			}
+			if tag.Profile != "" {
+				hookOpts.Env = []string{"TG_PROFILE=" + tag.Profile, "TG_BOT=" + tag.Bot}
+			}
+			if *execReply {
+				hookOpts.Reply = func(ctx context.Context, chatID string, threadID int64, text string) error {
+					_, err := client.SendMessageToThread(ctx, chatID, threadID, text)
+					return err
+				}
+			}
+			opts.Handler = exechook.New(hookOpts)
+			opts.Workers = *execConcurrency
+			opts.Retry = polling.RetryPolicy{MaxAttempts: retryAttempts(fs, *maxAttempts, *once), Backoff: *retryBackoff}
+		}
+		return polling.New(client, opts)
+	}
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	if err := poller.Run(ctx, os.Stdout, os.Stderr); err != nil {
+	var pollers []*polling.Poller
+	if profiles == nil {
+		client := mustClient(tokenOpt)
+		var tag polling.Tag
+		if *envelope {
+			tag = polling.Tag{Profile: *tokenOpt.profile, Bot: mustBotName(ctx, client, "updates listen")}
+		}
+		pollers = append(pollers, newPoller(client, tag))
+	}
+	for _, profile := range profiles {
+		client := mustProfileClient(tokenOpt, profile)
+		pollers = append(pollers, newPoller(client, polling.Tag{Profile: profile, Bot: mustBotName(ctx, client, profile)}))
+	}
+
+	if polling.IsTableFormat(*outputFormat) {
+		writeTableHeader(*outputFormat, tableColumns)
+	}
+	if err := polling.RunMany(ctx, pollers, os.Stdout, os.Stderr); err != nil {
 		if errors.Is(err, context.Canceled) {
 			return
 		}
@@ -91,13 +219,17 @@ func runUpdatesList(args []string) {
 	timeout := fs.Int("timeout", 0, "getUpdates timeout in seconds (default 0 for one-shot)")
 	offset := fs.Int64("offset", 0, "initial update offset")
 	deleteWebhook := fs.Bool("delete-webhook", true, "delete webhook before listing")
-	outputFormat := fs.String("format", "pretty", "updates output format: pretty|jsonl")
+	outputFormat := fs.String("format", "pretty", "updates output format: pretty|jsonl|csv|tsv")
+	columns := fs.String("columns", "", "comma-separated csv/tsv columns: named columns or JSON paths such as message.from.language_code")
+	envelope := fs.Bool("envelope", false, "wrap each update as {received_at,bot,profile,source,update}")
+	threadID := fs.Int64("thread-id", 0, "only list updates from this forum topic (message_thread_id)")
 	tokenOpt := registerTokenFlags(fs)
 
 	_ = fs.Parse(args)
 	if *limit <= 0 {
 		fatal("--limit must be greater than 0")
 	}
+	tableColumns := mustTableColumns(*outputFormat, *columns, *envelope)
 
 	ctx := context.Background()
 	client := mustClient(tokenOpt)
@@ -108,6 +240,9 @@ func runUpdatesList(args []string) {
 		}
 	}
 
+	learner := newChatLearner(tokenOpt, client)
+	topics := polling.TopicNames{}
+	filter := threadFilter(*threadID)
 	recent := make([]telegram.Update, 0, *limit)
 	currentOffset := *offset
 	for {
@@ -118,11 +253,17 @@ func runUpdatesList(args []string) {
 		if len(updates) == 0 {
 			break
 		}
+		receivedAt := time.Now()
 
 		for _, update := range updates {
-			recent = append(recent, update)
-			if len(recent) > *limit {
-				recent = recent[len(recent)-*limit:]
+			update.ReceivedAt = receivedAt
+			topics.Learn(&update)
+			_ = learner.Record(update, receivedAt)
+			if filter == nil || filter(update) {
+				recent = append(recent, update)
+				if len(recent) > *limit {
+					recent = recent[len(recent)-*limit:]
+				}
 			}
 			if update.UpdateID >= currentOffset {
 				currentOffset = update.UpdateID + 1
@@ -130,8 +271,23 @@ func runUpdatesList(args []string) {
 		}
 	}
 
+	var tag polling.Tag
+	if *envelope {
+		tag = polling.Tag{Profile: *tokenOpt.profile, Bot: mustBotName(ctx, client, "updates list")}
+	}
+	table := polling.IsTableFormat(*outputFormat)
+	if table {
+		writeTableHeader(*outputFormat, tableColumns)
+	}
 	for _, update := range recent {
 		formatted, err := polling.FormatUpdate(update.Raw, *outputFormat)
+		formatted = polling.WithTopicHeader(formatted, update, *outputFormat)
+		switch {
+		case table:
+			formatted, err = polling.FormatUpdateRow(update, *outputFormat, tableColumns, tag)
+		case *envelope:
+			formatted, err = polling.FormatEnvelope(polling.NewEnvelope(update, polling.SourcePolling, tag), *outputFormat)
+		}
 		if err != nil {
 			fatalf("format update failed: %v", err)
 		}
@@ -141,9 +297,54 @@ func runUpdatesList(args []string) {
 	}
 }
 
+// mustOpenAppend opens a jsonl file such as a --record or --dead-letter
+// file for appending, creating it if needed.
+func mustOpenAppend(path, what string) *os.File {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		fatalf("open %s file: %v", what, err)
+	}
+	return f
+}
+
+// mustTableColumns validates the csv/tsv flags and returns the --columns
+// list, nil for the defaults.
+func mustTableColumns(outputFormat, columns string, envelope bool) []string {
+	table := polling.IsTableFormat(outputFormat)
+	if columns != "" && !table {
+		fatal("--columns requires --format csv or tsv")
+	}
+	if envelope && table {
+		fatal("--envelope cannot be combined with --format csv or tsv; add received_at,bot,profile to --columns instead")
+	}
+	if columns == "" {
+		return nil
+	}
+	var out []string
+	for _, col := range strings.Split(columns, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			out = append(out, col)
+		}
+	}
+	if len(out) == 0 {
+		fatal("--columns must name at least one column")
+	}
+	return out
+}
+
+func writeTableHeader(outputFormat string, columns []string) {
+	header, err := polling.FormatHeader(outputFormat, columns)
+	if err != nil {
+		fatalf("format header failed: %v", err)
+	}
+	if _, err := os.Stdout.Write(header); err != nil {
+		fatalf("write output failed: %v", err)
+	}
+}
+
 func runBot(args []string) {
 	if len(args) == 0 {
-		fatal("usage: tgbot bot <me> [flags]")
+		fatal("usage: tgbot bot <me|logout|close> [flags]")
 	}
 	sub := args[0]
 	switch sub {
@@ -157,25 +358,55 @@ func runBot(args []string) {
 			fatalf("bot me failed: %v", err)
 		}
 		printJSON(res)
+	case "logout", "close":
+		runBotMigrate(sub, args[1:])
 	default:
-		fatal("usage: tgbot bot <me> [flags]")
+		fatal("usage: tgbot bot <me|logout|close> [flags]")
+	}
+}
+
+// runBotMigrate wraps logOut and close, used to move a bot between the cloud
+// Bot API and a local server. Both are disruptive, so they require --yes.
+func runBotMigrate(sub string, args []string) {
+	fs := baseFlagSet("bot " + sub)
+	yes := fs.Bool("yes", false, "confirm the call")
+	tokenOpt := registerTokenFlags(fs)
+	_ = fs.Parse(args)
+	if !*yes {
+		if sub == "logout" {
+			fatal("bot logout logs the bot out of the cloud Bot API (it cannot log back in for 10 minutes); rerun with --yes")
+		}
+		fatal("bot close shuts the bot instance down on the current server (not allowed in the first 10 minutes after launch); rerun with --yes")
+	}
+	client := mustClient(tokenOpt)
+	call, method := client.LogOut, "logOut"
+	if sub == "close" {
+		call, method = client.Close, "close"
 	}
+	if err := call(context.Background()); err != nil {
+		fatalf("bot %s failed: %v", sub, err)
+	}
+	printJSON(mustMarshal(map[string]any{"ok": true, "method": method}))
 }
 
 func runMessage(args []string) {
 	if len(args) == 0 || args[0] != "send" {
-		fatal("usage: tgbot message send --chat-id <id> --text <text> [flags]")
+		fatal("usage: tgbot message send --chat-id <id>|--chat <name> --text <text> [flags]")
 	}
 	fs := baseFlagSet("message send")
 	tokenOpt := registerTokenFlags(fs)
-	chatID := fs.String("chat-id", "", "target chat id")
+	chat := registerChatFlags(fs)
 	text := fs.String("text", "", "message text")
+	threadID := fs.Int64("thread-id", 0, "send to this forum topic (message_thread_id)")
+	archivePath := fs.String("archive", "", "store the sent message in this SQLite database (see tgbot archive)")
 	_ = fs.Parse(args[1:])
-	if *chatID == "" || *text == "" {
-		fatal("--chat-id and --text are required")
+	if chat.ref() == "" || *text == "" {
+		fatal("--chat-id and --text are required (--chat may replace --chat-id)")
 	}
 	client := mustClient(tokenOpt)
-	res, err := client.SendMessage(context.Background(), *chatID, *text)
+	chatID := mustResolveChatFlags(tokenOpt, client, chat)
+	defer archiveClient(*archivePath, client)()
+	res, err := client.SendMessageToThread(context.Background(), chatID, *threadID, *text)
 	if err != nil {
 		fatalf("message send failed: %v", err)
 	}
@@ -187,6 +418,19 @@ type tokenFlagOptions struct {
 	configPath *string
 	profile    *string
 	apiBase    *string
+	debug      *bool
+	traceFile  *string
+
+	proxy              *string
+	caFile             *string
+	clientCert         *string
+	clientKey          *string
+	insecureSkipVerify *bool
+	dialTimeout        *time.Duration
+	idleTimeout        *time.Duration
+	requestTimeout     *time.Duration
+	localAPI           *bool
+	testEnv            *bool
 }
 
 func registerTokenFlags(fs *flag.FlagSet) tokenFlagOptions {
@@ -195,6 +439,19 @@ func registerTokenFlags(fs *flag.FlagSet) tokenFlagOptions {
 		configPath: fs.String("config", "", "config path (default ~/.tgbot-cli/config.json)"),
 		profile:    fs.String("profile", "", "config profile name (defaults to active_profile)"),
 		apiBase:    fs.String("api-base", "https://api.telegram.org", "telegram api base"),
+		debug:      fs.Bool("debug", false, "log every api request and response to stderr"),
+		traceFile:  fs.String("trace-file", "", "record every api exchange to a HAR file"),
+
+		proxy:              fs.String("proxy", "", "proxy url (http://, https:// or socks5://); defaults to profile, then HTTPS_PROXY"),
+		caFile:             fs.String("ca-file", "", "extra PEM CA bundle to trust"),
+		clientCert:         fs.String("client-cert", "", "client TLS certificate (PEM)"),
+		clientKey:          fs.String("client-key", "", "client TLS private key (PEM)"),
+		insecureSkipVerify: fs.Bool("insecure-skip-verify", false, "skip TLS verification (local testing only)"),
+		dialTimeout:        fs.Duration("dial-timeout", 0, "connection dial timeout (default 30s)"),
+		idleTimeout:        fs.Duration("idle-timeout", 0, "idle keep-alive connection timeout (default 90s)"),
+		localAPI:           fs.Bool("local-api", false, "api base is a local telegram-bot-api server started with --local"),
+		testEnv:            fs.Bool("test-env", false, "use Telegram's test environment (/bot<token>/test/<method>)"),
+		requestTimeout:     fs.Duration("request-timeout", 0, "deadline for ordinary api calls (default 30s; getUpdates adds its long-poll timeout, uploads get at least 10m)"),
 	}
 }
 
@@ -207,13 +464,93 @@ func mustClient(opts tokenFlagOptions) *telegram.Client {
 	if err != nil {
 		fatalf("resolve token: %v", err)
 	}
-	return telegram.NewClient(*opts.apiBase, resolvedToken)
+	return mustClientWithToken(opts, *opts.profile, resolvedToken)
+}
+
+func mustClientWithToken(opts tokenFlagOptions, profile, resolvedToken string) *telegram.Client {
+	redactor.Add(resolvedToken)
+	settings, err := config.LoadProfileSettings(config.TokenOptions{
+		ConfigPath: *opts.configPath,
+		Profile:    profile,
+	})
+	if err != nil {
+		fatalf("load profile settings: %v", err)
+	}
+	client, err := telegram.NewClientWithOptions(telegram.ClientOptions{
+		APIBase:            *opts.apiBase,
+		Token:              resolvedToken,
+		ProxyURL:           firstNonEmpty(*opts.proxy, settings.Proxy),
+		CAFile:             firstNonEmpty(*opts.caFile, settings.CAFile),
+		ClientCertFile:     firstNonEmpty(*opts.clientCert, settings.ClientCert),
+		ClientKeyFile:      firstNonEmpty(*opts.clientKey, settings.ClientKey),
+		InsecureSkipVerify: *opts.insecureSkipVerify || settings.InsecureSkipVerify,
+		DialTimeout:        firstPositive(*opts.dialTimeout, time.Duration(settings.DialTimeout)),
+		IdleConnTimeout:    firstPositive(*opts.idleTimeout, time.Duration(settings.IdleConnTimeout)),
+		Timeouts: telegram.Timeouts{
+			Request: firstPositive(*opts.requestTimeout, time.Duration(settings.RequestTimeout)),
+		},
+		LocalAPI: *opts.localAPI || settings.LocalAPI,
+		TestEnv:  *opts.testEnv || settings.TestEnv,
+	})
+	if err != nil {
+		fatalf("configure client: %v", err)
+	}
+	if *opts.traceFile != "" {
+		client.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+			rec, err := telegram.NewHARRecorder(next, *opts.traceFile, client.Redactor())
+			if err != nil {
+				fatalf("open trace file: %v", err)
+			}
+			return rec
+		})
+	}
+	if *opts.debug {
+		client.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+			return telegram.NewDebugTransport(next, os.Stderr, client.Redactor())
+		})
+	}
+	return client
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func firstPositive(values ...time.Duration) time.Duration {
+	for _, v := range values {
+		if v > 0 {
+			return v
+		}
+	}
+	return 0
 }
 
 func baseFlagSet(name string) *flag.FlagSet {
 	return flag.NewFlagSet(name, flag.ExitOnError)
 }
 
+// flagGiven reports whether the flag name was set on the command line.
+func flagGiven(fs *flag.FlagSet, name string) bool {
+	given := false
+	fs.Visit(func(f *flag.Flag) { given = given || f.Name == name })
+	return given
+}
+
+// retryAttempts is the --max-attempts value to poll with: a single polling
+// cycle confirms nothing and so cannot retry, making the default 1 with
+// --once.
+func retryAttempts(fs *flag.FlagSet, maxAttempts int, once bool) int {
+	if once && !flagGiven(fs, "max-attempts") {
+		return 1
+	}
+	return maxAttempts
+}
+
 func printJSON(raw []byte) {
 	var out any
 	if err := json.Unmarshal(raw, &out); err != nil {
@@ -228,20 +565,92 @@ func printJSON(raw []byte) {
 	fmt.Println(string(formatted))
 }
 
+func mustMarshal(v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fatalf("encode output: %v", err)
+	}
+	return data
+}
+
 func printUsage() {
 	fmt.Print(`tgbot - Telegram Bot CLI
 
 Usage:
   tgbot updates listen [flags]
   tgbot updates list [flags]
+  tgbot updates inject --to <webhook-url> [--type message] [--text <text>] [flags]
+  tgbot updates replay <session.jsonl> --to <webhook-url>|--stdout [--speed 10x] [flags]
   tgbot bot me [flags]
-  tgbot message send --chat-id <id> --text <text> [flags]
+  tgbot message send --chat-id <id>|--chat <name> --text <text> [--thread-id id] [flags]
+  tgbot chats list|show <chat>|alias <name> <chat> [flags]
+  tgbot chat info|admins|members count <chat> [--format table|json] [flags]
+  tgbot chat member <chat> <user> [--format table|json] [flags]
+  tgbot chat ban|unban|restrict|promote <chat> [user...] [--from-file users.txt] [flags]
+  tgbot chat set-admin-title <chat> <title> [user...] [--from-file users.txt] [flags]
+  tgbot chat set-title|set-description|set-photo|delete-photo|set-permissions|pin|unpin|unpin-all|leave <chat> [args] [flags]
+  tgbot chat invite create|edit|revoke|export|list-export <chat> [link] [flags]
+  tgbot chat join-requests watch --rules rules.json [--dry-run] [flags]
+  tgbot topic create <chat> <name> [--icon-color red] [--icon-emoji id] [flags]
+  tgbot topic edit|close|reopen|delete <chat> <thread_id|general> [flags]
+  tgbot topic hide|unhide <chat> [flags]
+  tgbot topic list-icons [flags]
+  tgbot api call <method> [--param key=value ...] [--json @body.json] [--file field=@path ...] [flags]
+  tgbot file download <file_id> [--out path] [flags]
+  tgbot bot logout|close --yes [flags]
+  tgbot mock-server [--listen 127.0.0.1:8081] [--state file.json]
+  tgbot mock-server inject --text <text> [--from alice] [--server http://localhost:8081]
+  tgbot archive query|stats|export [--db bot.db] [flags]
 
 Example:
   tgbot updates listen --interval 3s --timeout 20 --format pretty
   tgbot updates list --limit 20 --format pretty
+  tgbot updates list --limit 100 --format csv --columns update_id,date,chat_id,text
+  tgbot updates listen --record session.jsonl
+  tgbot updates listen --exec ./handler.sh --exec-reply --exec-concurrency 4
+  tgbot updates listen --exec ./handler.sh --max-attempts 5 --dead-letter failed.jsonl
+  tgbot updates listen --profile dev,staging --format jsonl
+  tgbot updates listen --archive bot.db
+  tgbot updates replay session.jsonl --to http://localhost:8080/hook --speed 10x
+  tgbot updates inject --to http://localhost:8080/hook --text "/start ref123" --from-id 42 --secret s3cret
   tgbot bot me
   tgbot message send --chat-id 12345 --text "hello"
+  tgbot chats alias dev -1001234567890
+  tgbot message send --chat dev --text "deployed"
+  tgbot chat info dev
+  tgbot chat member dev 42 --format json
+  tgbot chat ban dev --from-file spammers.txt --until 24h --revoke-messages
+  tgbot chat restrict dev 42 --allow send_messages --until 1h
+  tgbot chat invite create dev --name launch --expire 24h --member-limit 100
+  tgbot chat set-photo dev ./logo.png
+  tgbot chat pin dev 1234 --silent
+  tgbot chat join-requests watch --rules rules.json
+  tgbot topic create dev "Bug reports" --icon-color red
+  tgbot message send --chat dev --thread-id 42 --text "looking into it"
+  tgbot updates listen --thread-id 42 --format csv --columns date,topic,username,text
+  tgbot api call getChat --param chat_id=12345
+  tgbot api call sendPhoto --param chat_id=12345 --file photo=@cat.jpg
+  tgbot archive query "SELECT chat_id, count(*) FROM messages GROUP BY chat_id"
+  tgbot archive export --chat-id 12345 --since 24h --out chat.jsonl
+  tgbot archive export --chat-id 12345 --format html --out transcript.html
+
+Local Bot API server:
+  --local-api          api base is a local telegram-bot-api (--local): downloads read
+                       from disk, uploads are sent as file:// URIs, 2000 MB upload limit
+
+Test environment:
+  --test-env           send requests to Telegram's test DC (also "test_env": true in a profile)
+
+Debugging:
+  --debug              log api method, params, status, latency and body to stderr
+  --trace-file out.har record full api exchanges in HAR format
+
+Network:
+  --proxy URL          http://, https:// or socks5:// proxy
+  --ca-file PATH       extra CA bundle (e.g. internal Bot API mirror)
+  --client-cert/--client-key, --insecure-skip-verify, --dial-timeout, --idle-timeout
+  --request-timeout D  deadline for ordinary calls; long polls and uploads scale from it
+  Each may also be set per profile in the config file.
 
 Token resolution order:
   1) --token
@@ -251,11 +660,11 @@ Token resolution order:
 }
 
 func fatal(msg string) {
-	fmt.Fprintln(os.Stderr, msg)
+	fmt.Fprintln(os.Stderr, redactor.String(msg))
 	os.Exit(1)
 }
 
 func fatalf(format string, args ...any) {
-	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	fmt.Fprintln(os.Stderr, redactor.String(fmt.Sprintf(format, args...)))
 	os.Exit(1)
 }
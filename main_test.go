@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const leakToken = "987654:LEAK-me-not"
+
+// TestMain re-executes the test binary as the CLI when TGBOT_CLI_MAIN is set,
+// so tests can exercise main() including its os.Exit paths.
+func TestMain(m *testing.M) {
+	if os.Getenv("TGBOT_CLI_MAIN") == "1" {
+		var args []string
+		if err := json.Unmarshal([]byte(os.Getenv("TGBOT_CLI_ARGS")), &args); err != nil {
+			panic(err)
+		}
+		os.Args = append([]string{"tgbot"}, args...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+type cliResult struct {
+	stdout string
+	stderr string
+	code   int
+}
+
+func runCLI(t *testing.T, env []string, args ...string) cliResult {
+	t.Helper()
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		t.Fatalf("encode args: %v", err)
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "TGBOT_CLI_MAIN=1", "TGBOT_CLI_ARGS="+string(encoded), "HOME="+t.TempDir())
+	cmd.Env = append(cmd.Env, env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	res := cliResult{stdout: stdout.String(), stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		res.code = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("run cli: %v", err)
+	}
+	return res
+}
+
+// tokenCommands lists every command that resolves a token, with the extra
+// arguments it needs to reach the network.
+var tokenCommands = [][]string{
+	{"bot", "me"},
+	{"message", "send", "--chat-id", "1", "--text", "hi"},
+	{"updates", "list", "--delete-webhook=false"},
+	{"updates", "list"},
+	{"updates", "listen", "--once", "--delete-webhook=false"},
+	{"updates", "listen", "--once"},
+	{"api", "call", "getChat", "--param", "chat_id=1"},
+	{"file", "download", "file-id"},
+	{"bot", "logout", "--yes"},
+	{"chat", "info", "1"},
+	{"topic", "create", "1", "Support"},
+	{"chat", "invite", "create", "1"},
+	{"chat", "join-requests", "watch", "1", "--once", "--dry-run"},
+}
+
+func assertNoLeak(t *testing.T, args []string, res cliResult) {
+	t.Helper()
+	if strings.Contains(res.stdout, leakToken) || strings.Contains(res.stderr, leakToken) {
+		t.Fatalf("%v leaked token:\nstdout: %s\nstderr: %s", args, res.stdout, res.stderr)
+	}
+	if strings.Contains(res.stderr, "LEAK-me-not") {
+		t.Fatalf("%v leaked token secret:\nstderr: %s", args, res.stderr)
+	}
+}
+
+func TestNoTokenLeakOnNetworkErrors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	base := srv.URL
+	srv.Close()
+
+	for _, args := range tokenCommands {
+		full := append(append([]string{}, args...), "--token", leakToken, "--api-base", base)
+		res := runCLI(t, nil, full...)
+		if res.code == 0 {
+			t.Fatalf("%v: expected failure against closed server", args)
+		}
+		assertNoLeak(t, args, res)
+	}
+}
+
+func TestNoTokenLeakOnAPIErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"description":"unauthorized for ` + r.URL.String() + `"}`))
+	}))
+	defer srv.Close()
+
+	for _, args := range tokenCommands {
+		full := append(append([]string{}, args...), "--api-base", srv.URL)
+		res := runCLI(t, []string{"TG_BOT_TOKEN=" + leakToken}, full...)
+		if res.code == 0 {
+			t.Fatalf("%v: expected failure on api error", args)
+		}
+		assertNoLeak(t, args, res)
+	}
+}
+
+func TestNoTokenLeakFromConfigProfile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`not json ` + r.URL.Path))
+	}))
+	defer srv.Close()
+
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	payload := `{"active_profile":"dev","profiles":{"dev":{"token":"` + leakToken + `"}}}`
+	if err := os.WriteFile(cfg, []byte(payload), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	for _, args := range tokenCommands {
+		full := append(append([]string{}, args...), "--config", cfg, "--api-base", srv.URL)
+		res := runCLI(t, []string{"TG_BOT_TOKEN="}, full...)
+		if res.code == 0 {
+			t.Fatalf("%v: expected failure on malformed response", args)
+		}
+		assertNoLeak(t, args, res)
+	}
+}
+
+func TestNoTokenLeakOffline(t *testing.T) {
+	res := runCLI(t, nil, "chats", "list", "--token", leakToken)
+	if res.code != 0 {
+		t.Fatalf("chats list failed: %s", res.stderr)
+	}
+	assertNoLeak(t, []string{"chats", "list"}, res)
+}
+
+func TestNoTokenLeakFromSeveralProfiles(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":false,"description":"unauthorized for ` + r.URL.String() + `"}`))
+	}))
+	defer srv.Close()
+
+	const otherToken = "987654:OTHER-secret"
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	payload := `{"profiles":{"dev":{"token":"` + leakToken + `"},"staging":{"token":"` + otherToken + `"}}}`
+	if err := os.WriteFile(cfg, []byte(payload), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	for _, profiles := range []string{"--profile=dev,staging", "--all-profiles"} {
+		args := []string{"updates", "listen", "--once", profiles, "--config", cfg, "--api-base", srv.URL}
+		res := runCLI(t, []string{"TG_BOT_TOKEN="}, args...)
+		if res.code == 0 {
+			t.Fatalf("%v: expected failure on api error", args)
+		}
+		assertNoLeak(t, args, res)
+		if strings.Contains(res.stdout+res.stderr, "OTHER-secret") {
+			t.Fatalf("%v leaked the second profile's token:\nstderr: %s", args, res.stderr)
+		}
+	}
+}
+
+func TestNoTokenLeakInDebugAndTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"ok":true,"result":{"echo":"` + r.URL.Path + `"}}`))
+	}))
+	defer srv.Close()
+
+	harPath := filepath.Join(t.TempDir(), "trace.har")
+	res := runCLI(t, nil, "bot", "me", "--token", leakToken, "--api-base", srv.URL, "--debug", "--trace-file", harPath)
+	if res.code != 0 {
+		t.Fatalf("bot me failed: %s", res.stderr)
+	}
+	if !strings.Contains(res.stderr, "[debug] POST getMe") {
+		t.Fatalf("expected debug log on stderr, got %q", res.stderr)
+	}
+	if strings.Contains(res.stderr, leakToken) {
+		t.Fatalf("debug log leaked token: %s", res.stderr)
+	}
+	har, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	if strings.Contains(string(har), leakToken) {
+		t.Fatalf("trace file leaked token")
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+const mockServerUsage = "usage: tgbot mock-server [--listen 127.0.0.1:8081] [--state file.json] [flags]\n       tgbot mock-server inject --text <text> [--from alice] [--server http://localhost:8081] [flags]"
+
+func runMockServer(args []string) {
+	if len(args) > 0 && args[0] == "inject" {
+		runMockServerInject(args[1:])
+		return
+	}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		fatal(mockServerUsage)
+	}
+
+	fs := baseFlagSet("mock-server")
+	listen := fs.String("listen", "127.0.0.1:8081", "listen address; use :8081 to accept connections from other hosts")
+	statePath := fs.String("state", "", "persist chats, messages and pending updates to this JSON file (default in memory)")
+	acceptToken := fs.String("accept-token", "", "only accept this bot token (default any token)")
+	retry := fs.Duration("webhook-retry", 5*time.Second, "delay before retrying a failed webhook delivery")
+	_ = fs.Parse(args)
+
+	handler := telegramtest.NewHandler(*acceptToken)
+	if *statePath != "" {
+		if err := loadMockState(handler, *statePath); err != nil {
+			fatalf("load state: %v", err)
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(telegramtest.AdminPrefix, handler.AdminHandler())
+	mux.Handle("/", logMockCalls(handler, os.Stderr))
+
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		_ = handler.RunWebhookDelivery(ctx, &http.Client{Timeout: 30 * time.Second}, *retry)
+	}()
+	saved := make(chan struct{})
+	go func() {
+		defer close(saved)
+		persistMockState(ctx, handler, *statePath)
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "[info] mock Bot API listening on http://%s (admin API under %s)\n", ln.Addr(), telegramtest.AdminPrefix)
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fatalf("mock-server failed: %v", err)
+	}
+	<-saved
+}
+
+// logMockCalls logs the method of every Bot API call. Only the method is
+// printed, since the path also carries the token.
+func logMockCalls(next http.Handler, out io.Writer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(out, "[mock] %s %s\n", r.Method, path.Base(r.URL.Path))
+		next.ServeHTTP(w, r)
+	})
+}
+
+func loadMockState(h *telegramtest.Handler, statePath string) error {
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var st telegramtest.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("parse %s: %w", statePath, err)
+	}
+	h.Restore(st)
+	return nil
+}
+
+// persistMockState writes the handler state whenever it changes, and once
+// more when ctx ends.
+func persistMockState(ctx context.Context, h *telegramtest.Handler, statePath string) {
+	if statePath == "" {
+		<-ctx.Done()
+		return
+	}
+	saved := h.Revision()
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := saveMockState(h, statePath); err != nil {
+				fmt.Fprintf(os.Stderr, "[warn] save state: %v\n", err)
+			}
+			return
+		case <-ticker.C:
+			if rev := h.Revision(); rev != saved {
+				if err := saveMockState(h, statePath); err != nil {
+					fmt.Fprintf(os.Stderr, "[warn] save state: %v\n", err)
+					continue
+				}
+				saved = rev
+			}
+		}
+	}
+}
+
+func saveMockState(h *telegramtest.Handler, statePath string) error {
+	data, err := json.MarshalIndent(h.Snapshot(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(statePath), ".mock-state-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), statePath)
+}
+
+func runMockServerInject(args []string) {
+	fs := baseFlagSet("mock-server inject")
+	server := fs.String("server", "http://localhost:8081", "mock-server base url")
+	text := fs.String("text", "", "message text")
+	from := fs.String("from", "", "sender username (created on first use; default alice)")
+	fromID := fs.Int64("from-id", 0, "sender user id; without --from, a separate user rather than alice; must match a known --from user")
+	chatID := fs.Int64("chat-id", 0, "target chat id (default the sender's private chat)")
+	chatType := fs.String("chat-type", "", "chat type: private|group|supergroup|channel")
+	chatTitle := fs.String("chat-title", "", "chat title for group chats")
+	_ = fs.Parse(args)
+	if *text == "" {
+		fatal("--text is required")
+	}
+
+	body, err := json.Marshal(telegramtest.InjectRequest{
+		Text:      *text,
+		From:      *from,
+		FromID:    *fromID,
+		ChatID:    *chatID,
+		ChatType:  *chatType,
+		ChatTitle: *chatTitle,
+	})
+	if err != nil {
+		fatalf("encode inject request: %v", err)
+	}
+	endpoint := strings.TrimRight(*server, "/") + telegramtest.AdminPrefix + "inject"
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		fatalf("inject failed: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		fatalf("inject failed: %v", err)
+	}
+	defer resp.Body.Close()
+	res, err := io.ReadAll(resp.Body)
+	if err != nil {
+		fatalf("inject failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		fatalf("inject failed: %s: %s", resp.Status, bytes.TrimSpace(res))
+	}
+	printJSON(res)
+}
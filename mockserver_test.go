@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+	_ = ln.Close()
+	return addr
+}
+
+// startCLI runs the CLI in the background; the returned command must be
+// stopped by the caller.
+func startCLI(t *testing.T, args ...string) *exec.Cmd {
+	t.Helper()
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		t.Fatalf("encode args: %v", err)
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "TGBOT_CLI_MAIN=1", "TGBOT_CLI_ARGS="+string(encoded))
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("start cli: %v", err)
+	}
+	return cmd
+}
+
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			_ = conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server at %s did not start", addr)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestCommandMockServer(t *testing.T) {
+	addr := freeAddr(t)
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	server := startCLI(t, "mock-server", "--listen", addr, "--state", statePath)
+	waitListening(t, addr)
+	base := "http://" + addr
+
+	runOK(t, "mock-server", "inject", "--server", base, "--text", "hi from alice", "--from", "alice")
+
+	flags := []string{"--token", "555:any", "--api-base", base}
+	res := runOK(t, append([]string{"updates", "list", "--format", "jsonl"}, flags...)...)
+	if !strings.Contains(res.stdout, `"text":"hi from alice"`) || !strings.Contains(res.stdout, `"username":"alice"`) {
+		t.Fatalf("expected injected update, got %q", res.stdout)
+	}
+	runOK(t, append([]string{"message", "send", "--chat-id", "42", "--text", "hello alice"}, flags...)...)
+
+	_ = server.Process.Signal(syscall.SIGINT)
+	_ = server.Wait()
+
+	data, err := os.ReadFile(statePath)
+	if err != nil {
+		t.Fatalf("read state: %v", err)
+	}
+	var st telegramtest.State
+	if err := json.Unmarshal(data, &st); err != nil {
+		t.Fatalf("parse state: %v", err)
+	}
+	if len(st.Messages) != 2 || len(st.Chats) != 1 {
+		t.Fatalf("expected both messages and the chat persisted, got %+v", st)
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// moderationFlags select the users a moderation command acts on, besides
+// those given as arguments.
+type moderationFlags struct {
+	fromFile *string
+}
+
+func registerModerationFlags(fs *flag.FlagSet, f *moderationFlags) {
+	f.fromFile = fs.String("from-file", "", "file with one user per line (id or known private chat); '#' starts a comment, - reads stdin")
+}
+
+// mustUserRefs collects the user references from args and --from-file.
+func (f *moderationFlags) mustUserRefs(args []string) []string {
+	refs := append([]string(nil), args...)
+	if *f.fromFile != "" {
+		fromFile, err := readUserFile(*f.fromFile)
+		if err != nil {
+			fatalf("read --from-file: %v", err)
+		}
+		refs = append(refs, fromFile...)
+	}
+	return refs
+}
+
+// readUserFile reads the first field of every line of path, skipping blank
+// lines and comments, so "42  # spammer" names user 42.
+func readUserFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	var refs []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if fields := strings.Fields(line); len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// moderationResult is one user's line in the report.
+type moderationResult struct {
+	User   string `json:"user"`
+	UserID int64  `json:"user_id,omitempty"`
+	OK     bool   `json:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// moderate applies action to every user in refs, carrying on past
+// failures, prints a report and exits non-zero if any user failed.
+func moderate(cmd chatCommand, refs []string, action func(ctx context.Context, userID int64) error) {
+	if len(refs) == 0 {
+		fatal("no users given: pass user ids as arguments or use --from-file")
+	}
+	ctx := context.Background()
+	resolver := userResolver{opts: cmd.tokenOpt, client: cmd.client, strict: true}
+	results := make([]moderationResult, 0, len(refs))
+	failed := 0
+	for _, ref := range refs {
+		res := moderationResult{User: ref}
+		id, warning, err := resolver.resolve(ref)
+		if warning != "" {
+			fmt.Fprintf(os.Stderr, "[warn] %s\n", warning)
+		}
+		if err == nil {
+			res.UserID = id
+			err = action(ctx, id)
+		}
+		if err != nil {
+			res.Error = err.Error()
+			failed++
+		} else {
+			res.OK = true
+		}
+		results = append(results, res)
+	}
+
+	if cmd.format == "json" {
+		printJSON(mustMarshal(map[string]any{
+			"ok":        failed == 0,
+			"succeeded": len(results) - failed,
+			"failed":    failed,
+			"results":   results,
+		}))
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "USER\tUSER ID\tRESULT")
+		for _, r := range results {
+			result := "ok"
+			if !r.OK {
+				result = "failed: " + r.Error
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", r.User, r.UserID, result)
+		}
+		_ = tw.Flush()
+	}
+	if failed > 0 {
+		fatalf("%d of %d users failed", failed, len(results))
+	}
+}
+
+// parseUntil turns --until or --expire into an absolute time: a duration
+// from now such as 24h, or an RFC 3339 time. Empty means forever, the zero
+// time.
+func parseUntil(s string, now time.Time) (time.Time, error) {
+	if s == "" || s == "forever" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if d, derr := time.ParseDuration(s); derr == nil {
+		t, err = now.Add(d), nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q (want e.g. 24h or 2024-05-01T00:00:00Z)", s)
+	}
+	if !t.After(now) {
+		return time.Time{}, fmt.Errorf("time %q is not in the future", s)
+	}
+	return t, nil
+}
+
+// Telegram treats a ban or restriction of less than 30 seconds or more than
+// 366 days from now as permanent.
+const (
+	minRestriction = 30 * time.Second
+	maxRestriction = 366 * 24 * time.Hour
+)
+
+// parseRestrictionUntil is parseUntil for the --until of ban and restrict,
+// refusing times Telegram would silently turn into forever.
+func parseRestrictionUntil(s string, now time.Time) (time.Time, error) {
+	until, err := parseUntil(s, now)
+	if err != nil || until.IsZero() {
+		return until, err
+	}
+	if d := until.Sub(now); d < minRestriction || d > maxRestriction {
+		return time.Time{}, fmt.Errorf("time %q is outside the 30s to 366 days Telegram accepts and would be applied forever (omit --until for that)", s)
+	}
+	return until, nil
+}
+
+func mustParseUntil(flagName, s string, parse func(string, time.Time) (time.Time, error)) time.Time {
+	until, err := parse(s, time.Now())
+	if err != nil {
+		fatalf("--%s: %v", flagName, err)
+	}
+	return until
+}
+
+// setNamedFlags sets the boolean fields of target, a pointer to a struct
+// such as telegram.ChatPermissions, named in list to value. Names are JSON
+// field names with or without the can_ prefix, comma separated; "all"
+// names every can_* field.
+func setNamedFlags(target any, list string, value bool) error {
+	data, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+	fields := map[string]bool{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		switch _, known := fields["can_"+name]; {
+		case name == "":
+		case name == "all":
+			for k := range fields {
+				if strings.HasPrefix(k, "can_") {
+					fields[k] = value
+				}
+			}
+		case known:
+			fields["can_"+name] = value
+		default:
+			if _, ok := fields[name]; !ok || !strings.HasPrefix(name, "can_") {
+				return fmt.Errorf("unknown right %q (want all or one of %s)", name, strings.Join(knownRights(fields), ", "))
+			}
+			fields[name] = value
+		}
+	}
+	data, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+func knownRights(fields map[string]bool) []string {
+	var names []string
+	for k := range fields {
+		if name, ok := strings.CutPrefix(k, "can_"); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// mustPermissions builds ChatPermissions from --allow, which grants only
+// the listed permissions, or --deny, which grants all but the listed ones.
+func mustPermissions(allow, deny string) telegram.ChatPermissions {
+	if allow != "" && deny != "" {
+		fatal("use either --allow or --deny, not both")
+	}
+	var perms telegram.ChatPermissions
+	if deny != "" {
+		if err := setNamedFlags(&perms, "all", true); err != nil {
+			fatal(err.Error())
+		}
+	}
+	if err := setNamedFlags(&perms, firstNonEmpty(allow, deny), deny == ""); err != nil {
+		fatal(err.Error())
+	}
+	return perms
+}
+
+func runChatBan(args []string) {
+	var mf moderationFlags
+	var until *string
+	var revoke *bool
+	cmd := parseModerationCommand("chat ban", "usage: tgbot chat ban <chat> [user...] [--from-file users.txt] [--until 24h] [--revoke-messages] [flags]", args, -1, func(fs *flag.FlagSet) {
+		registerModerationFlags(fs, &mf)
+		until = fs.String("until", "", "ban for a duration (24h) or until an RFC 3339 time; default forever")
+		revoke = fs.Bool("revoke-messages", false, "also delete the user's messages in the chat")
+	})
+	untilTime := mustParseUntil("until", *until, parseRestrictionUntil)
+	moderate(cmd, mf.mustUserRefs(cmd.args), func(ctx context.Context, userID int64) error {
+		return cmd.client.BanChatMember(ctx, cmd.chatID, userID, untilTime, *revoke)
+	})
+}
+
+func runChatUnban(args []string) {
+	var mf moderationFlags
+	var onlyIfBanned *bool
+	cmd := parseModerationCommand("chat unban", "usage: tgbot chat unban <chat> [user...] [--from-file users.txt] [flags]", args, -1, func(fs *flag.FlagSet) {
+		registerModerationFlags(fs, &mf)
+		onlyIfBanned = fs.Bool("only-if-banned", true, "leave users who are not banned alone; false also removes current members")
+	})
+	moderate(cmd, mf.mustUserRefs(cmd.args), func(ctx context.Context, userID int64) error {
+		return cmd.client.UnbanChatMember(ctx, cmd.chatID, userID, *onlyIfBanned)
+	})
+}
+
+func runChatRestrict(args []string) {
+	var mf moderationFlags
+	var until, allow, deny *string
+	cmd := parseModerationCommand("chat restrict", "usage: tgbot chat restrict <chat> [user...] [--from-file users.txt] [--allow list|--deny list] [--until 24h] [flags]", args, -1, func(fs *flag.FlagSet) {
+		registerModerationFlags(fs, &mf)
+		until = fs.String("until", "", "restrict for a duration (24h) or until an RFC 3339 time; default forever")
+		allow = fs.String("allow", "", "permissions to keep, e.g. send_messages,send_photos; everything else is denied")
+		deny = fs.String("deny", "", "permissions to take away, e.g. send_polls,pin_messages; everything else is allowed")
+	})
+	// With neither flag every permission is denied, muting the users.
+	perms := mustPermissions(*allow, *deny)
+	untilTime := mustParseUntil("until", *until, parseRestrictionUntil)
+	moderate(cmd, mf.mustUserRefs(cmd.args), func(ctx context.Context, userID int64) error {
+		return cmd.client.RestrictChatMember(ctx, cmd.chatID, userID, perms, untilTime)
+	})
+}
+
+func runChatPromote(args []string) {
+	var mf moderationFlags
+	var rightsList *string
+	var anonymous, demote *bool
+	cmd := parseModerationCommand("chat promote", "usage: tgbot chat promote <chat> [user...] [--from-file users.txt] --rights list|--demote [flags]", args, -1, func(fs *flag.FlagSet) {
+		registerModerationFlags(fs, &mf)
+		rightsList = fs.String("rights", "", "administrator rights to grant, e.g. delete_messages,pin_messages, or all")
+		anonymous = fs.Bool("anonymous", false, "hide the administrator's name in the chat")
+		demote = fs.Bool("demote", false, "take every right away, making the users regular members")
+	})
+	var rights telegram.ChatAdministratorRights
+	switch {
+	case *demote && (*rightsList != "" || *anonymous):
+		fatal("--demote cannot be combined with --rights or --anonymous")
+	case !*demote && *rightsList == "":
+		fatal("--rights is required (or --demote)")
+	case !*demote:
+		if err := setNamedFlags(&rights, *rightsList, true); err != nil {
+			fatal(err.Error())
+		}
+		rights.IsAnonymous = *anonymous
+	}
+	moderate(cmd, mf.mustUserRefs(cmd.args), func(ctx context.Context, userID int64) error {
+		return cmd.client.PromoteChatMember(ctx, cmd.chatID, userID, rights)
+	})
+}
+
+// runChatSetAdminTitle sets the custom title of the administrators given as
+// arguments or in --from-file.
+func runChatSetAdminTitle(args []string) {
+	var mf moderationFlags
+	cmd := parseModerationCommand("chat set-admin-title", "usage: tgbot chat set-admin-title <chat> <title> [user...] [--from-file users.txt] [flags]", args, -1, func(fs *flag.FlagSet) {
+		registerModerationFlags(fs, &mf)
+	})
+	if len(cmd.args) == 0 {
+		fatal("usage: tgbot chat set-admin-title <chat> <title> [user...] [--from-file users.txt] [flags]")
+	}
+	title := cmd.args[0]
+	moderate(cmd, mf.mustUserRefs(cmd.args[1:]), func(ctx context.Context, userID int64) error {
+		return cmd.client.SetChatAdministratorCustomTitle(ctx, cmd.chatID, userID, title)
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func TestCommandChatModeration(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultUser, Status: "creator"})
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_restrict_members": true, "can_promote_members": true}})
+	for _, id := range []int64{7, 8} {
+		srv.SetMember(group, telegramtest.Member{User: telegramtest.User{ID: id}, Status: "member"})
+	}
+
+	users := filepath.Join(t.TempDir(), "users.txt")
+	if err := os.WriteFile(users, []byte("# spammers\n7\n8  # second wave\n\n42\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	res := runCLI(t, nil, append([]string{"chat", "ban", "-1001", "--from-file", users, "--until", "24h", "--revoke-messages", "--format", "json"}, flags...)...)
+	if res.code == 0 || !strings.Contains(res.stderr, "1 of 3 users failed") {
+		t.Fatalf("expected partial failure, got %d: %s", res.code, res.stderr)
+	}
+	var report struct {
+		Succeeded int `json:"succeeded"`
+		Results   []struct {
+			UserID int64  `json:"user_id"`
+			OK     bool   `json:"ok"`
+			Error  string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(res.stdout), &report); err != nil {
+		t.Fatalf("decode report %q: %v", res.stdout, err)
+	}
+	if report.Succeeded != 2 || len(report.Results) != 3 || report.Results[2].OK || !strings.Contains(report.Results[2].Error, "chat owner") {
+		t.Fatalf("unexpected report: %s", res.stdout)
+	}
+	if m := srv.Member(-1001, 7); m.Status != "kicked" || m.Fields["until_date"] == int64(0) {
+		t.Fatalf("unexpected banned member: %+v", m)
+	}
+	if n := srv.CallCount("banChatMember"); n != 3 {
+		t.Fatalf("expected 3 banChatMember calls, got %d", n)
+	}
+
+	res = runOK(t, append([]string{"chat", "unban", "-1001", "7", "8"}, flags...)...)
+	if strings.Count(res.stdout, " ok") != 2 || srv.Member(-1001, 8).Status != "left" {
+		t.Fatalf("unexpected unban report:\n%s", res.stdout)
+	}
+
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.User{ID: 7}, Status: "member"})
+	runOK(t, append([]string{"chat", "restrict", "-1001", "7", "--allow", "send_messages,can_send_photos"}, flags...)...)
+	if m := srv.Member(-1001, 7); m.Status != "restricted" || m.Fields["can_send_photos"] != true || m.Fields["can_send_polls"] != false {
+		t.Fatalf("unexpected restricted member: %+v", m)
+	}
+	bad := runCLI(t, nil, append([]string{"chat", "restrict", "-1001", "7", "--deny", "fly"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, `unknown right "fly"`) {
+		t.Fatalf("expected unknown right error, got %d: %s", bad.code, bad.stderr)
+	}
+
+	runOK(t, append([]string{"chat", "promote", "-1001", "7", "--rights", "pin_messages,delete_messages"}, flags...)...)
+	runOK(t, append([]string{"chat", "set-admin-title", "-1001", "night mod", "7"}, flags...)...)
+	if m := srv.Member(-1001, 7); m.Status != "administrator" || m.Fields["can_delete_messages"] != true || m.Fields["custom_title"] != "night mod" {
+		t.Fatalf("unexpected promoted member: %+v", m)
+	}
+	runOK(t, append([]string{"chat", "promote", "-1001", "7", "--demote"}, flags...)...)
+	if m := srv.Member(-1001, 7); m.Status != "member" {
+		t.Fatalf("unexpected demoted member: %+v", m)
+	}
+}
+
+func TestParseUntil(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if got, err := parseUntil("24h", now); err != nil || !got.Equal(now.Add(24*time.Hour)) {
+		t.Fatalf("parseUntil(24h) = %v, %v", got, err)
+	}
+	if got, err := parseUntil("", now); err != nil || !got.IsZero() {
+		t.Fatalf("parseUntil(\"\") = %v, %v", got, err)
+	}
+	if _, err := parseUntil("tomorrow", now); err == nil {
+		t.Fatal("expected error for invalid --until")
+	}
+	for _, in := range []string{"-1h", "0s", "2024-04-30T12:00:00Z"} {
+		if _, err := parseUntil(in, now); err == nil || !strings.Contains(err.Error(), "not in the future") {
+			t.Fatalf("parseUntil(%q) should reject a past time, got %v", in, err)
+		}
+	}
+	for in, ok := range map[string]bool{"30s": true, "8784h": true, "29s": false, "8785h": false, "2026-01-01T00:00:00Z": false, "": true} {
+		if _, err := parseRestrictionUntil(in, now); (err == nil) != ok {
+			t.Fatalf("parseRestrictionUntil(%q) error = %v, want ok %v", in, err, ok)
+		}
+	}
+}
+
+func TestModerationNeedsExactReferences(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	flags = append(flags, "--config", filepath.Join(t.TempDir(), "config.json"))
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Dev Group"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_restrict_members": true}})
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.User{ID: 7, FirstName: "Mallory", Username: "mallory"}, Status: "member"})
+	srv.EnqueueMessage(telegramtest.IncomingMessage{Chat: group, Text: "a"})
+	srv.EnqueueMessage(telegramtest.IncomingMessage{Chat: telegramtest.Chat{ID: 7, Type: "private", FirstName: "Mallory", Username: "mallory"}, Text: "b"})
+	runOK(t, append([]string{"updates", "list", "--format", "jsonl"}, flags...)...)
+
+	bad := runCLI(t, nil, append([]string{"chat", "ban", "dev", "7"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "exact @username") {
+		t.Fatalf("expected a title to be refused, got %d: %s", bad.code, bad.stderr)
+	}
+	bad = runCLI(t, nil, append([]string{"chat", "ban", "-1001", "Mall"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stdout, "unknown user") {
+		t.Fatalf("expected a partial name to be refused, got %d: %s", bad.code, bad.stdout)
+	}
+	if n := srv.CallCount("banChatMember"); n != 0 {
+		t.Fatalf("expected no banChatMember calls, got %d", n)
+	}
+	runOK(t, append([]string{"chat", "ban", "-1001", "@mallory"}, flags...)...)
+	if m := srv.Member(-1001, 7); m.Status != "kicked" {
+		t.Fatalf("unexpected banned member: %+v", m)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/example/tgbot-cli/internal/config"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+// listenProfiles returns the profiles to listen with when several bots were
+// requested via --all-profiles or a comma-separated --profile, and nil for
+// the usual single-bot mode.
+func listenProfiles(opts tokenFlagOptions, all bool) []string {
+	requested := strings.Contains(*opts.profile, ",")
+	if !all && !requested {
+		return nil
+	}
+	if all && *opts.profile != "" {
+		fatal("use either --all-profiles or --profile, not both")
+	}
+	if *opts.token != "" {
+		fatal("--token cannot be combined with several profiles; their tokens come from the config file")
+	}
+	if *opts.traceFile != "" {
+		fatal("--trace-file cannot be combined with several profiles")
+	}
+
+	var profiles []string
+	if all {
+		names, err := config.ProfileNames(*opts.configPath)
+		if err != nil {
+			fatalf("list profiles: %v", err)
+		}
+		profiles = names
+	} else {
+		seen := map[string]bool{}
+		for _, p := range strings.Split(*opts.profile, ",") {
+			p = strings.TrimSpace(p)
+			if p != "" && !seen[p] {
+				seen[p] = true
+				profiles = append(profiles, p)
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		fatal("no profiles to listen with")
+	}
+	return profiles
+}
+
+// mustProfileClient builds the client for one of several profiles. The
+// token always comes from the config file, never --token or TG_BOT_TOKEN.
+func mustProfileClient(opts tokenFlagOptions, profile string) *telegram.Client {
+	token, err := config.ProfileToken(*opts.configPath, profile)
+	if err != nil {
+		fatalf("resolve token: %v", err)
+	}
+	return mustClientWithToken(opts, profile, token)
+}
+
+// mustBotName returns "@username" of the bot behind client; label prefixes
+// errors.
+func mustBotName(ctx context.Context, client *telegram.Client, label string) string {
+	raw, err := client.GetMe(ctx)
+	if err != nil {
+		fatalf("%s: get bot info: %v", label, err)
+	}
+	var me telegram.User
+	if err := json.Unmarshal(raw, &me); err != nil {
+		fatalf("%s: decode bot info: %v", label, err)
+	}
+	return "@" + me.Username
+}
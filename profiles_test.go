@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommandUpdatesListenSeveralProfiles(t *testing.T) {
+	srv, _ := newFakeAPI(t)
+	srv.Token = ""
+	srv.EnqueueText("hi")
+
+	cfg := filepath.Join(t.TempDir(), "config.json")
+	content := `{"active_profile":"dev","profiles":{"dev":{"token":"111:dev-secret"},"staging":{"token":"222:staging-secret"}}}`
+	if err := os.WriteFile(cfg, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	env := []string{"TG_BOT_TOKEN=999:env-secret"}
+
+	res := runCLI(t, env, "updates", "listen", "--once", "--timeout", "0", "--format", "jsonl", "--config", cfg, "--api-base", srv.URL, "--all-profiles")
+	if res.code != 0 {
+		t.Fatalf("listen exited %d: %s", res.code, res.stderr)
+	}
+	for _, want := range []string{`{"bot":"@test_bot","profile":"dev","update":{`, `{"bot":"@test_bot","profile":"staging","update":{`} {
+		if !strings.Contains(res.stdout, want) {
+			t.Fatalf("missing %s in merged output: %s", want, res.stdout)
+		}
+	}
+	if !strings.Contains(res.stderr, "[staging] [info] deleting webhook") {
+		t.Fatalf("expected profile-tagged logs, got %s", res.stderr)
+	}
+	res = runCLI(t, nil, "updates", "listen", "--once", "--config", cfg, "--api-base", srv.URL, "--profile", "dev,staging", "--token", "1:x")
+	if res.code == 0 || !strings.Contains(res.stderr, "--token cannot be combined with several profiles") {
+		t.Fatalf("expected --token to be rejected, got %d: %s", res.code, res.stderr)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/example/tgbot-cli/internal/polling"
+	"github.com/example/tgbot-cli/internal/session"
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const replayUsage = "usage: tgbot updates replay <session.jsonl> --to <webhook-url>|--stdout [--speed 1x] [flags]"
+
+func runUpdatesReplay(args []string) {
+	path := ""
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		path, args = args[0], args[1:]
+	}
+	fs := baseFlagSet("updates replay")
+	to := fs.String("to", "", "webhook url to post updates to")
+	toStdout := fs.Bool("stdout", false, "print updates instead of posting them")
+	outputFormat := fs.String("format", "pretty", "stdout output format: pretty|jsonl")
+	envelope := fs.Bool("envelope", false, "with --stdout, wrap each update as {received_at,source,update} with the recorded receive time")
+	speed := fs.String("speed", "1x", "timing: 1x keeps original gaps, 10x is ten times faster, max sends without delay")
+	secret := fs.String("secret", "", "secret token sent as "+webhookSecretHeader)
+	timeout := fs.Duration("timeout", 10*time.Second, "webhook request timeout")
+	firstUpdateID := fs.Int64("first-update-id", 0, "renumber update ids sequentially from this value")
+	chatID := fs.Int64("chat-id", 0, "replace every chat id with this id")
+	var chatMap stringSliceFlag
+	fs.Var(&chatMap, "map-chat", "replace one chat id as old=new (repeatable, wins over --chat-id)")
+	_ = fs.Parse(args)
+	if path == "" && fs.NArg() > 0 {
+		path = fs.Arg(0)
+	}
+	if path == "" || (*to == "") == !*toStdout {
+		fatal(replayUsage)
+	}
+	if *envelope && !*toStdout {
+		fatal("updates replay: --envelope requires --stdout")
+	}
+
+	speedFactor, err := session.ParseSpeed(*speed)
+	if err != nil {
+		fatalf("updates replay: %v", err)
+	}
+	chatIDs, err := parseChatMap(chatMap)
+	if err != nil {
+		fatalf("updates replay: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		fatalf("open session: %v", err)
+	}
+	entries, err := session.Read(f)
+	_ = f.Close()
+	if err != nil {
+		fatalf("read session: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	failures := 0
+	emit := func(e session.Entry) error {
+		if *toStdout {
+			formatted, err := polling.FormatUpdate(e.Update, *outputFormat)
+			if *envelope {
+				update := telegram.Update{Raw: e.Update, ReceivedAt: e.ReceivedAt}
+				formatted, err = polling.FormatEnvelope(polling.NewEnvelope(update, polling.SourceReplay, polling.Tag{}), *outputFormat)
+			}
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(formatted)
+			return err
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, *timeout)
+		defer cancel()
+		status, body, err := postWebhook(reqCtx, *to, *secret, e.Update)
+		if err != nil {
+			failures++
+			fmt.Fprintf(os.Stderr, "[warn] deliver update: %v\n", err)
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "[info] POST %s -> %s\n", *to, status)
+		if !strings.HasPrefix(status, "2") {
+			failures++
+			fmt.Fprintf(os.Stderr, "[warn] webhook answered %s: %s\n", status, bytes.TrimSpace(body))
+		}
+		return nil
+	}
+
+	err = session.Replay(ctx, entries, session.ReplayOptions{
+		Speed:         speedFactor,
+		FirstUpdateID: *firstUpdateID,
+		ChatIDs:       chatIDs,
+		AllChatsTo:    *chatID,
+	}, emit)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		fatalf("updates replay failed: %v", err)
+	}
+	if failures > 0 {
+		fatalf("updates replay: %d of %d updates were not accepted", failures, len(entries))
+	}
+}
+
+func parseChatMap(pairs []string) (map[int64]int64, error) {
+	out := make(map[int64]int64, len(pairs))
+	for _, p := range pairs {
+		oldStr, newStr, ok := strings.Cut(p, "=")
+		oldID, err1 := strconv.ParseInt(oldStr, 10, 64)
+		newID, err2 := strconv.ParseInt(newStr, 10, 64)
+		if !ok || err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("invalid --map-chat %q, expected old=new chat ids", p)
+		}
+		out[oldID] = newID
+	}
+	return out, nil
+}
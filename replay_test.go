@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCommandRecordAndReplay(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	srv.EnqueueText("first")
+	srv.EnqueueText("second")
+
+	sessionPath := filepath.Join(t.TempDir(), "session.jsonl")
+	runOK(t, append([]string{"updates", "listen", "--once", "--timeout", "0", "--format", "jsonl", "--record", sessionPath}, flags...)...)
+	data, err := os.ReadFile(sessionPath)
+	if err != nil {
+		t.Fatalf("read session: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(data)), "\n"); len(lines) != 2 || !strings.Contains(lines[0], `"received_at":`) {
+		t.Fatalf("unexpected session file: %s", data)
+	}
+
+	res := runOK(t, "updates", "replay", sessionPath, "--stdout", "--format", "jsonl", "--speed", "max", "--first-update-id", "500", "--chat-id", "7")
+	lines := strings.Split(strings.TrimSpace(res.stdout), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"update_id":501`) || !strings.Contains(lines[0], `"chat":{"first_name":"Alice","id":7`) {
+		t.Fatalf("unexpected replay output: %s", res.stdout)
+	}
+
+	var mu sync.Mutex
+	var posted []string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		posted = append(posted, string(body))
+		mu.Unlock()
+	}))
+	defer backend.Close()
+	runOK(t, "updates", "replay", sessionPath, "--to", backend.URL, "--speed", "100x")
+	if len(posted) != 2 || !strings.Contains(posted[1], `"text":"second"`) {
+		t.Fatalf("unexpected webhook deliveries: %v", posted)
+	}
+
+	res = runCLI(t, nil, "updates", "replay", sessionPath)
+	if res.code == 0 || !strings.Contains(res.stderr, "usage: tgbot updates replay") {
+		t.Fatalf("expected usage error without --to or --stdout, got %d: %s", res.code, res.stderr)
+	}
+}
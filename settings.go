@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"strconv"
+)
+
+// runChatSetting calls a chat settings method and reports success like
+// bot logout does.
+func runChatSetting(cmd chatCommand, method string, call func(ctx context.Context) error) {
+	if err := call(context.Background()); err != nil {
+		fatalf("%s failed: %v", method, err)
+	}
+	printJSON(mustMarshal(map[string]any{"ok": true, "method": method, "chat_id": cmd.chatID}))
+}
+
+func runChatSetTitle(args []string) {
+	cmd := parseChatAction("chat set-title", "usage: tgbot chat set-title <chat> <title> [flags] (see set-admin-title for administrators' titles)", args, 1, nil)
+	runChatSetting(cmd, "setChatTitle", func(ctx context.Context) error {
+		return cmd.client.SetChatTitle(ctx, cmd.chatID, cmd.args[0])
+	})
+}
+
+func runChatSetDescription(args []string) {
+	cmd := parseChatAction("chat set-description", "usage: tgbot chat set-description <chat> [description] [flags]", args, -1, nil)
+	if len(cmd.args) > 1 {
+		fatal("usage: tgbot chat set-description <chat> [description] [flags] (quote the description; omit it to clear)")
+	}
+	description := ""
+	if len(cmd.args) == 1 {
+		description = cmd.args[0]
+	}
+	runChatSetting(cmd, "setChatDescription", func(ctx context.Context) error {
+		return cmd.client.SetChatDescription(ctx, cmd.chatID, description)
+	})
+}
+
+func runChatSetPhoto(args []string) {
+	cmd := parseChatAction("chat set-photo", "usage: tgbot chat set-photo <chat> <image> [flags]", args, 1, nil)
+	runChatSetting(cmd, "setChatPhoto", func(ctx context.Context) error {
+		return cmd.client.SetChatPhoto(ctx, cmd.chatID, cmd.args[0])
+	})
+}
+
+func runChatDeletePhoto(args []string) {
+	cmd := parseDestructiveAction("chat delete-photo", "usage: tgbot chat delete-photo <chat> [flags]", args, 0, nil)
+	runChatSetting(cmd, "deleteChatPhoto", func(ctx context.Context) error {
+		return cmd.client.DeleteChatPhoto(ctx, cmd.chatID)
+	})
+}
+
+func runChatSetPermissions(args []string) {
+	var allow, deny *string
+	cmd := parseDestructiveAction("chat set-permissions", "usage: tgbot chat set-permissions <chat> --allow list|--deny list [flags]", args, 0, func(fs *flag.FlagSet) {
+		allow = fs.String("allow", "", "permissions members keep, e.g. send_messages,send_photos; everything else is denied")
+		deny = fs.String("deny", "", "permissions taken from members, e.g. send_polls,pin_messages, or all; everything else is allowed")
+	})
+	if *allow == "" && *deny == "" {
+		fatal("--allow or --deny is required (--deny all makes the chat read-only)")
+	}
+	perms := mustPermissions(*allow, *deny)
+	runChatSetting(cmd, "setChatPermissions", func(ctx context.Context) error {
+		return cmd.client.SetChatPermissions(ctx, cmd.chatID, perms)
+	})
+}
+
+func runChatPin(args []string) {
+	var silent *bool
+	cmd := parseChatAction("chat pin", "usage: tgbot chat pin <chat> <message_id> [--silent] [flags]", args, 1, func(fs *flag.FlagSet) {
+		silent = fs.Bool("silent", false, "pin without notifying members")
+	})
+	messageID := mustMessageID(cmd.args[0])
+	runChatSetting(cmd, "pinChatMessage", func(ctx context.Context) error {
+		return cmd.client.PinChatMessage(ctx, cmd.chatID, messageID, *silent)
+	})
+}
+
+func runChatUnpin(args []string) {
+	cmd := parseChatAction("chat unpin", "usage: tgbot chat unpin <chat> [message_id] [flags]", args, -1, nil)
+	if len(cmd.args) > 1 {
+		fatal("usage: tgbot chat unpin <chat> [message_id] [flags]")
+	}
+	// Without a message id Telegram unpins the most recent pin.
+	var messageID int64
+	if len(cmd.args) == 1 {
+		messageID = mustMessageID(cmd.args[0])
+	}
+	runChatSetting(cmd, "unpinChatMessage", func(ctx context.Context) error {
+		return cmd.client.UnpinChatMessage(ctx, cmd.chatID, messageID)
+	})
+}
+
+func runChatUnpinAll(args []string) {
+	var yes *bool
+	cmd := parseDestructiveAction("chat unpin-all", "usage: tgbot chat unpin-all <chat> --yes [flags]", args, 0, func(fs *flag.FlagSet) {
+		yes = fs.Bool("yes", false, "confirm the call")
+	})
+	if !*yes {
+		fatal("chat unpin-all unpins every pinned message of the chat; rerun with --yes")
+	}
+	runChatSetting(cmd, "unpinAllChatMessages", func(ctx context.Context) error {
+		return cmd.client.UnpinAllChatMessages(ctx, cmd.chatID)
+	})
+}
+
+func runChatLeave(args []string) {
+	var yes *bool
+	cmd := parseDestructiveAction("chat leave", "usage: tgbot chat leave <chat> --yes [flags]", args, 0, func(fs *flag.FlagSet) {
+		yes = fs.Bool("yes", false, "confirm the call")
+	})
+	if !*yes {
+		fatal("chat leave removes the bot from the chat; only an administrator can add it back; rerun with --yes")
+	}
+	runChatSetting(cmd, "leaveChat", func(ctx context.Context) error {
+		return cmd.client.LeaveChat(ctx, cmd.chatID)
+	})
+}
+
+func mustMessageID(s string) int64 {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		fatalf("invalid message id %q", s)
+	}
+	return id
+}
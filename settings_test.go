@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func TestCommandChatSettings(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	group := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Ops"}
+	srv.SetMember(group, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{
+		"can_change_info": true, "can_restrict_members": true, "can_pin_messages": true,
+	}})
+
+	runOK(t, append([]string{"chat", "set-title", "-1001", "Ops Team"}, flags...)...)
+	runOK(t, append([]string{"chat", "set-description", "-1001", "on-call rotation"}, flags...)...)
+	photo := filepath.Join(t.TempDir(), "logo.png")
+	if err := os.WriteFile(photo, []byte("png"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runOK(t, append([]string{"chat", "set-photo", "-1001", photo}, flags...)...)
+	if s := srv.Settings(-1001); s.Description != "on-call rotation" || s.PhotoFileID == "" {
+		t.Fatalf("unexpected settings: %+v", s)
+	}
+	runOK(t, append([]string{"chat", "delete-photo", "-1001"}, flags...)...)
+
+	runOK(t, append([]string{"chat", "set-permissions", "-1001", "--deny", "send_polls,pin_messages"}, flags...)...)
+	if p := srv.Settings(-1001).Permissions; p["can_send_messages"] != true || p["can_send_polls"] != false {
+		t.Fatalf("unexpected permissions: %+v", p)
+	}
+
+	runOK(t, append([]string{"message", "send", "--chat-id", "-1001", "--text", "read the rules"}, flags...)...)
+	id := srv.SentMessages()[0].MessageID
+	runOK(t, append([]string{"chat", "pin", "-1001", strconv.FormatInt(id, 10), "--silent"}, flags...)...)
+	res := runOK(t, append([]string{"chat", "info", "-1001"}, flags...)...)
+	for _, want := range []string{"Ops Team", "on-call rotation", "read the rules"} {
+		if !strings.Contains(res.stdout, want) {
+			t.Fatalf("chat info missing %q:\n%s", want, res.stdout)
+		}
+	}
+	runOK(t, append([]string{"chat", "unpin", "-1001"}, flags...)...)
+
+	bad := runCLI(t, nil, append([]string{"chat", "unpin-all", "-1001"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "--yes") {
+		t.Fatalf("expected confirmation error, got %d: %s", bad.code, bad.stderr)
+	}
+	runOK(t, append([]string{"chat", "unpin-all", "-1001", "--yes"}, flags...)...)
+	res = runOK(t, append([]string{"chat", "leave", "-1001", "--yes"}, flags...)...)
+	if !strings.Contains(res.stdout, `"leaveChat"`) || srv.Member(-1001, telegramtest.DefaultBot.ID).Status != "left" {
+		t.Fatalf("unexpected leave: %s", res.stdout)
+	}
+}
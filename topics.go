@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/example/tgbot-cli/internal/telegram"
+)
+
+const topicUsage = "usage: tgbot topic <create|edit|close|reopen|delete|hide|unhide|list-icons> <chat> [flags]"
+
+// generalTopic names a forum's General topic where commands take a thread
+// id; the Bot API has separate methods for it.
+const generalTopic = "general"
+
+func runTopic(args []string) {
+	if len(args) == 0 {
+		fatal(topicUsage)
+	}
+	switch args[0] {
+	case "create":
+		runTopicCreate(args[1:])
+	case "edit":
+		runTopicEdit(args[1:])
+	case "close", "reopen":
+		runTopicClose(args[0], args[1:])
+	case "delete":
+		runTopicDelete(args[1:])
+	case "hide", "unhide":
+		runTopicHide(args[0], args[1:])
+	case "list-icons":
+		runTopicListIcons(args[1:])
+	default:
+		fatal(topicUsage)
+	}
+}
+
+// parseIconColor accepts a color name such as blue, or its RGB value in
+// hex (0x6FB9F0, #6FB9F0) or decimal.
+func parseIconColor(s string) (int, error) {
+	if color, ok := telegram.TopicIconColors[strings.ToLower(s)]; ok {
+		return color, nil
+	}
+	digits, base := strings.ToLower(s), 10
+	if hex, ok := strings.CutPrefix(digits, "#"); ok {
+		digits, base = hex, 16
+	} else if hex, ok := strings.CutPrefix(digits, "0x"); ok {
+		digits, base = hex, 16
+	}
+	if color, err := strconv.ParseInt(digits, base, 32); err == nil {
+		for _, known := range telegram.TopicIconColors {
+			if int(color) == known {
+				return known, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("invalid icon color %q (want one of %s, or their RGB value such as 0x6FB9F0)", s, strings.Join(iconColorNames(), ", "))
+}
+
+func iconColorNames() []string {
+	names := make([]string, 0, len(telegram.TopicIconColors))
+	for name := range telegram.TopicIconColors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// threadFilter returns a polling filter keeping the messages of forum topic
+// threadID, or nil for no filter when threadID is 0.
+func threadFilter(threadID int64) func(telegram.Update) bool {
+	if threadID == 0 {
+		return nil
+	}
+	return func(update telegram.Update) bool {
+		m := update.EffectiveMessage()
+		return m != nil && m.MessageThreadID == threadID
+	}
+}
+
+// mustThreadID parses a topic's message_thread_id.
+func mustThreadID(s string) int64 {
+	id, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || id <= 0 {
+		fatalf("invalid topic %q (want a message_thread_id or %s)", s, generalTopic)
+	}
+	return id
+}
+
+func runTopicCreate(args []string) {
+	var iconColor, iconEmoji *string
+	cmd := parseChatCommand("topic create", "usage: tgbot topic create <chat> <name> [--icon-color blue] [--icon-emoji id] [flags]", args, 1, func(fs *flag.FlagSet) {
+		iconColor = fs.String("icon-color", "", "icon color: "+strings.Join(iconColorNames(), ", ")+" or its RGB value; default chosen by Telegram")
+		iconEmoji = fs.String("icon-emoji", "", "custom emoji id of the icon (see topic list-icons)")
+	})
+	var color int
+	if *iconColor != "" {
+		var err error
+		if color, err = parseIconColor(*iconColor); err != nil {
+			fatal(err.Error())
+		}
+	}
+	topic, err := cmd.client.CreateForumTopic(context.Background(), cmd.chatID, cmd.args[0], color, *iconEmoji)
+	if err != nil {
+		fatalf("topic create failed: %v", err)
+	}
+	if cmd.format == "json" {
+		printJSON(mustMarshal(topic))
+		return
+	}
+	writeKeyValues([][2]string{
+		{"thread id", strconv.FormatInt(topic.MessageThreadID, 10)},
+		{"name", topic.Name},
+		{"icon color", fmt.Sprintf("0x%06X", topic.IconColor)},
+		{"icon emoji", firstNonEmpty(topic.IconCustomEmojiID, "none")},
+	})
+}
+
+func runTopicEdit(args []string) {
+	var name, iconEmoji *string
+	var clearIcon *bool
+	cmd := parseChatAction("topic edit", "usage: tgbot topic edit <chat> <thread_id|general> [--name n] [--icon-emoji id|--clear-icon] [flags]", args, 1, func(fs *flag.FlagSet) {
+		name = fs.String("name", "", "new topic name")
+		iconEmoji = fs.String("icon-emoji", "", "custom emoji id of the new icon (see topic list-icons)")
+		clearIcon = fs.Bool("clear-icon", false, "remove the custom emoji icon")
+	})
+	if cmd.args[0] == generalTopic {
+		if *name == "" || *iconEmoji != "" || *clearIcon {
+			fatal("the General topic can only be renamed: pass --name")
+		}
+		runChatSetting(cmd, "editGeneralForumTopic", func(ctx context.Context) error {
+			return cmd.client.EditGeneralForumTopic(ctx, cmd.chatID, *name)
+		})
+		return
+	}
+	threadID := mustThreadID(cmd.args[0])
+	var icon *string
+	switch {
+	case *iconEmoji != "" && *clearIcon:
+		fatal("--icon-emoji cannot be combined with --clear-icon")
+	case *iconEmoji != "":
+		icon = iconEmoji
+	case *clearIcon:
+		icon = new(string)
+	case *name == "":
+		fatal("nothing to change: pass --name, --icon-emoji or --clear-icon")
+	}
+	runChatSetting(cmd, "editForumTopic", func(ctx context.Context) error {
+		return cmd.client.EditForumTopic(ctx, cmd.chatID, threadID, *name, icon)
+	})
+}
+
+// runTopicClose closes or reopens a topic, so only administrators can post
+// in it until then.
+func runTopicClose(sub string, args []string) {
+	cmd := parseChatAction("topic "+sub, "usage: tgbot topic "+sub+" <chat> <thread_id|general> [flags]", args, 1, nil)
+	general := cmd.args[0] == generalTopic
+	var threadID int64
+	if !general {
+		threadID = mustThreadID(cmd.args[0])
+	}
+	var method string
+	var call func(ctx context.Context) error
+	switch {
+	case sub == "close" && general:
+		method, call = "closeGeneralForumTopic", func(ctx context.Context) error { return cmd.client.CloseGeneralForumTopic(ctx, cmd.chatID) }
+	case sub == "close":
+		method, call = "closeForumTopic", func(ctx context.Context) error { return cmd.client.CloseForumTopic(ctx, cmd.chatID, threadID) }
+	case general:
+		method, call = "reopenGeneralForumTopic", func(ctx context.Context) error { return cmd.client.ReopenGeneralForumTopic(ctx, cmd.chatID) }
+	default:
+		method, call = "reopenForumTopic", func(ctx context.Context) error { return cmd.client.ReopenForumTopic(ctx, cmd.chatID, threadID) }
+	}
+	runChatSetting(cmd, method, call)
+}
+
+func runTopicDelete(args []string) {
+	var yes *bool
+	cmd := parseDestructiveAction("topic delete", "usage: tgbot topic delete <chat> <thread_id> --yes [flags]", args, 1, func(fs *flag.FlagSet) {
+		yes = fs.Bool("yes", false, "confirm the call")
+	})
+	if cmd.args[0] == generalTopic {
+		fatal("the General topic cannot be deleted; use topic hide")
+	}
+	threadID := mustThreadID(cmd.args[0])
+	if !*yes {
+		fatal("topic delete deletes the topic with all its messages; rerun with --yes")
+	}
+	runChatSetting(cmd, "deleteForumTopic", func(ctx context.Context) error {
+		return cmd.client.DeleteForumTopic(ctx, cmd.chatID, threadID)
+	})
+}
+
+// runTopicHide hides or unhides the General topic, the only topic that can
+// be hidden.
+func runTopicHide(sub string, args []string) {
+	cmd := parseChatAction("topic "+sub, "usage: tgbot topic "+sub+" <chat> [flags] (acts on the General topic)", args, 0, nil)
+	if sub == "hide" {
+		runChatSetting(cmd, "hideGeneralForumTopic", func(ctx context.Context) error {
+			return cmd.client.HideGeneralForumTopic(ctx, cmd.chatID)
+		})
+		return
+	}
+	runChatSetting(cmd, "unhideGeneralForumTopic", func(ctx context.Context) error {
+		return cmd.client.UnhideGeneralForumTopic(ctx, cmd.chatID)
+	})
+}
+
+func runTopicListIcons(args []string) {
+	fs := baseFlagSet("topic list-icons")
+	outputFormat := fs.String("format", "table", "output format: table|json")
+	tokenOpt := registerTokenFlags(fs)
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		fatal("usage: tgbot topic list-icons [--format table|json] [flags]")
+	}
+	if *outputFormat != "table" && *outputFormat != "json" {
+		fatalf("unknown --format %q (want table or json)", *outputFormat)
+	}
+	stickers, err := mustClient(tokenOpt).GetForumTopicIconStickers(context.Background())
+	if err != nil {
+		fatalf("topic list-icons failed: %v", err)
+	}
+	if *outputFormat == "json" {
+		printJSON(mustMarshal(stickers))
+		return
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "EMOJI\tCUSTOM EMOJI ID")
+	for _, s := range stickers {
+		fmt.Fprintf(tw, "%s\t%s\n", s.Emoji, s.CustomEmojiID)
+	}
+	_ = tw.Flush()
+}
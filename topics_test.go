@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/example/tgbot-cli/internal/telegram/telegramtest"
+)
+
+func TestCommandTopic(t *testing.T) {
+	srv, flags := newFakeAPI(t)
+	forum := telegramtest.Chat{ID: -1001, Type: "supergroup", Title: "Support", IsForum: true}
+	srv.SetMember(forum, telegramtest.Member{User: telegramtest.DefaultBot, Status: "administrator", Fields: map[string]any{"can_manage_topics": true}})
+
+	res := runOK(t, append([]string{"topic", "create", "-1001", "Bugs", "--icon-color", "red", "--format", "json"}, flags...)...)
+	var topic struct {
+		MessageThreadID int64 `json:"message_thread_id"`
+		IconColor       int   `json:"icon_color"`
+	}
+	if err := json.Unmarshal([]byte(res.stdout), &topic); err != nil || topic.MessageThreadID == 0 || topic.IconColor != 0xFB6F5F {
+		t.Fatalf("unexpected topic %s: %v", res.stdout, err)
+	}
+	thread := strconv.FormatInt(topic.MessageThreadID, 10)
+
+	res = runOK(t, append([]string{"message", "send", "--chat-id", "-1001", "--thread-id", thread, "--text", "triage here"}, flags...)...)
+	if !strings.Contains(res.stdout, `"message_thread_id": `+thread) {
+		t.Fatalf("expected a topic message, got %s", res.stdout)
+	}
+
+	srv.EnqueueMessage(telegramtest.IncomingMessage{Chat: forum, Text: "crash on start", ThreadID: topic.MessageThreadID})
+	srv.EnqueueMessage(telegramtest.IncomingMessage{Chat: forum, Text: "off topic"})
+	res = runOK(t, append([]string{"updates", "list", "--thread-id", thread, "--format", "csv", "--columns", "thread_id,topic,text"}, flags...)...)
+	if res.stdout != "thread_id,topic,text\n"+thread+",Bugs,crash on start\n" {
+		t.Fatalf("unexpected filtered updates:\n%s", res.stdout)
+	}
+
+	runOK(t, append([]string{"topic", "edit", "-1001", thread, "--name", "Bug reports"}, flags...)...)
+	runOK(t, append([]string{"topic", "close", "-1001", thread}, flags...)...)
+	if got := srv.Topics(-1001)[topic.MessageThreadID]; got.Name != "Bug reports" || !got.Closed {
+		t.Fatalf("unexpected topic state %+v", got)
+	}
+	runOK(t, append([]string{"topic", "reopen", "-1001", thread}, flags...)...)
+	bad := runCLI(t, nil, append([]string{"topic", "delete", "-1001", thread}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "--yes") {
+		t.Fatalf("expected confirmation error, got %d: %s", bad.code, bad.stderr)
+	}
+	runOK(t, append([]string{"topic", "delete", "-1001", thread, "--yes"}, flags...)...)
+	if len(srv.Topics(-1001)) != 0 {
+		t.Fatalf("expected topic deleted, got %+v", srv.Topics(-1001))
+	}
+
+	runOK(t, append([]string{"topic", "edit", "-1001", "general", "--name", "Lobby"}, flags...)...)
+	res = runOK(t, append([]string{"topic", "hide", "-1001"}, flags...)...)
+	if !strings.Contains(res.stdout, `"hideGeneralForumTopic"`) || srv.GeneralTopic(-1001) != (telegramtest.Topic{Name: "Lobby", Closed: true, Hidden: true}) {
+		t.Fatalf("unexpected hide: %s %+v", res.stdout, srv.GeneralTopic(-1001))
+	}
+	bad = runCLI(t, nil, append([]string{"topic", "edit", "-1001", "general", "--clear-icon"}, flags...)...)
+	if bad.code == 0 || !strings.Contains(bad.stderr, "can only be renamed") {
+		t.Fatalf("expected general topic edit error, got %d: %s", bad.code, bad.stderr)
+	}
+
+	res = runOK(t, append([]string{"topic", "list-icons"}, flags...)...)
+	if !strings.Contains(res.stdout, "CUSTOM EMOJI ID") || !strings.Contains(res.stdout, telegramtest.IconStickers[0]["custom_emoji_id"].(string)) {
+		t.Fatalf("unexpected icons:\n%s", res.stdout)
+	}
+}
+
+func TestParseIconColor(t *testing.T) {
+	for in, want := range map[string]int{"blue": 0x6FB9F0, "Red": 0xFB6F5F, "0x8EEE98": 0x8EEE98, "#ffd67e": 0xFFD67E, "13338331": 0xCB86DB} {
+		if got, err := parseIconColor(in); err != nil || got != want {
+			t.Fatalf("parseIconColor(%q) = %#x, %v; want %#x", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"pink", "0x123456", "12"} {
+		if _, err := parseIconColor(in); err == nil {
+			t.Fatalf("expected error for %q", in)
+		}
+	}
+}